@@ -0,0 +1,48 @@
+package main
+
+import (
+	"crypto/tls"
+	"gRPC-server/pkg/client"
+	"gRPC-server/pkg/tlsconfig"
+	"time"
+
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"google.golang.org/grpc"
+)
+
+type connOptions struct {
+	addr       string
+	tls        bool
+	caFile     string
+	certFile   string
+	keyFile    string
+	serverName string
+	apiKey     string
+	token      string
+}
+
+func dial(opts connOptions, timeout time.Duration) (*client.Client, error) {
+	var tlsConfig *tls.Config
+	// CA, сертификат клиента или имя сервера без -tls тоже включают TLS
+	if opts.tls || opts.caFile != "" || opts.certFile != "" || opts.serverName != "" {
+		config, _, err := tlsconfig.ClientConfig(tlsconfig.ClientOptions{
+			CAFile:     opts.caFile,
+			CertFile:   opts.certFile,
+			KeyFile:    opts.keyFile,
+			ServerName: opts.serverName,
+		})
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig = config
+	}
+
+	return client.New(client.Options{
+		Addr:        opts.addr,
+		TLS:         tlsConfig,
+		APIKey:      opts.apiKey,
+		Token:       opts.token,
+		Timeout:     timeout,
+		DialOptions: []grpc.DialOption{grpc.WithStatsHandler(otelgrpc.NewClientHandler())},
+	})
+}
@@ -2,77 +2,405 @@ package main
 
 import (
 	"context"
+	"errors"
+	"flag"
 	"fmt"
+	"gRPC-server/internal/tracing"
 	"gRPC-server/pkg/parseCSV/grpcPb"
-	"log"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
 	"time"
 
-	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials/insecure"
+	"github.com/spf13/viper"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
 )
 
-func TryConnect() (*grpc.ClientConn, error) {
-	conn, err := grpc.NewClient("grpc-server:8889", grpc.WithTransportCredentials(insecure.NewCredentials()))
+// коды выхода
+const (
+	exitOK          = 0
+	exitError       = 1
+	exitUsage       = 2 // неверные флаги или аргументы запроса
+	exitUnavailable = 3 // сервер или источник недоступен, истёк таймаут
+	exitAuth        = 4 // нет доступа
+)
+
+const usage = `Usage: client [global flags] <command> [flags]
+
+Commands:
+  fetch   загрузить CSV по ссылке в базу
+  list    вывести отсортированную страницу товаров
+  watch   выводить страницу товаров заново каждые -interval
+  export  выгрузить каталог целиком в csv, json или ndjson
+
+Global flags:
+`
+
+type globalFlags struct {
+	conn    connOptions
+	output  string
+	timeout time.Duration
+}
+
+type listFlags struct {
+	sort           string
+	desc           bool
+	offset         int
+	limit          int
+	includeDeleted bool
+	name           string
+	source         string
+}
+
+func main() {
+	// трассировка клиента включается переменными TRACING_EXPORTER и TRACING_ENDPOINT
+	viper.Set("tracing.exporter", os.Getenv("TRACING_EXPORTER"))
+	viper.Set("tracing.endpoint", os.Getenv("TRACING_ENDPOINT"))
+	viper.Set("tracing.insecure", true)
+	viper.Set("tracing.service_name", "gRPC-client")
+	shutdownTracing, err := tracing.Init(context.Background())
 	if err != nil {
-		fmt.Printf("1) %s", err)
-		return nil, err
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(exitError)
 	}
-	return conn, nil
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	code := run(ctx, os.Args[1:], os.Stdout, os.Stderr)
+	stop()
+	shutdownTracing(context.Background())
+	os.Exit(code)
 }
 
-func main() {
-	time.Sleep(time.Second * 10)
-	//Создать соединение с gRPC сервером
-	conn, err := grpc.NewClient("grpc-server:8889", grpc.WithTransportCredentials(insecure.NewCredentials()))
+func run(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	var global globalFlags
+	flags := flag.NewFlagSet("client", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprint(stderr, usage)
+		flags.PrintDefaults()
+	}
+	flags.StringVar(&global.conn.addr, "addr", envOr("SORT_ADDR", "localhost:8889"), "адрес gRPC сервера (SORT_ADDR)")
+	flags.BoolVar(&global.conn.tls, "tls", false, "подключаться по TLS с системными корневыми сертификатами")
+	flags.StringVar(&global.conn.caFile, "ca", os.Getenv("TLS_CA_FILE"), "CA для проверки сервера, включает TLS (TLS_CA_FILE)")
+	flags.StringVar(&global.conn.certFile, "cert", os.Getenv("TLS_CERT_FILE"), "сертификат клиента для mTLS (TLS_CERT_FILE)")
+	flags.StringVar(&global.conn.keyFile, "key", os.Getenv("TLS_KEY_FILE"), "ключ сертификата клиента (TLS_KEY_FILE)")
+	flags.StringVar(&global.conn.serverName, "server-name", os.Getenv("TLS_SERVER_NAME"), "имя сервера в сертификате (TLS_SERVER_NAME)")
+	flags.StringVar(&global.conn.apiKey, "api-key", os.Getenv("API_KEY"), "API ключ (API_KEY)")
+	flags.StringVar(&global.conn.token, "token", os.Getenv("AUTH_TOKEN"), "JWT (AUTH_TOKEN)")
+	flags.StringVar(&global.output, "o", formatTable, "формат вывода: table, json или csv")
+	flags.DurationVar(&global.timeout, "timeout", 10*time.Second, "таймаут одного вызова")
+
+	if err := flags.Parse(args); err != nil {
+		return exitUsage
+	}
+	if !validFormat(global.output) {
+		fmt.Fprintf(stderr, "unknown output format %q\n", global.output)
+		return exitUsage
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return exitUsage
+	}
+
+	command, commandArgs := flags.Arg(0), flags.Args()[1:]
+	switch command {
+	case "fetch":
+		return runFetch(ctx, global, commandArgs, stdout, stderr)
+	case "list":
+		return runList(ctx, global, commandArgs, stdout, stderr)
+	case "watch":
+		return runWatch(ctx, global, commandArgs, stdout, stderr)
+	case "export":
+		return runExport(ctx, global, commandArgs, stdout, stderr)
+	default:
+		fmt.Fprintf(stderr, "unknown command %q\n", command)
+		flags.Usage()
+		return exitUsage
+	}
+}
+
+func runFetch(ctx context.Context, global globalFlags, args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("fetch", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	url := flags.String("url", "", "ссылка на CSV (можно передать аргументом)")
+	source := flags.String("source", "", "источник из реестра сервера, без -url берётся его ссылка")
+	if err := flags.Parse(args); err != nil {
+		return exitUsage
+	}
+	if *url == "" && flags.NArg() > 0 {
+		*url = flags.Arg(0)
+	}
+	if *url == "" && *source == "" {
+		fmt.Fprintln(stderr, "fetch: url or source is required")
+		return exitUsage
+	}
+
+	sortClient, err := dial(global.conn, global.timeout)
 	if err != nil {
-		fmt.Printf("1) %s", err)
-		for {
-			conn, err = TryConnect()
-			if err != nil {
-				fmt.Printf("2) %s", err)
-				time.Sleep(time.Second * 1) // Добавляем задержку между попытками
-			} else {
-				fmt.Println("Соединение с сервером установлено")
-				break
+		fmt.Fprintln(stderr, err)
+		return exitUsage
+	}
+	defer sortClient.Close()
+
+	result, err := sortClient.FetchSource(ctx, *source, *url)
+	if err != nil {
+		return fail(stderr, "fetch", err)
+	}
+	if err := writeStatus(stdout, global.output, result); err != nil {
+		return fail(stderr, "fetch", err)
+	}
+	return exitOK
+}
+
+func addListFlags(flags *flag.FlagSet, list *listFlags) {
+	flags.StringVar(&list.sort, "sort", "id", "поле сортировки: id, name или price")
+	flags.BoolVar(&list.desc, "desc", false, "по убыванию")
+	flags.IntVar(&list.offset, "offset", 0, "пропустить записей")
+	flags.IntVar(&list.limit, "limit", 0, "записей на странице, 0 - все")
+	flags.BoolVar(&list.includeDeleted, "include-deleted", false, "включить мягко удалённые товары")
+	flags.StringVar(&list.name, "name", "", "товары, в названии которых есть подстрока; страницы читаются, пока не наберётся -limit совпадений")
+	flags.StringVar(&list.source, "source", "", "товары одного источника, без него - объединённый каталог")
+}
+
+func (l listFlags) request() (*grpcPb.ListRequest, error) {
+	field, ok := grpcPb.ListRequest_SortParameters_value[strings.ToLower(l.sort)]
+	if !ok {
+		return nil, fmt.Errorf("unknown sort field %q", l.sort)
+	}
+	if l.offset < 0 || l.limit < 0 {
+		return nil, errors.New("offset and limit must not be negative")
+	}
+
+	sortAsc := int32(1)
+	if l.desc {
+		sortAsc = -1
+	}
+	return &grpcPb.ListRequest{
+		SortField:      grpcPb.ListRequest_SortParameters(field),
+		SortAsc:        sortAsc,
+		PagingOffset:   int32(l.offset),
+		PagingLimit:    int32(l.limit),
+		IncludeDeleted: l.includeDeleted,
+		Source:         l.source,
+	}, nil
+}
+
+// listMatching returns the page req asks for. The server doesn't filter List by name, so with
+// -name pages are read on until -limit products match or the catalog ends; -offset still
+// counts products of the catalog, not matches.
+func (l listFlags) listMatching(ctx context.Context, client grpcPb.SortServiceClient, req *grpcPb.ListRequest) ([]product, error) {
+	if l.name == "" {
+		return listPage(ctx, client, req)
+	}
+
+	req = proto.Clone(req).(*grpcPb.ListRequest)
+	limit := int(req.GetPagingLimit())
+	matched := []product{}
+	for {
+		page, err := listPage(ctx, client, req)
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range page {
+			if !strings.Contains(strings.ToLower(p.Name), strings.ToLower(l.name)) {
+				continue
+			}
+			matched = append(matched, p)
+			if len(matched) == limit {
+				return matched, nil
 			}
 		}
+		if limit == 0 || len(page) < limit {
+			return matched, nil
+		}
+		req.PagingOffset += int32(len(page))
 	}
-	fmt.Println("Соединение с сервером установлено")
-	defer conn.Close()
+}
 
-	//Создать контекст подключения
+func runList(ctx context.Context, global globalFlags, args []string, stdout, stderr io.Writer) int {
+	var list listFlags
+	flags := flag.NewFlagSet("list", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	addListFlags(flags, &list)
+	if err := flags.Parse(args); err != nil {
+		return exitUsage
+	}
+	req, err := list.request()
+	if err != nil {
+		fmt.Fprintf(stderr, "list: %s\n", err)
+		return exitUsage
+	}
 
-	// Инициализировать нового клиента
-	client := grpcPb.NewSortServiceClient(conn)
+	sortClient, err := dial(global.conn, global.timeout)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitUsage
+	}
+	defer sortClient.Close()
 
-	for {
-		//Отправить данные на сервер
-		ctx, cancel := context.WithTimeout(context.Background(), time.Second*3)
+	products, err := list.listMatching(ctx, sortClient.Raw(), req)
+	if err != nil {
+		return fail(stderr, "list", err)
+	}
+	if err := writeProducts(stdout, global.output, products); err != nil {
+		return fail(stderr, "list", err)
+	}
+	return exitOK
+}
 
-		resp, err := client.Fetch(ctx, &grpcPb.FetchRequest{
-			Url: "http://web-app:8085/products/",
-		})
+func runWatch(ctx context.Context, global globalFlags, args []string, stdout, stderr io.Writer) int {
+	var list listFlags
+	flags := flag.NewFlagSet("watch", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	addListFlags(flags, &list)
+	interval := flags.Duration("interval", 5*time.Second, "как часто запрашивать страницу")
+	count := flags.Int("count", 0, "остановиться после стольких запросов, 0 - до Ctrl+C")
+	if err := flags.Parse(args); err != nil {
+		return exitUsage
+	}
+	if *interval <= 0 {
+		fmt.Fprintln(stderr, "watch: interval must be positive")
+		return exitUsage
+	}
+	req, err := list.request()
+	if err != nil {
+		fmt.Fprintf(stderr, "watch: %s\n", err)
+		return exitUsage
+	}
 
-		if err != nil {
-			fmt.Println("Fetch error")
-			fmt.Printf("3) %s", err)
+	sortClient, err := dial(global.conn, global.timeout)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitUsage
+	}
+	defer sortClient.Close()
+
+	ticker := time.NewTicker(*interval)
+	defer ticker.Stop()
+
+	for polls := 1; ; polls++ {
+		products, err := list.listMatching(ctx, sortClient.Raw(), req)
+		switch {
+		case ctx.Err() != nil:
+			return exitOK
+		case err != nil && exitCode(err) != exitUnavailable:
+			return fail(stderr, "watch", err)
+		case err != nil:
+			// сервер может вернуться, продолжаем опрос
+			fmt.Fprintf(stderr, "watch: %s\n", status.Convert(err).Message())
+		default:
+			if global.output == formatTable {
+				fmt.Fprintf(stdout, "-- %s --\n", time.Now().Format(time.RFC3339))
+			}
+			if err := writeProducts(stdout, global.output, products); err != nil {
+				return fail(stderr, "watch", err)
+			}
 		}
 
-		log.Printf("Fetch success: %s", resp.Status)
+		if *count > 0 && polls >= *count {
+			return exitOK
+		}
+		select {
+		case <-ctx.Done():
+			return exitOK
+		case <-ticker.C:
+		}
+	}
+}
+
+// runExport writes the file as the server streams it, -o doesn't apply here.
+func runExport(ctx context.Context, global globalFlags, args []string, stdout, stderr io.Writer) int {
+	var list listFlags
+	flags := flag.NewFlagSet("export", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	addListFlags(flags, &list)
+	format := flags.String("format", "csv", "формат файла: csv, json или ndjson")
+	if err := flags.Parse(args); err != nil {
+		return exitUsage
+	}
+	exportFormat, ok := grpcPb.ExportRequest_Format_value[strings.ToLower(*format)]
+	if !ok {
+		fmt.Fprintf(stderr, "export: unknown format %q\n", *format)
+		return exitUsage
+	}
+	if list.name != "" {
+		fmt.Fprintln(stderr, "export: -name is not supported, the server exports whole pages")
+		return exitUsage
+	}
+	req, err := list.request()
+	if err != nil {
+		fmt.Fprintf(stderr, "export: %s\n", err)
+		return exitUsage
+	}
+
+	sortClient, err := dial(global.conn, global.timeout)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitUsage
+	}
+	defer sortClient.Close()
 
-		//отправить запрос на получение данных
-		list, err := client.List(ctx, &grpcPb.ListRequest{
-			SortField:    grpcPb.ListRequest_name,
-			SortAsc:      1,
-			PagingOffset: 0,
-			PagingLimit:  10,
-		})
+	stream, err := sortClient.Raw().Export(ctx, &grpcPb.ExportRequest{
+		List:   req,
+		Format: grpcPb.ExportRequest_Format(exportFormat),
+	})
+	if err != nil {
+		return fail(stderr, "export", err)
+	}
+	for {
+		chunk, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return exitOK
+		}
 		if err != nil {
-			fmt.Printf("4) %s", err)
+			return fail(stderr, "export", err)
+		}
+		if _, err := stdout.Write(chunk.GetData()); err != nil {
+			return fail(stderr, "export", err)
 		}
-		fmt.Println("Сортировка от сервера: ", list)
+	}
+}
+
+// listPage keeps prices as the server formatted them, the SDK would parse them into decimals.
+func listPage(ctx context.Context, client grpcPb.SortServiceClient, req *grpcPb.ListRequest) ([]product, error) {
+	resp, err := client.List(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return toProducts(resp.GetProduct()), nil
+}
+
+func fail(stderr io.Writer, command string, err error) int {
+	if st, ok := status.FromError(err); ok {
+		fmt.Fprintf(stderr, "%s: %s: %s\n", command, st.Code(), st.Message())
+	} else {
+		fmt.Fprintf(stderr, "%s: %s\n", command, err)
+	}
+	return exitCode(err)
+}
+
+func exitCode(err error) int {
+	switch status.Code(err) {
+	case codes.OK:
+		return exitOK
+	case codes.InvalidArgument:
+		return exitUsage
+	case codes.Unavailable, codes.DeadlineExceeded, codes.ResourceExhausted:
+		return exitUnavailable
+	case codes.Unauthenticated, codes.PermissionDenied:
+		return exitAuth
+	default:
+		return exitError
+	}
+}
 
-		time.Sleep(1 * time.Second)
-		cancel()
+func envOr(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
 	}
+	return fallback
 }
@@ -0,0 +1,243 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"gRPC-server/pkg/parseCSV/grpcPb"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+type sortServer struct {
+	grpcPb.UnimplementedSortServiceServer
+	listReq  *grpcPb.ListRequest
+	apiKey   string
+	fetchErr error
+}
+
+func (s *sortServer) Fetch(ctx context.Context, req *grpcPb.FetchRequest) (*grpcPb.FethResponce, error) {
+	if s.fetchErr != nil {
+		return nil, s.fetchErr
+	}
+	return &grpcPb.FethResponce{Status: "Success"}, nil
+}
+
+func (s *sortServer) List(ctx context.Context, req *grpcPb.ListRequest) (*grpcPb.ListResponce, error) {
+	s.listReq = req
+	if md, ok := metadata.FromIncomingContext(ctx); ok && len(md.Get("x-api-key")) > 0 {
+		s.apiKey = md.Get("x-api-key")[0]
+	}
+	return &grpcPb.ListResponce{Product: []*grpcPb.Product{
+		{Source: "web-app", Id: 1, Name: "Apple", Price: "50.00"},
+		{Source: "web-app", Id: 2, Name: "Pear", Price: "60.00", DeletedAt: timestamppb.New(time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC))},
+	}}, nil
+}
+
+func (s *sortServer) Export(req *grpcPb.ExportRequest, stream grpcPb.SortService_ExportServer) error {
+	s.listReq = req.GetList()
+	if err := stream.Send(&grpcPb.ExportChunk{Data: []byte("1;Apple;50.00\n")}); err != nil {
+		return err
+	}
+	return stream.Send(&grpcPb.ExportChunk{Data: []byte("2;Pear;60.00\n")})
+}
+
+func startServer(t *testing.T, srv *sortServer) string {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	server := grpc.NewServer()
+	grpcPb.RegisterSortServiceServer(server, srv)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+	return listener.Addr().String()
+}
+
+func TestRun(t *testing.T) {
+	srv := &sortServer{}
+	addr := startServer(t, srv)
+
+	testTables := []struct {
+		name       string
+		args       []string
+		fetchErr   error
+		wantCode   int
+		wantStdout string
+		wantReq    *grpcPb.ListRequest
+	}{
+		{
+			name:       "Fetch",
+			args:       []string{"-addr", addr, "fetch", "http://web-app:8085/products/"},
+			wantCode:   exitOK,
+			wantStdout: "Success\n",
+		},
+		{
+			name:     "Fetch without url",
+			args:     []string{"-addr", addr, "fetch"},
+			wantCode: exitUsage,
+		},
+		{
+			name:     "Fetch source unavailable",
+			args:     []string{"-addr", addr, "fetch", "-url", "http://nowhere/"},
+			fetchErr: status.Error(codes.Unavailable, "source unavailable"),
+			wantCode: exitUnavailable,
+		},
+		{
+			name:     "Fetch denied",
+			args:     []string{"-addr", addr, "fetch", "-url", "http://web-app:8085/products/"},
+			fetchErr: status.Error(codes.PermissionDenied, "permission denied"),
+			wantCode: exitAuth,
+		},
+		{
+			name:       "List csv",
+			args:       []string{"-addr", addr, "-o", "csv", "-api-key", "secret", "list", "-sort", "price", "-desc", "-offset", "5", "-limit", "10"},
+			wantCode:   exitOK,
+			wantStdout: "1;Apple;50.00\n2;Pear;60.00\n",
+			wantReq:    &grpcPb.ListRequest{SortField: grpcPb.ListRequest_price, SortAsc: -1, PagingOffset: 5, PagingLimit: 10},
+		},
+		{
+			name:       "List json with name filter",
+			args:       []string{"-addr", addr, "-o", "json", "list", "-name", "pear", "-include-deleted"},
+			wantCode:   exitOK,
+			wantStdout: `[{"source":"web-app","id":2,"name":"Pear","price":"60.00","deleted_at":"2025-01-02T03:04:05Z"}]` + "\n",
+			wantReq:    &grpcPb.ListRequest{SortField: grpcPb.ListRequest_id, SortAsc: 1, IncludeDeleted: true},
+		},
+		{
+			name:     "List unknown sort field",
+			args:     []string{"-addr", addr, "list", "-sort", "weight"},
+			wantCode: exitUsage,
+		},
+		{
+			name:     "Unknown format",
+			args:     []string{"-addr", addr, "-o", "xml", "list"},
+			wantCode: exitUsage,
+		},
+		{
+			name:     "Unknown command",
+			args:     []string{"-addr", addr, "delete"},
+			wantCode: exitUsage,
+		},
+		{
+			name:       "Export",
+			args:       []string{"-addr", addr, "export", "-sort", "name", "-source", "web-app"},
+			wantCode:   exitOK,
+			wantStdout: "1;Apple;50.00\n2;Pear;60.00\n",
+			wantReq:    &grpcPb.ListRequest{SortField: grpcPb.ListRequest_name, SortAsc: 1, Source: "web-app"},
+		},
+		{
+			name:     "Export unknown format",
+			args:     []string{"-addr", addr, "export", "-format", "xml"},
+			wantCode: exitUsage,
+		},
+		{
+			name:       "Watch",
+			args:       []string{"-addr", addr, "-o", "csv", "watch", "-interval", "10ms", "-count", "2"},
+			wantCode:   exitOK,
+			wantStdout: "1;Apple;50.00\n2;Pear;60.00\n1;Apple;50.00\n2;Pear;60.00\n",
+		},
+	}
+
+	for _, table := range testTables {
+		t.Run(table.name, func(t *testing.T) {
+			srv.fetchErr = table.fetchErr
+			srv.listReq = nil
+			var stdout, stderr bytes.Buffer
+
+			code := run(context.Background(), table.args, &stdout, &stderr)
+
+			assert.Equal(t, table.wantCode, code, stderr.String())
+			if table.wantStdout != "" {
+				assert.Equal(t, table.wantStdout, stdout.String())
+			}
+			if table.wantReq != nil {
+				assert.Equal(t, table.wantReq.String(), srv.listReq.String())
+			}
+		})
+	}
+
+	assert.Equal(t, "secret", srv.apiKey)
+}
+
+func TestWriteProductsTable(t *testing.T) {
+	var out bytes.Buffer
+	deletedAt := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	err := writeProducts(&out, formatTable, []product{
+		{Source: "web-app", Id: 1, Name: "Apple", Price: "50.00"},
+		{Source: "supplier", Id: 22, Name: "Pear", Price: "60.00", DeletedAt: &deletedAt},
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "SOURCE    ID  NAME   PRICE  DELETED AT\n"+
+		"web-app   1   Apple  50.00  \n"+
+		"supplier  22  Pear   60.00  2025-01-02T03:04:05Z\n", out.String())
+
+	var decoded []product
+	out.Reset()
+	assert.NoError(t, writeProducts(&out, formatJSON, nil))
+	assert.NoError(t, json.Unmarshal(out.Bytes(), &decoded))
+	assert.Empty(t, decoded)
+}
+
+// pagedClient serves List pages out of products.
+type pagedClient struct {
+	grpcPb.SortServiceClient
+	products []*grpcPb.Product
+	calls    int
+}
+
+func (c *pagedClient) List(ctx context.Context, req *grpcPb.ListRequest, opts ...grpc.CallOption) (*grpcPb.ListResponce, error) {
+	c.calls++
+	page := c.products[min(int(req.GetPagingOffset()), len(c.products)):]
+	if limit := int(req.GetPagingLimit()); limit > 0 && limit < len(page) {
+		page = page[:limit]
+	}
+	return &grpcPb.ListResponce{Product: page}, nil
+}
+
+func TestListMatching(t *testing.T) {
+	names := []string{"Apple", "Pear", "Apple juice", "Plum", "Apple pie", "Pineapple"}
+	var products []*grpcPb.Product
+	for i, name := range names {
+		products = append(products, &grpcPb.Product{Id: int64(i + 1), Name: name, Price: "1"})
+	}
+
+	testTables := []struct {
+		name      string
+		list      listFlags
+		req       *grpcPb.ListRequest
+		wantIds   []int64
+		wantCalls int
+	}{
+		{name: "Limit filled across pages", list: listFlags{name: "apple"}, req: &grpcPb.ListRequest{PagingLimit: 2}, wantIds: []int64{1, 3}, wantCalls: 2},
+		{name: "Catalog ends first", list: listFlags{name: "apple"}, req: &grpcPb.ListRequest{PagingLimit: 3, PagingOffset: 3}, wantIds: []int64{5, 6}, wantCalls: 2},
+		{name: "No limit", list: listFlags{name: "apple"}, req: &grpcPb.ListRequest{}, wantIds: []int64{1, 3, 5, 6}, wantCalls: 1},
+		{name: "No matches", list: listFlags{name: "kiwi"}, req: &grpcPb.ListRequest{PagingLimit: 4}, wantIds: []int64{}, wantCalls: 2},
+		{name: "Without name", req: &grpcPb.ListRequest{PagingLimit: 2}, wantIds: []int64{1, 2}, wantCalls: 1},
+	}
+
+	for _, table := range testTables {
+		t.Run(table.name, func(t *testing.T) {
+			client := &pagedClient{products: products}
+
+			got, err := table.list.listMatching(context.Background(), client, table.req)
+
+			require.NoError(t, err)
+			ids := []int64{}
+			for _, p := range got {
+				ids = append(ids, p.Id)
+			}
+			assert.Equal(t, table.wantIds, ids)
+			assert.Equal(t, table.wantCalls, client.calls)
+		})
+	}
+}
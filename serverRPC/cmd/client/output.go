@@ -0,0 +1,89 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"gRPC-server/pkg/parseCSV/grpcPb"
+	"io"
+	"strconv"
+	"text/tabwriter"
+	"time"
+)
+
+const (
+	formatTable = "table"
+	formatJSON  = "json"
+	formatCSV   = "csv"
+)
+
+// product is how a product looks in JSON output, deleted_at only for soft-deleted ones.
+type product struct {
+	Source    string     `json:"source"`
+	Id        int64      `json:"id"`
+	Name      string     `json:"name"`
+	Price     string     `json:"price"`
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+}
+
+func toProducts(in []*grpcPb.Product) []product {
+	out := make([]product, 0, len(in))
+	for _, p := range in {
+		item := product{Source: p.GetSource(), Id: p.GetId(), Name: p.GetName(), Price: p.GetPrice()}
+		if p.GetDeletedAt() != nil {
+			deletedAt := p.GetDeletedAt().AsTime()
+			item.DeletedAt = &deletedAt
+		}
+		out = append(out, item)
+	}
+	return out
+}
+
+func validFormat(format string) bool {
+	return format == formatTable || format == formatJSON || format == formatCSV
+}
+
+func writeProducts(w io.Writer, format string, products []product) error {
+	switch format {
+	case formatJSON:
+		return json.NewEncoder(w).Encode(products)
+	case formatCSV:
+		// тот же формат, что отдаёт web-app: id;name;price
+		writer := csv.NewWriter(w)
+		writer.Comma = ';'
+		for _, p := range products {
+			if err := writer.Write([]string{strconv.FormatInt(p.Id, 10), p.Name, p.Price}); err != nil {
+				return err
+			}
+		}
+		writer.Flush()
+		return writer.Error()
+	default:
+		table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(table, "SOURCE\tID\tNAME\tPRICE\tDELETED AT")
+		for _, p := range products {
+			deletedAt := ""
+			if p.DeletedAt != nil {
+				deletedAt = p.DeletedAt.Format(time.RFC3339)
+			}
+			fmt.Fprintf(table, "%s\t%d\t%s\t%s\t%s\n", p.Source, p.Id, p.Name, p.Price, deletedAt)
+		}
+		return table.Flush()
+	}
+}
+
+func writeStatus(w io.Writer, format, status string) error {
+	switch format {
+	case formatJSON:
+		return json.NewEncoder(w).Encode(map[string]string{"status": status})
+	case formatCSV:
+		writer := csv.NewWriter(w)
+		writer.Comma = ';'
+		writer.Write([]string{status})
+		writer.Flush()
+		return writer.Error()
+	default:
+		_, err := fmt.Fprintln(w, status)
+		return err
+	}
+}
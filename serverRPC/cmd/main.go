@@ -3,9 +3,14 @@ package main
 import (
 	"context"
 	"fmt"
+	"gRPC-server/internal/gateway"
+	"gRPC-server/internal/metrics"
 	"gRPC-server/internal/repository"
+	"gRPC-server/internal/scheduler"
 	"gRPC-server/internal/server"
 	"gRPC-server/internal/service"
+	"gRPC-server/internal/sources"
+	"gRPC-server/internal/tracing"
 	"gRPC-server/pkg/logger"
 	"log"
 	"os"
@@ -16,6 +21,8 @@ import (
 	"github.com/spf13/viper"
 )
 
+const defaultShutdownTimeout = 5 * time.Second
+
 func init() {
 	viper.AddConfigPath("configs")
 	viper.SetConfigName("config")
@@ -28,20 +35,51 @@ func init() {
 func main() {
 	logger := logger.GetLogger()
 
-	db, err := repository.NewMongoConnect()
+	shutdownTracing, err := tracing.Init(context.Background())
+	if err != nil {
+		logger.Error(err)
+		log.Fatal(err)
+	}
 
+	registry, err := sources.FromConfig()
 	if err != nil {
 		logger.Error(err)
 		log.Fatal(err)
 	}
 
-	mongo := repository.MongoInit(db, logger)
-	repo := repository.NewRepo(mongo, logger)
-	service := service.NewService(repo, logger)
+	openCtx, cancelOpen := context.WithTimeout(context.Background(), 30*time.Second)
+	backend, err := repository.Open(openCtx, registry.Default(), logger)
+	cancelOpen()
+	if err != nil {
+		logger.Error(err)
+		log.Fatal(err)
+	}
+	repo := repository.NewRepo(backend, logger)
+	service := service.NewService(repo, registry, logger)
 	sortService := server.NewSortServerService(service, logger)
-	server := server.NewGrpcServer(sortService, logger)
+	server, err := server.NewGrpcServer(sortService, service, logger)
+	if err != nil {
+		logger.Error(err)
+		log.Fatal(err)
+	}
+	metricsServer := metrics.NewServer(logger)
+	gatewayServer, err := gateway.NewServer(logger)
+	if err != nil {
+		logger.Error(err)
+		log.Fatal(err)
+	}
 
 	go server.ListenAndServer()
+	if metricsServer != nil {
+		go metricsServer.ListenAndServe()
+	}
+	if gatewayServer != nil {
+		go gatewayServer.ListenAndServe()
+	}
+	jobs := scheduler.New(service, service, logger)
+	if jobs != nil {
+		jobs.Start()
+	}
 
 	fmt.Println("Server started on port 8889")
 	quit := make(chan os.Signal, 1)
@@ -49,9 +87,32 @@ func main() {
 	<-quit
 	log.Println("Shutdown Server ...")
 
-	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	// each component has its own budget, so a slow one can't use up the time of those after it
+	if gatewayServer != nil {
+		shutdown(logger, "gateway", "Gateway shutdown error", gatewayServer.Shutdown)
+	}
+	if jobs != nil {
+		shutdown(logger, "scheduler", "Scheduler shutdown error", jobs.Shutdown)
+	}
+	shutdown(logger, "grpc", "Shutdown error", server.GracefulShutDown)
+	// after the gRPC server, so imports it was still finishing get their alerts queued
+	shutdown(logger, "alerts", "Alerts shutdown error", service.Shutdown)
+	if metricsServer != nil {
+		shutdown(logger, "metrics", "Metrics shutdown error", metricsServer.Shutdown)
+	}
+	shutdown(logger, "tracing", "Tracing shutdown error", shutdownTracing)
+}
+
+// shutdown stops one component within shutdown.<component> of the config.
+func shutdown(logger *logger.Logger, component, message string, stop func(ctx context.Context) error) {
+	timeout := viper.GetDuration("shutdown." + component)
+	if timeout <= 0 {
+		timeout = defaultShutdownTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
 	defer cancel()
-	if err := server.GracefulShutDown(ctx); err != nil {
-		logger.Error(fmt.Sprintf("Shutdown error: %s", err))
+
+	if err := stop(ctx); err != nil {
+		logger.Error(fmt.Sprintf("%s: %s", message, err))
 	}
 }
@@ -0,0 +1,269 @@
+package alerts
+
+import (
+	"context"
+	"encoding/json"
+	"gRPC-server/internal/domain"
+	"gRPC-server/pkg/logger"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type fakeStore struct {
+	mu      sync.Mutex
+	rules   []domain.AlertRule
+	letters []domain.DeadLetter
+}
+
+func (f *fakeStore) ListAlertRules(ctx context.Context) ([]domain.AlertRule, error) {
+	return f.rules, nil
+}
+
+func (f *fakeStore) InsertDeadLetter(ctx context.Context, letter domain.DeadLetter) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.letters = append(f.letters, letter)
+	return nil
+}
+
+func (f *fakeStore) deadLetters() []domain.DeadLetter {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]domain.DeadLetter(nil), f.letters...)
+}
+
+func price(s string) primitive.Decimal128 {
+	d, _ := primitive.ParseDecimal128(s)
+	return d
+}
+
+func update(source string, id int, oldPrice, newPrice string) domain.ProductUpdate {
+	return domain.ProductUpdate{
+		Old: domain.Product{Source: source, Id: id, Name: "Milk", Price: price(oldPrice)},
+		New: domain.Product{Source: source, Id: id, Name: "Milk", Price: price(newPrice)},
+	}
+}
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		name    string
+		rule    domain.AlertRule
+		update  domain.ProductUpdate
+		match   bool
+		percent *float64
+	}{
+		{
+			name:    "any change",
+			rule:    domain.AlertRule{},
+			update:  update("default", 1, "100", "90"),
+			match:   true,
+			percent: ptr(-10),
+		},
+		{
+			name:   "other source",
+			rule:   domain.AlertRule{Source: "shop"},
+			update: update("default", 1, "100", "90"),
+		},
+		{
+			name:   "other product",
+			rule:   domain.AlertRule{ProductId: 2},
+			update: update("default", 1, "100", "90"),
+		},
+		{
+			name:   "wrong direction",
+			rule:   domain.AlertRule{Direction: domain.DirectionUp},
+			update: update("default", 1, "100", "90"),
+		},
+		{
+			name:   "name change only",
+			rule:   domain.AlertRule{Direction: domain.DirectionDown},
+			update: update("default", 1, "100", "100"),
+		},
+		{
+			name:   "threshold is strict",
+			rule:   domain.AlertRule{MinChangePercent: 10},
+			update: update("default", 1, "100", "110"),
+		},
+		{
+			name:    "over threshold",
+			rule:    domain.AlertRule{MinChangePercent: 10, Direction: domain.DirectionUp},
+			update:  update("default", 1, "100", "110.01"),
+			match:   true,
+			percent: ptr(10.01),
+		},
+		{
+			name:   "from zero",
+			rule:   domain.AlertRule{MinChangePercent: 50},
+			update: update("default", 1, "0", "5"),
+			match:  true,
+		},
+	}
+
+	at := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			event, ok := Match(tt.rule, tt.update, at)
+			assert.Equal(t, tt.match, ok)
+			if ok {
+				assert.Equal(t, tt.percent, event.ChangePercent)
+				assert.Equal(t, tt.update.New.Price.String(), event.NewPrice)
+				assert.Equal(t, at, event.ChangedAt)
+			}
+		})
+	}
+}
+
+func ptr(v float64) *float64 {
+	return &v
+}
+
+func testOptions() options {
+	return options{
+		workers:     1,
+		queueSize:   10,
+		maxAttempts: 3,
+		backoff:     time.Millisecond,
+		maxBackoff:  time.Millisecond,
+		timeout:     time.Second,
+		// receivers are httptest servers on loopback
+		allowPrivate: true,
+	}
+}
+
+func TestDeliver(t *testing.T) {
+	var body []byte
+	var signature string
+	received := make(chan struct{})
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = io.ReadAll(r.Body)
+		signature = r.Header.Get(SignatureHeader)
+		assert.NotEmpty(t, r.Header.Get(DeliveryHeader))
+		close(received)
+	}))
+	defer receiver.Close()
+
+	store := &fakeStore{rules: []domain.AlertRule{{Name: "milk", WebhookUrl: receiver.URL, Secret: "key"}}}
+	dispatcher := newDispatcher(store, testOptions(), logger.GetLogger())
+	dispatcher.Notify(context.Background(), []domain.ProductUpdate{update("default", 1, "100", "90")})
+	<-received
+	assert.NoError(t, dispatcher.Shutdown(context.Background()))
+
+	assert.True(t, Verify("key", body, signature))
+	assert.False(t, Verify("other", body, signature))
+	var event Event
+	assert.NoError(t, json.Unmarshal(body, &event))
+	assert.Equal(t, "milk", event.RuleName)
+	assert.Equal(t, "90", event.NewPrice)
+	assert.Empty(t, store.deadLetters())
+}
+
+func TestDeliverRetries(t *testing.T) {
+	var calls atomic.Int32
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer receiver.Close()
+
+	store := &fakeStore{rules: []domain.AlertRule{{WebhookUrl: receiver.URL}}}
+	dispatcher := newDispatcher(store, testOptions(), logger.GetLogger())
+	dispatcher.Notify(context.Background(), []domain.ProductUpdate{update("default", 1, "100", "90")})
+	assert.NoError(t, dispatcher.Shutdown(context.Background()))
+
+	assert.Equal(t, int32(3), calls.Load())
+	assert.Empty(t, store.deadLetters())
+}
+
+func TestDeliverDeadLetter(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		attempts int
+	}{
+		{name: "client error is not retried", status: http.StatusBadRequest, attempts: 1},
+		{name: "attempts exhausted", status: http.StatusInternalServerError, attempts: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls atomic.Int32
+			receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls.Add(1)
+				w.WriteHeader(tt.status)
+			}))
+			defer receiver.Close()
+
+			rule := domain.AlertRule{Id: primitive.NewObjectID(), WebhookUrl: receiver.URL}
+			store := &fakeStore{rules: []domain.AlertRule{rule}}
+			dispatcher := newDispatcher(store, testOptions(), logger.GetLogger())
+			dispatcher.Notify(context.Background(), []domain.ProductUpdate{update("default", 1, "100", "90")})
+			assert.NoError(t, dispatcher.Shutdown(context.Background()))
+
+			assert.Equal(t, int32(tt.attempts), calls.Load())
+			letters := store.deadLetters()
+			if assert.Len(t, letters, 1) {
+				assert.Equal(t, rule.Id, letters[0].RuleId)
+				assert.Equal(t, tt.attempts, letters[0].Attempts)
+				assert.Contains(t, letters[0].Payload, `"product_id":1`)
+			}
+		})
+	}
+}
+
+func TestDeliverBlocked(t *testing.T) {
+	var calls atomic.Int32
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		http.Redirect(w, r, "http://169.254.169.254/latest/meta-data", http.StatusFound)
+	}))
+	defer receiver.Close()
+
+	testTables := []struct {
+		name         string
+		allowPrivate bool
+		wantError    string
+		wantCalls    int32
+	}{
+		{name: "Private address", allowPrivate: false, wantError: "webhook address is not allowed: 127.0.0.1", wantCalls: 0},
+		{name: "Redirect", allowPrivate: true, wantError: "webhook responded 302 Found", wantCalls: 1},
+	}
+
+	for _, table := range testTables {
+		t.Run(table.name, func(t *testing.T) {
+			calls.Store(0)
+			opts := testOptions()
+			opts.allowPrivate = table.allowPrivate
+
+			store := &fakeStore{rules: []domain.AlertRule{{Name: "milk", WebhookUrl: receiver.URL, Secret: "key"}}}
+			dispatcher := newDispatcher(store, opts, logger.GetLogger())
+			dispatcher.Notify(context.Background(), []domain.ProductUpdate{update("default", 1, "100", "90")})
+			assert.NoError(t, dispatcher.Shutdown(context.Background()))
+
+			letters := store.deadLetters()
+			if assert.Len(t, letters, 1) {
+				assert.Contains(t, letters[0].LastError, table.wantError)
+				assert.Equal(t, 1, letters[0].Attempts)
+			}
+			assert.Equal(t, table.wantCalls, calls.Load())
+		})
+	}
+}
+
+func TestBlocked(t *testing.T) {
+	for _, addr := range []string{"127.0.0.1", "10.1.2.3", "192.168.0.1", "169.254.169.254", "100.64.0.1", "::1", "fd00::1", "fe80::1", "::ffff:127.0.0.1", "0.0.0.0"} {
+		assert.True(t, blocked(netip.MustParseAddr(addr)), addr)
+	}
+	for _, addr := range []string{"93.184.216.34", "2606:2800:220:1::1"} {
+		assert.False(t, blocked(netip.MustParseAddr(addr)), addr)
+	}
+}
@@ -0,0 +1,319 @@
+package alerts
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gRPC-server/internal/domain"
+	"gRPC-server/internal/metrics"
+	"gRPC-server/pkg/logger"
+	"io"
+	mathrand "math/rand/v2"
+	"net"
+	"net/http"
+	"net/netip"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// Store is the part of the storage the dispatcher needs.
+type Store interface {
+	ListAlertRules(ctx context.Context) ([]domain.AlertRule, error)
+	InsertDeadLetter(ctx context.Context, letter domain.DeadLetter) error
+}
+
+type options struct {
+	workers     int
+	queueSize   int
+	maxAttempts int
+	backoff     time.Duration
+	maxBackoff  time.Duration
+	timeout     time.Duration
+	// allowPrivate lets webhooks reach loopback, private and link-local addresses
+	allowPrivate bool
+}
+
+type delivery struct {
+	id   string
+	rule domain.AlertRule
+	body []byte
+}
+
+// Dispatcher delivers matching price changes to webhooks in the background, an import
+// never waits for a receiver.
+type Dispatcher struct {
+	store  Store
+	logger *logger.Logger
+	client *http.Client
+	opts   options
+
+	mu     sync.RWMutex
+	closed bool
+	jobs   chan delivery
+	stop   chan struct{}
+	wg     sync.WaitGroup
+}
+
+// New starts the workers, it returns nil when alerts.enabled is off.
+func New(store Store, logger *logger.Logger) *Dispatcher {
+	if !viper.GetBool("alerts.enabled") {
+		return nil
+	}
+	return newDispatcher(store, options{
+		workers:     viper.GetInt("alerts.workers"),
+		queueSize:   viper.GetInt("alerts.queue_size"),
+		maxAttempts: viper.GetInt("alerts.max_attempts"),
+		backoff:     viper.GetDuration("alerts.backoff"),
+		maxBackoff:  viper.GetDuration("alerts.max_backoff"),
+		timeout:     viper.GetDuration("alerts.timeout"),
+
+		allowPrivate: viper.GetBool("alerts.allow_private_networks"),
+	}, logger)
+}
+
+func newDispatcher(store Store, opts options, logger *logger.Logger) *Dispatcher {
+	if opts.workers <= 0 {
+		opts.workers = 1
+	}
+	if opts.queueSize < 0 {
+		opts.queueSize = 0
+	}
+	if opts.maxAttempts <= 0 {
+		opts.maxAttempts = 1
+	}
+	if opts.maxBackoff < opts.backoff {
+		opts.maxBackoff = opts.backoff
+	}
+	if opts.timeout <= 0 {
+		opts.timeout = 10 * time.Second
+	}
+
+	d := &Dispatcher{
+		store:  store,
+		logger: logger,
+		client: newClient(opts),
+		opts:   opts,
+		jobs:   make(chan delivery, opts.queueSize),
+		stop:   make(chan struct{}),
+	}
+	for i := 0; i < opts.workers; i++ {
+		d.wg.Add(1)
+		go d.work()
+	}
+	return d
+}
+
+// errBlockedAddress is returned for a webhook resolving to an address the server must not call.
+var errBlockedAddress = errors.New("webhook address is not allowed")
+
+// newClient checks the address after name resolution, so a public name pointing to an internal
+// address is blocked too, and doesn't follow redirects, which could lead anywhere.
+func newClient(opts options) *http.Client {
+	dialer := &net.Dialer{Timeout: opts.timeout}
+	if !opts.allowPrivate {
+		dialer.Control = func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip, err := netip.ParseAddr(host); err != nil || blocked(ip) {
+				return fmt.Errorf("%w: %s", errBlockedAddress, host)
+			}
+			return nil
+		}
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	// through a proxy the dialer would only see the proxy address
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &http.Client{
+		Timeout:   opts.timeout,
+		Transport: transport,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// blocked covers loopback, private, link-local (cloud metadata lives there) and other addresses
+// that are not a public unicast host.
+func blocked(ip netip.Addr) bool {
+	ip = ip.Unmap()
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified() || sharedAddressSpace.Contains(ip)
+}
+
+// sharedAddressSpace is the carrier-grade NAT range, internal to providers like private ranges.
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+// Notify queues an event for every rule each update matches. Rules are read on every call,
+// so a rule created between imports applies to the next one.
+func (d *Dispatcher) Notify(ctx context.Context, updates []domain.ProductUpdate) {
+	if len(updates) == 0 {
+		return
+	}
+	rules, err := d.store.ListAlertRules(ctx)
+	if err != nil {
+		d.logger.FromContext(ctx).Errorf("Can't load alert rules: %s", err)
+		return
+	}
+
+	now := time.Now()
+	for _, update := range updates {
+		for _, rule := range rules {
+			event, ok := Match(rule, update, now)
+			if !ok {
+				continue
+			}
+			body, err := json.Marshal(event)
+			if err != nil {
+				d.logger.FromContext(ctx).Errorf("Can't encode alert event: %s", err)
+				continue
+			}
+			d.enqueue(ctx, delivery{id: deliveryID(), rule: rule, body: body})
+		}
+	}
+}
+
+// enqueue never blocks, with the queue full the delivery goes straight to the dead letters.
+func (d *Dispatcher) enqueue(ctx context.Context, job delivery) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	if !d.closed {
+		select {
+		case d.jobs <- job:
+			return
+		default:
+		}
+	}
+	d.deadLetter(ctx, job, 0, fmt.Errorf("delivery queue is full"))
+}
+
+func (d *Dispatcher) work() {
+	defer d.wg.Done()
+	for job := range d.jobs {
+		d.deliver(job)
+	}
+}
+
+func (d *Dispatcher) deliver(job delivery) {
+	log := d.logger.WithField("alert_rule", job.rule.Id.Hex())
+
+	var err error
+	attempt := 1
+	for ; ; attempt++ {
+		var retry bool
+		retry, err = d.send(job)
+		if err == nil {
+			metrics.AlertDeliveries.WithLabelValues("delivered").Inc()
+			return
+		}
+		if !retry || attempt >= d.opts.maxAttempts {
+			break
+		}
+
+		metrics.AlertDeliveries.WithLabelValues("retried").Inc()
+		log.Warnf("Webhook delivery %s attempt %d failed, retrying: %s", job.id, attempt, err)
+		select {
+		case <-time.After(d.backoff(attempt)):
+		case <-d.stop:
+			d.deadLetter(context.Background(), job, attempt, fmt.Errorf("shutting down: %w", err))
+			return
+		}
+	}
+
+	log.Errorf("Webhook delivery %s failed after %d attempts: %s", job.id, attempt, err)
+	d.deadLetter(context.Background(), job, attempt, err)
+}
+
+// send makes one attempt; network errors, 429 and 5xx are worth retrying, other answers are not.
+// A redirect is an answer too, it isn't followed.
+func (d *Dispatcher) send(job delivery) (bool, error) {
+	req, err := http.NewRequest(http.MethodPost, job.rule.WebhookUrl, bytes.NewReader(job.body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(DeliveryHeader, job.id)
+	req.Header.Set(SignatureHeader, Sign(job.rule.Secret, job.body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return !errors.Is(err, errBlockedAddress), err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+	retry := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+	return retry, fmt.Errorf("webhook responded %s", resp.Status)
+}
+
+// backoff doubles from alerts.backoff up to alerts.max_backoff with up to 20% of jitter.
+func (d *Dispatcher) backoff(attempt int) time.Duration {
+	delay := d.opts.backoff
+	for i := 1; i < attempt && delay < d.opts.maxBackoff; i++ {
+		delay *= 2
+	}
+	delay = min(delay, d.opts.maxBackoff)
+	if delay <= 0 {
+		return 0
+	}
+	return delay + mathrand.N(delay/5+1)
+}
+
+func (d *Dispatcher) deadLetter(ctx context.Context, job delivery, attempts int, err error) {
+	metrics.AlertDeliveries.WithLabelValues("dead_letter").Inc()
+	letter := domain.DeadLetter{
+		RuleId:     job.rule.Id,
+		WebhookUrl: job.rule.WebhookUrl,
+		Payload:    string(job.body),
+		Attempts:   attempts,
+		LastError:  err.Error(),
+		FailedAt:   time.Now(),
+	}
+	if err := d.store.InsertDeadLetter(context.WithoutCancel(ctx), letter); err != nil {
+		d.logger.FromContext(ctx).Errorf("Lost webhook delivery %s: %s", job.id, err)
+	}
+}
+
+// Shutdown stops taking events and waits for queued deliveries. When ctx expires first,
+// deliveries waiting for a retry are moved to the dead letters instead.
+func (d *Dispatcher) Shutdown(ctx context.Context) error {
+	d.mu.Lock()
+	if !d.closed {
+		d.closed = true
+		close(d.jobs)
+	}
+	d.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		d.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		close(d.stop)
+		return ctx.Err()
+	}
+}
+
+func deliveryID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
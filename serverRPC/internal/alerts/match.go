@@ -0,0 +1,107 @@
+package alerts
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"gRPC-server/internal/domain"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// SignatureHeader carries "sha256=" and the hex HMAC-SHA256 of the body keyed with the rule's secret.
+const SignatureHeader = "X-Alert-Signature"
+
+// DeliveryHeader is the same for every attempt of a delivery, receivers can use it to drop repeats.
+const DeliveryHeader = "X-Alert-Delivery"
+
+// Event is the JSON body of a webhook call.
+type Event struct {
+	RuleId    string `json:"rule_id"`
+	RuleName  string `json:"rule_name"`
+	Source    string `json:"source"`
+	ProductId int    `json:"product_id"`
+	OldName   string `json:"old_name"`
+	Name      string `json:"name"`
+	OldPrice  string `json:"old_price"`
+	NewPrice  string `json:"new_price"`
+	// ChangePercent is null when the old price was zero
+	ChangePercent *float64  `json:"change_percent"`
+	ChangedAt     time.Time `json:"changed_at"`
+}
+
+// Match tells whether the update is something rule is waiting for and builds the event for it.
+func Match(rule domain.AlertRule, update domain.ProductUpdate, at time.Time) (Event, bool) {
+	if rule.Source != "" && rule.Source != update.New.Source {
+		return Event{}, false
+	}
+	if rule.ProductId != 0 && rule.ProductId != update.New.Id {
+		return Event{}, false
+	}
+
+	oldPrice, errOld := decimal.NewFromString(update.Old.Price.String())
+	newPrice, errNew := decimal.NewFromString(update.New.Price.String())
+	if errOld != nil || errNew != nil {
+		return Event{}, false
+	}
+	delta := newPrice.Sub(oldPrice)
+
+	switch rule.Direction {
+	case domain.DirectionUp:
+		if !delta.IsPositive() {
+			return Event{}, false
+		}
+	case domain.DirectionDown:
+		if !delta.IsNegative() {
+			return Event{}, false
+		}
+	}
+
+	var percent *float64
+	if !oldPrice.IsZero() {
+		p := delta.Div(oldPrice).Mul(decimal.NewFromInt(100)).Round(2).InexactFloat64()
+		percent = &p
+	}
+	if rule.MinChangePercent > 0 {
+		if delta.IsZero() {
+			return Event{}, false
+		}
+		// a price going up from zero has moved by any percent
+		if percent != nil && abs(*percent) <= rule.MinChangePercent {
+			return Event{}, false
+		}
+	}
+
+	return Event{
+		RuleId:        rule.Id.Hex(),
+		RuleName:      rule.Name,
+		Source:        update.New.Source,
+		ProductId:     update.New.Id,
+		OldName:       update.Old.Name,
+		Name:          update.New.Name,
+		OldPrice:      update.Old.Price.String(),
+		NewPrice:      update.New.Price.String(),
+		ChangePercent: percent,
+		ChangedAt:     at,
+	}, true
+}
+
+func abs(v float64) float64 {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+// Sign returns the value of SignatureHeader for body.
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks a SignatureHeader value in constant time, for receivers written in Go.
+func Verify(secret string, body []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, body)), []byte(signature))
+}
@@ -0,0 +1,193 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/spf13/viper"
+	"google.golang.org/grpc/metadata"
+)
+
+const (
+	APIKeyHeader        = "x-api-key"
+	AuthorizationHeader = "authorization"
+)
+
+var (
+	// ErrNoCredentials means the call carried neither an api key nor a bearer token.
+	ErrNoCredentials  = errors.New("no credentials")
+	ErrBadCredentials = errors.New("invalid credentials")
+	ErrForbidden      = errors.New("caller has no role allowed for this method")
+)
+
+// Identity is the authenticated caller.
+type Identity struct {
+	Subject string
+	Roles   []string
+	// Kind is how the caller authenticated: "api_key" or "jwt".
+	Kind string
+}
+
+func (i *Identity) HasRole(roles ...string) bool {
+	for _, want := range roles {
+		for _, role := range i.Roles {
+			if role == want {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+type identityKey struct{}
+
+func WithIdentity(ctx context.Context, identity *Identity) context.Context {
+	return context.WithValue(ctx, identityKey{}, identity)
+}
+
+// FromContext returns the caller stored by the auth interceptor, nil when auth is off.
+func FromContext(ctx context.Context) *Identity {
+	identity, _ := ctx.Value(identityKey{}).(*Identity)
+	return identity
+}
+
+// Authenticator checks one kind of credentials. It returns ErrNoCredentials when the call
+// doesn't carry its kind, so the next authenticator can try.
+type Authenticator interface {
+	Authenticate(ctx context.Context, md metadata.MD) (*Identity, error)
+}
+
+// MethodRule says who may call a method. Method is a full method name ("/grpcPb.SortService/Fetch")
+// or a whole service ("grpc.reflection.v1.ServerReflection"); Open lets in any authenticated caller,
+// otherwise the caller needs one of Roles.
+type MethodRule struct {
+	Method string   `mapstructure:"method"`
+	Roles  []string `mapstructure:"roles"`
+	Open   bool     `mapstructure:"open"`
+}
+
+// Authorizer authenticates the caller and checks the roles configured for the method.
+type Authorizer struct {
+	authenticators []Authenticator
+	// methods maps a lower case full method or service name to its rule. A method that matches
+	// no rule is denied, so a newly added RPC stays closed until it is configured.
+	methods map[string]MethodRule
+	public  map[string]bool
+}
+
+func NewAuthorizer(methods []MethodRule, public []string, authenticators ...Authenticator) *Authorizer {
+	a := &Authorizer{
+		authenticators: authenticators,
+		methods:        map[string]MethodRule{},
+		public:         map[string]bool{},
+	}
+	for _, rule := range methods {
+		a.methods[strings.ToLower(rule.Method)] = rule
+	}
+	for _, method := range public {
+		a.public[strings.ToLower(method)] = true
+	}
+	return a
+}
+
+// NewFromConfig builds the Authorizer from the auth section of the config, nil if auth is disabled.
+func NewFromConfig() (*Authorizer, error) {
+	if !viper.GetBool("auth.enabled") {
+		return nil, nil
+	}
+
+	var authenticators []Authenticator
+
+	var keys []APIKey
+	if err := viper.UnmarshalKey("auth.api_keys", &keys); err != nil {
+		return nil, fmt.Errorf("auth.api_keys: %w", err)
+	}
+	if len(keys) > 0 {
+		authenticators = append(authenticators, NewAPIKeys(keys))
+	}
+
+	if file := viper.GetString("auth.jwks_file"); file != "" {
+		jwt, err := NewJWT(JWTOptions{
+			JWKSFile:   file,
+			Issuer:     viper.GetString("auth.issuer"),
+			Audience:   viper.GetString("auth.audience"),
+			RolesClaim: viper.GetString("auth.roles_claim"),
+		})
+		if err != nil {
+			return nil, err
+		}
+		authenticators = append(authenticators, jwt)
+	}
+
+	if len(authenticators) == 0 {
+		return nil, errors.New("auth is enabled but neither api_keys nor jwks_file is set")
+	}
+
+	var methods []MethodRule
+	if err := viper.UnmarshalKey("auth.methods", &methods); err != nil {
+		return nil, fmt.Errorf("auth.methods: %w", err)
+	}
+	for _, rule := range methods {
+		// a short name like "Fetch" would never match and silently deny the method
+		if !strings.Contains(rule.Method, ".") {
+			return nil, fmt.Errorf("auth.methods: %q is neither a full method nor a service name", rule.Method)
+		}
+	}
+
+	return NewAuthorizer(methods, viper.GetStringSlice("auth.public"), authenticators...), nil
+}
+
+// Public reports whether the method can be called without credentials. The public list may
+// name full methods ("/grpc.health.v1.Health/Check") or whole services ("grpc.health.v1.Health").
+func (a *Authorizer) Public(fullMethod string) bool {
+	fullMethod = strings.ToLower(fullMethod)
+	return a.public[fullMethod] || a.public[serviceOf(fullMethod)]
+}
+
+// rule returns the rule of the full method, one naming the method wins over one naming its service.
+func (a *Authorizer) rule(fullMethod string) (MethodRule, bool) {
+	fullMethod = strings.ToLower(fullMethod)
+	if rule, ok := a.methods[fullMethod]; ok {
+		return rule, true
+	}
+	rule, ok := a.methods[serviceOf(fullMethod)]
+	return rule, ok
+}
+
+func serviceOf(fullMethod string) string {
+	service, _ := path.Split(fullMethod)
+	return strings.Trim(service, "/")
+}
+
+// Authorize returns the caller of fullMethod. Errors wrap ErrNoCredentials, ErrBadCredentials or ErrForbidden.
+func (a *Authorizer) Authorize(ctx context.Context, fullMethod string) (*Identity, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+
+	var identity *Identity
+	for _, authenticator := range a.authenticators {
+		id, err := authenticator.Authenticate(ctx, md)
+		if errors.Is(err, ErrNoCredentials) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		identity = id
+		break
+	}
+	if identity == nil {
+		return nil, ErrNoCredentials
+	}
+
+	rule, ok := a.rule(fullMethod)
+	if !ok {
+		return identity, fmt.Errorf("%w: %s is not in auth.methods", ErrForbidden, fullMethod)
+	}
+	if !rule.Open && !identity.HasRole(rule.Roles...) {
+		return identity, fmt.Errorf("%w: %s needs one of %v", ErrForbidden, fullMethod, rule.Roles)
+	}
+	return identity, nil
+}
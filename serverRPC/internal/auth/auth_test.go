@@ -0,0 +1,226 @@
+package auth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/metadata"
+)
+
+func b64(i *big.Int) string {
+	return base64.RawURLEncoding.EncodeToString(i.Bytes())
+}
+
+// writeJWKS writes the public halves of the keys into a JWKS file and returns its path.
+func writeJWKS(t *testing.T, rsaKey *rsa.PrivateKey, ecKey *ecdsa.PrivateKey) string {
+	t.Helper()
+	set := map[string]interface{}{
+		"keys": []map[string]string{
+			{"kty": "RSA", "kid": "rsa-1", "use": "sig", "n": b64(rsaKey.N), "e": b64(big.NewInt(int64(rsaKey.E)))},
+			{"kty": "EC", "kid": "ec-1", "crv": "P-256", "x": b64(ecKey.X), "y": b64(ecKey.Y)},
+		},
+	}
+	data, err := json.Marshal(set)
+	require.NoError(t, err)
+	file := filepath.Join(t.TempDir(), "jwks.json")
+	require.NoError(t, os.WriteFile(file, data, 0o600))
+	return file
+}
+
+func sign(t *testing.T, method jwt.SigningMethod, kid string, key interface{}, claims jwt.MapClaims) string {
+	t.Helper()
+	token := jwt.NewWithClaims(method, claims)
+	token.Header["kid"] = kid
+	s, err := token.SignedString(key)
+	require.NoError(t, err)
+	return s
+}
+
+func TestAuthorize(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	jwtAuth, err := NewJWT(JWTOptions{JWKSFile: writeJWKS(t, rsaKey, ecKey), Issuer: "test-issuer"})
+	require.NoError(t, err)
+
+	authorizer := NewAuthorizer(
+		[]MethodRule{
+			{Method: "/grpcPb.SortService/Fetch", Roles: []string{"importer"}},
+			{Method: "/grpcPb.SortService/List", Roles: []string{"reader", "importer"}},
+			{Method: "/grpcPb.SortService/GetStats", Open: true},
+			{Method: "grpc.channelz.v1.Channelz", Roles: []string{"admin"}},
+		},
+		[]string{"grpc.health.v1.Health", "/grpc.reflection.v1.ServerReflection/ServerReflectionInfo"},
+		NewAPIKeys([]APIKey{
+			{Key: "reader-key", Subject: "dashboard", Roles: []string{"reader"}},
+			{Key: "importer-key", Subject: "cron", Roles: []string{"importer"}},
+		}),
+		jwtAuth,
+	)
+
+	exp := time.Now().Add(time.Hour).Unix()
+
+	tests := []struct {
+		name        string
+		md          metadata.MD
+		method      string
+		wantSubject string
+		wantErr     error
+	}{
+		{
+			name:        "Api key with role",
+			md:          metadata.Pairs(APIKeyHeader, "importer-key"),
+			method:      "/grpcPb.SortService/Fetch",
+			wantSubject: "cron",
+		},
+		{
+			name:        "Api key without role",
+			md:          metadata.Pairs(APIKeyHeader, "reader-key"),
+			method:      "/grpcPb.SortService/Fetch",
+			wantSubject: "dashboard",
+			wantErr:     ErrForbidden,
+		},
+		{
+			name:    "Unknown api key",
+			md:      metadata.Pairs(APIKeyHeader, "guess"),
+			method:  "/grpcPb.SortService/List",
+			wantErr: ErrBadCredentials,
+		},
+		{
+			name:    "No credentials",
+			md:      metadata.MD{},
+			method:  "/grpcPb.SortService/List",
+			wantErr: ErrNoCredentials,
+		},
+		{
+			name:        "Open method",
+			md:          metadata.Pairs(APIKeyHeader, "reader-key"),
+			method:      "/grpcPb.SortService/GetStats",
+			wantSubject: "dashboard",
+		},
+		{
+			name:        "Method not listed",
+			md:          metadata.Pairs(APIKeyHeader, "importer-key"),
+			method:      "/grpcPb.SortService/PurgeDeleted",
+			wantSubject: "cron",
+			wantErr:     ErrForbidden,
+		},
+		{
+			name:        "Same short name in another service",
+			md:          metadata.Pairs(APIKeyHeader, "importer-key"),
+			method:      "/other.Service/Fetch",
+			wantSubject: "cron",
+			wantErr:     ErrForbidden,
+		},
+		{
+			name:        "Rule for a whole service",
+			md:          metadata.Pairs(APIKeyHeader, "importer-key"),
+			method:      "/grpc.channelz.v1.Channelz/GetTopChannels",
+			wantSubject: "cron",
+			wantErr:     ErrForbidden,
+		},
+		{
+			name: "RSA token",
+			md: metadata.Pairs(AuthorizationHeader, "Bearer "+sign(t, jwt.SigningMethodRS256, "rsa-1", rsaKey, jwt.MapClaims{
+				"sub": "alice", "iss": "test-issuer", "exp": exp, "roles": []string{"reader"},
+			})),
+			method:      "/grpcPb.SortService/List",
+			wantSubject: "alice",
+		},
+		{
+			name: "EC token with space separated roles",
+			md: metadata.Pairs(AuthorizationHeader, "Bearer "+sign(t, jwt.SigningMethodES256, "ec-1", ecKey, jwt.MapClaims{
+				"sub": "bob", "iss": "test-issuer", "exp": exp, "roles": "reader importer",
+			})),
+			method:      "/grpcPb.SortService/Fetch",
+			wantSubject: "bob",
+		},
+		{
+			name: "Expired token",
+			md: metadata.Pairs(AuthorizationHeader, "Bearer "+sign(t, jwt.SigningMethodRS256, "rsa-1", rsaKey, jwt.MapClaims{
+				"sub": "alice", "iss": "test-issuer", "exp": time.Now().Add(-time.Minute).Unix(),
+			})),
+			method:  "/grpcPb.SortService/List",
+			wantErr: ErrBadCredentials,
+		},
+		{
+			name: "Wrong issuer",
+			md: metadata.Pairs(AuthorizationHeader, "Bearer "+sign(t, jwt.SigningMethodRS256, "rsa-1", rsaKey, jwt.MapClaims{
+				"sub": "alice", "iss": "someone-else", "exp": exp,
+			})),
+			method:  "/grpcPb.SortService/List",
+			wantErr: ErrBadCredentials,
+		},
+		{
+			name: "Token signed by unknown key",
+			md: metadata.Pairs(AuthorizationHeader, "Bearer "+sign(t, jwt.SigningMethodRS256, "rsa-1", otherKey, jwt.MapClaims{
+				"sub": "mallory", "iss": "test-issuer", "exp": exp, "roles": []string{"importer"},
+			})),
+			method:  "/grpcPb.SortService/Fetch",
+			wantErr: ErrBadCredentials,
+		},
+		{
+			name: "Unsigned token",
+			md: metadata.Pairs(AuthorizationHeader, "Bearer "+sign(t, jwt.SigningMethodNone, "rsa-1", jwt.UnsafeAllowNoneSignatureType, jwt.MapClaims{
+				"sub": "mallory", "iss": "test-issuer", "exp": exp, "roles": []string{"importer"},
+			})),
+			method:  "/grpcPb.SortService/Fetch",
+			wantErr: ErrBadCredentials,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctx := metadata.NewIncomingContext(context.Background(), test.md)
+			identity, err := authorizer.Authorize(ctx, test.method)
+
+			if test.wantErr != nil {
+				assert.ErrorIs(t, err, test.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+			if test.wantSubject != "" && assert.NotNil(t, identity) {
+				assert.Equal(t, test.wantSubject, identity.Subject)
+			}
+		})
+	}
+
+	assert.True(t, authorizer.Public("/grpc.health.v1.Health/Watch"))
+	assert.True(t, authorizer.Public("/grpc.reflection.v1.ServerReflection/ServerReflectionInfo"))
+	assert.False(t, authorizer.Public("/grpcPb.SortService/Watch"))
+	assert.False(t, authorizer.Public("/other.Service/ServerReflectionInfo"))
+}
+
+func TestLoadJWKSErrors(t *testing.T) {
+	dir := t.TempDir()
+
+	_, err := NewJWT(JWTOptions{JWKSFile: filepath.Join(dir, "missing.json")})
+	assert.Error(t, err)
+
+	file := filepath.Join(dir, "empty.json")
+	require.NoError(t, os.WriteFile(file, []byte(`{"keys":[]}`), 0o600))
+	_, err = NewJWT(JWTOptions{JWKSFile: file})
+	assert.Error(t, err)
+
+	file = filepath.Join(dir, "bad-curve.json")
+	require.NoError(t, os.WriteFile(file, []byte(`{"keys":[{"kty":"EC","kid":"x","crv":"P-256","x":"AQ","y":"AQ"}]}`), 0o600))
+	_, err = NewJWT(JWTOptions{JWKSFile: file})
+	assert.Error(t, err)
+}
@@ -0,0 +1,29 @@
+package cache
+
+import (
+	"context"
+	"gRPC-server/internal/domain"
+
+	"github.com/spf13/viper"
+)
+
+// Cache keeps List results by key. LRU is the in-process implementation; a shared store such as
+// Redis only has to implement the same three methods.
+type Cache interface {
+	Get(ctx context.Context, key string) ([]domain.Product, bool)
+	Set(ctx context.Context, key string, products []domain.Product)
+	// Clear drops every entry, it is called once a change to the catalog is committed
+	Clear(ctx context.Context)
+}
+
+// New returns the cache cache.list configures, nil unless it is enabled.
+func New() Cache {
+	if !viper.GetBool("cache.list.enabled") {
+		return nil
+	}
+	return NewLRU(
+		viper.GetInt("cache.list.size"),
+		viper.GetInt("cache.list.max_products"),
+		viper.GetDuration("cache.list.ttl"),
+	)
+}
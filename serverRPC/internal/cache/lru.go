@@ -0,0 +1,97 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"gRPC-server/internal/domain"
+	"slices"
+	"sync"
+	"time"
+)
+
+const defaultSize = 256
+
+// LRU keeps the size most recently used results in the memory of the process. Results longer
+// than maxProducts are not kept, with ttl an entry expires that long after it was set.
+type LRU struct {
+	mu          sync.Mutex
+	size        int
+	maxProducts int
+	ttl         time.Duration
+	now         func() time.Time
+
+	// order has the most recently used entry in front
+	order   *list.List
+	entries map[string]*list.Element
+}
+
+type entry struct {
+	key      string
+	products []domain.Product
+	expires  time.Time
+}
+
+func NewLRU(size, maxProducts int, ttl time.Duration) *LRU {
+	if size <= 0 {
+		size = defaultSize
+	}
+	return &LRU{
+		size:        size,
+		maxProducts: maxProducts,
+		ttl:         ttl,
+		now:         time.Now,
+		order:       list.New(),
+		entries:     map[string]*list.Element{},
+	}
+}
+
+// Get returns a copy of the slice, so a caller appending to it can't change the entry.
+func (c *LRU) Get(ctx context.Context, key string) ([]domain.Product, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	cached := element.Value.(*entry)
+	if c.ttl > 0 && !c.now().Before(cached.expires) {
+		c.order.Remove(element)
+		delete(c.entries, key)
+		return nil, false
+	}
+
+	c.order.MoveToFront(element)
+	return slices.Clone(cached.products), true
+}
+
+func (c *LRU) Set(ctx context.Context, key string, products []domain.Product) {
+	if c.maxProducts > 0 && len(products) > c.maxProducts {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	cached := &entry{key: key, products: slices.Clone(products), expires: c.now().Add(c.ttl)}
+	if element, ok := c.entries[key]; ok {
+		element.Value = cached
+		c.order.MoveToFront(element)
+		return
+	}
+
+	c.entries[key] = c.order.PushFront(cached)
+	for c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*entry).key)
+	}
+}
+
+func (c *LRU) Clear(ctx context.Context) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.order.Init()
+	clear(c.entries)
+}
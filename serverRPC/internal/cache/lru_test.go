@@ -0,0 +1,73 @@
+package cache
+
+import (
+	"context"
+	"gRPC-server/internal/domain"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLRU(t *testing.T) {
+	ctx := context.Background()
+	products := []domain.Product{{Id: 1, Name: "milk"}, {Id: 2, Name: "bread"}}
+
+	t.Run("Evicts least recently used", func(t *testing.T) {
+		c := NewLRU(2, 0, 0)
+		c.Set(ctx, "a", products)
+		c.Set(ctx, "b", products)
+		_, _ = c.Get(ctx, "a")
+		c.Set(ctx, "c", products)
+
+		_, ok := c.Get(ctx, "b")
+		assert.False(t, ok)
+		_, ok = c.Get(ctx, "a")
+		assert.True(t, ok)
+		_, ok = c.Get(ctx, "c")
+		assert.True(t, ok)
+	})
+
+	t.Run("Expires after ttl", func(t *testing.T) {
+		now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+		c := NewLRU(2, 0, time.Minute)
+		c.now = func() time.Time { return now }
+		c.Set(ctx, "a", products)
+
+		now = now.Add(59 * time.Second)
+		_, ok := c.Get(ctx, "a")
+		assert.True(t, ok)
+
+		now = now.Add(time.Second)
+		_, ok = c.Get(ctx, "a")
+		assert.False(t, ok)
+	})
+
+	t.Run("Skips long results", func(t *testing.T) {
+		c := NewLRU(2, 1, 0)
+		c.Set(ctx, "a", products)
+
+		_, ok := c.Get(ctx, "a")
+		assert.False(t, ok)
+	})
+
+	t.Run("Clear", func(t *testing.T) {
+		c := NewLRU(2, 0, 0)
+		c.Set(ctx, "a", products)
+		c.Clear(ctx)
+
+		_, ok := c.Get(ctx, "a")
+		assert.False(t, ok)
+	})
+
+	t.Run("Returns a copy", func(t *testing.T) {
+		c := NewLRU(2, 0, 0)
+		c.Set(ctx, "a", products)
+
+		got, _ := c.Get(ctx, "a")
+		got[0].Name = "changed"
+
+		got, _ = c.Get(ctx, "a")
+		assert.Equal(t, products, got)
+	})
+}
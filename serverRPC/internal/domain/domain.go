@@ -1,13 +1,60 @@
 package domain
 
 import (
+	"strings"
+	"time"
+
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+// Product is identified by Source and Id together, Id is whatever the source's CSV calls it.
 type Product struct {
-	Id    int                  `json:"Id" binding:"required"`
-	Name  string               `json:"Name" binding:"required"`
-	Price primitive.Decimal128 `json:"Price" binding:"required"`
+	Source    string               `json:"Source" bson:"source"`
+	Id        int                  `json:"Id" binding:"required"`
+	Name      string               `json:"Name" binding:"required"`
+	Price     primitive.Decimal128 `json:"Price" binding:"required"`
+	DeletedAt *time.Time           `json:"DeletedAt,omitempty" bson:"deleted_at,omitempty"`
+
+	DateOfChange time.Time `json:"DateOfChange" bson:"date_of_change,omitempty"`
+}
+
+type PriceChange struct {
+	Source    string               `bson:"source"`
+	Id        int                  `bson:"id"`
+	OldName   string               `bson:"old_name"`
+	NewName   string               `bson:"new_name"`
+	OldPrice  primitive.Decimal128 `bson:"old_price"`
+	NewPrice  primitive.Decimal128 `bson:"new_price"`
+	ChangedAt time.Time            `bson:"changed_at"`
+}
+
+const (
+	EventInsert = "insert"
+	EventUpdate = "update"
+	EventDelete = "delete"
+)
+
+// ProductUpdate is a product as stored and as an import would leave it.
+type ProductUpdate struct {
+	Old Product
+	New Product
+}
+
+// ImportDiff is what importing a CSV into Source would change.
+type ImportDiff struct {
+	Source    string
+	Created   []Product
+	Updated   []ProductUpdate
+	Deleted   []Product
+	Unchanged int
+	Rejected  []Rejection
+}
+
+type ProductEvent struct {
+	Type        string
+	Product     Product
+	ResumeToken string
+	Time        time.Time
 }
 
 type URL struct {
@@ -16,6 +63,16 @@ type URL struct {
 
 type Status struct {
 	Status string
+	// Rejected are the rows the import left out
+	Rejected []Rejection
+}
+
+// Rejection is a CSV row an import refused and the rule that refused it.
+type Rejection struct {
+	Id     int
+	Name   string
+	Rule   string
+	Reason string
 }
 
 type SortParams struct {
@@ -23,4 +80,133 @@ type SortParams struct {
 	SortAsc      int32
 	PagingOffset int32
 	PagingLimit  int32
+
+	IncludeDeleted bool
+
+	// Source limits the list to one source, without it products from all sources are merged by Merge
+	Source string
+	Merge  MergeRule
+}
+
+type StatsFilter struct {
+	Name     string
+	MinPrice *primitive.Decimal128
+	MaxPrice *primitive.Decimal128
+
+	HistogramBounds  []primitive.Decimal128
+	HistogramBuckets int32
+	ChangedDays      int32
+	ChangedSince     time.Time
+	TopChanged       int32
+}
+
+type Stats struct {
+	Total           int64
+	MinPrice        primitive.Decimal128
+	MaxPrice        primitive.Decimal128
+	AvgPrice        primitive.Decimal128
+	MedianPrice     primitive.Decimal128
+	Histogram       []HistogramBucket
+	ChangedRecently int64
+	MostChanged     []ProductChanges
+}
+
+type HistogramBucket struct {
+	LowerBound primitive.Decimal128
+	UpperBound primitive.Decimal128
+	Count      int64
+}
+
+type ProductChanges struct {
+	Product      `bson:",inline"`
+	ChangesCount int `bson:"changes_count"`
+}
+
+// CSVFormat describes how a source writes its CSV.
+type CSVFormat struct {
+	Delimiter  rune `bson:"delimiter"`
+	SkipHeader bool `bson:"skip_header"`
+}
+
+// DefaultCSVFormat is what web-app serves: id;name;price without a header.
+var DefaultCSVFormat = CSVFormat{Delimiter: ';'}
+
+// Source is a supplier registered in config, its products live in their own id space.
+type Source struct {
+	Name     string
+	Url      string
+	Format   CSVFormat
+	Priority int
+}
+
+// SourceRegistry is what clients are told about the configured sources.
+type SourceRegistry struct {
+	Sources []Source
+	Default string
+	Merge   string
+}
+
+const (
+	MergePriority    = "priority"
+	MergeLowestPrice = "lowest_price"
+	MergeNewest      = "newest"
+)
+
+// MergeRule decides which source's copy of a product the merged catalog shows. Copies are
+// the same product when their MergeKey matches.
+type MergeRule struct {
+	Strategy   string
+	Priorities map[string]int
+}
+
+// MergeKey is the name with surrounding spaces trimmed and Unicode letters lowered. Backends
+// store it when the name is written, so "Молоко" and " молоко" merge the same way everywhere.
+func MergeKey(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
+
+// Schedule is a source the server fetches on its own by a cron expression.
+type Schedule struct {
+	Id        primitive.ObjectID `bson:"_id,omitempty"`
+	Source    string             `bson:"source"`
+	Url       string             `bson:"url"`
+	Cron      string             `bson:"cron"`
+	Format    CSVFormat          `bson:"format"`
+	Paused    bool               `bson:"paused"`
+	NextRun   time.Time          `bson:"next_run"`
+	CreatedAt time.Time          `bson:"created_at"`
+
+	LastRun    *time.Time `bson:"last_run,omitempty"`
+	LastStatus string     `bson:"last_status,omitempty"`
+	LastError  string     `bson:"last_error,omitempty"`
+}
+
+const (
+	DirectionUp   = "up"
+	DirectionDown = "down"
+)
+
+// AlertRule sends price changes of matching products to a webhook. Zero Source, ProductId
+// and MinChangePercent match anything.
+type AlertRule struct {
+	Id               primitive.ObjectID `bson:"_id,omitempty"`
+	Name             string             `bson:"name"`
+	Source           string             `bson:"source,omitempty"`
+	ProductId        int                `bson:"product_id,omitempty"`
+	MinChangePercent float64            `bson:"min_change_percent"`
+	Direction        string             `bson:"direction,omitempty"`
+	WebhookUrl       string             `bson:"webhook_url"`
+	Secret           string             `bson:"secret"`
+	CreatedAt        time.Time          `bson:"created_at"`
+}
+
+// DeadLetter is a webhook delivery that ran out of attempts.
+type DeadLetter struct {
+	Id         primitive.ObjectID `bson:"_id,omitempty"`
+	RuleId     primitive.ObjectID `bson:"rule_id"`
+	WebhookUrl string             `bson:"webhook_url"`
+	Payload    string             `bson:"payload"`
+	Attempts   int                `bson:"attempts"`
+	LastError  string             `bson:"last_error"`
+	FailedAt   time.Time          `bson:"failed_at"`
 }
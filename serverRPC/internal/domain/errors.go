@@ -1,5 +1,69 @@
 package domain
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
 
 var ErrNoProducts = errors.New("no products to insert")
+
+var ErrProductNotFound = errors.New("product not found")
+
+var ErrInvalidArgument = errors.New("invalid argument")
+
+var ErrInvalidCSV = errors.New("invalid csv")
+
+var ErrSourceUnavailable = errors.New("source unavailable")
+
+var ErrUnavailable = errors.New("storage unavailable")
+
+var ErrImportQueueFull = errors.New("import queue is full")
+
+var ErrScheduleNotFound = errors.New("schedule not found")
+
+var ErrAlertRuleNotFound = errors.New("alert rule not found")
+
+// FieldError points at the request field that made the call invalid.
+type FieldError struct {
+	Field       string
+	Description string
+	Err         error
+}
+
+func NewFieldError(field, description string) *FieldError {
+	return &FieldError{
+		Field:       field,
+		Description: description,
+		Err:         ErrInvalidArgument,
+	}
+}
+
+func (e *FieldError) Error() string {
+	return fmt.Sprintf("%s: %s: %s", e.Err, e.Field, e.Description)
+}
+
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+type NotFoundError struct {
+	Ids []int
+}
+
+func (e *NotFoundError) Error() string {
+	return fmt.Sprintf("%s: ids %s", ErrProductNotFound, e.JoinIds())
+}
+
+func (e *NotFoundError) Unwrap() error {
+	return ErrProductNotFound
+}
+
+func (e *NotFoundError) JoinIds() string {
+	ids := make([]string, len(e.Ids))
+	for i, id := range e.Ids {
+		ids[i] = strconv.Itoa(id)
+	}
+	return strings.Join(ids, ",")
+}
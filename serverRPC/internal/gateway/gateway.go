@@ -0,0 +1,208 @@
+package gateway
+
+import (
+	"context"
+	_ "embed"
+	"errors"
+	"fmt"
+	"gRPC-server/pkg/logger"
+	"gRPC-server/pkg/parseCSV/grpcPb"
+	"gRPC-server/pkg/tlsconfig"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"strings"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/utilities"
+	"github.com/spf13/viper"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/protobuf/proto"
+)
+
+// openAPI is generated from proto/proto.proto together with the gateway code, see `make proto`.
+//
+//go:embed openapi.json
+var openAPI []byte
+
+// forwardedHeaders reach the gRPC server as metadata under the same name.
+var forwardedHeaders = map[string]bool{
+	"x-api-key":    true,
+	"x-request-id": true,
+}
+
+type Server struct {
+	httpServer    *http.Server
+	openAPIServer *http.Server
+	conn          *grpc.ClientConn
+	logger        *logger.Logger
+}
+
+// NewServer serves the REST/JSON gateway on gateway.addr, proxying to gateway.grpc_endpoint.
+// With an empty address it returns nil and the gateway is off.
+func NewServer(logger *logger.Logger) (*Server, error) {
+	addr := viper.GetString("gateway.addr")
+	if addr == "" {
+		return nil, nil
+	}
+
+	creds, err := transportCredentials()
+	if err != nil {
+		return nil, fmt.Errorf("gateway tls: %w", err)
+	}
+	conn, err := grpc.NewClient(viper.GetString("gateway.grpc_endpoint"),
+		grpc.WithTransportCredentials(creds),
+		grpc.WithStatsHandler(otelgrpc.NewClientHandler()))
+	if err != nil {
+		return nil, err
+	}
+
+	handler, err := NewHandler(context.Background(), conn)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	s := &Server{
+		conn:   conn,
+		logger: logger,
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/", handler)
+	if openAPIAddr := viper.GetString("gateway.openapi_addr"); openAPIAddr != "" {
+		openAPIMux := http.NewServeMux()
+		openAPIMux.HandleFunc("/openapi.json", serveOpenAPI)
+		s.openAPIServer = &http.Server{Addr: openAPIAddr, Handler: openAPIMux}
+	} else {
+		mux.HandleFunc("/openapi.json", serveOpenAPI)
+	}
+	s.httpServer = &http.Server{
+		Addr:    addr,
+		Handler: otelhttp.NewHandler(mux, "gateway"),
+	}
+
+	return s, nil
+}
+
+// NewHandler returns the REST routes of SortService proxied over conn.
+func NewHandler(ctx context.Context, conn *grpc.ClientConn) (http.Handler, error) {
+	mux := runtime.NewServeMux(
+		runtime.WithIncomingHeaderMatcher(incomingHeader),
+		runtime.WithOutgoingHeaderMatcher(outgoingHeader),
+		runtime.SetQueryParameterParser(&queryParser{}),
+	)
+	if err := grpcPb.RegisterSortServiceHandler(ctx, mux, conn); err != nil {
+		return nil, err
+	}
+	return mux, nil
+}
+
+func serveOpenAPI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(openAPI)
+}
+
+func incomingHeader(key string) (string, bool) {
+	if lower := strings.ToLower(key); forwardedHeaders[lower] {
+		return lower, true
+	}
+	return runtime.DefaultHeaderMatcher(key)
+}
+
+func outgoingHeader(key string) (string, bool) {
+	if forwardedHeaders[strings.ToLower(key)] {
+		return textproto.CanonicalMIMEHeaderKey(key), true
+	}
+	return fmt.Sprintf("%s%s", runtime.MetadataHeaderPrefix, key), true
+}
+
+// queryParser accepts short names for the List parameters:
+// sort=price&asc=true&offset=0&limit=10 is the same as
+// sort_field=price&sort_asc=1&paging_offset=0&paging_limit=10.
+type queryParser struct {
+	runtime.DefaultQueryParser
+}
+
+var listAliases = map[string]string{
+	"sort":   "sort_field",
+	"offset": "paging_offset",
+	"limit":  "paging_limit",
+}
+
+func (p *queryParser) Parse(msg proto.Message, values url.Values, filter *utilities.DoubleArray) error {
+	if _, ok := msg.(*grpcPb.ListRequest); ok {
+		var err error
+		if values, err = listQuery(values); err != nil {
+			return err
+		}
+	}
+	return p.DefaultQueryParser.Parse(msg, values, filter)
+}
+
+func listQuery(values url.Values) (url.Values, error) {
+	out := url.Values{}
+	for key, value := range values {
+		if alias, ok := listAliases[key]; ok {
+			key = alias
+		}
+		out[key] = value
+	}
+
+	if asc, ok := values["asc"]; ok {
+		delete(out, "asc")
+		switch strings.ToLower(asc[0]) {
+		case "true", "1":
+			out.Set("sort_asc", "1")
+		case "false", "-1":
+			out.Set("sort_asc", "-1")
+		default:
+			return nil, fmt.Errorf("asc must be true or false, got %q", asc[0])
+		}
+	}
+	return out, nil
+}
+
+// transportCredentials dials the gRPC server with TLS when the server has it enabled.
+func transportCredentials() (credentials.TransportCredentials, error) {
+	if !viper.GetBool("grpc.tls.enabled") {
+		return insecure.NewCredentials(), nil
+	}
+	config, _, err := tlsconfig.ClientConfig(tlsconfig.ClientOptions{
+		CAFile:     viper.GetString("gateway.tls.ca_file"),
+		CertFile:   viper.GetString("gateway.tls.cert_file"),
+		KeyFile:    viper.GetString("gateway.tls.key_file"),
+		ServerName: viper.GetString("gateway.tls.server_name"),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return credentials.NewTLS(config), nil
+}
+
+func (s *Server) ListenAndServe() {
+	if s.openAPIServer != nil {
+		go func() {
+			if err := s.openAPIServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				s.logger.Errorf("Can't serve openapi: %s", err)
+			}
+		}()
+	}
+
+	s.logger.Infof("REST gateway has been started on %s", s.httpServer.Addr)
+	if err := s.httpServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		s.logger.Errorf("Can't serve REST gateway: %s", err)
+	}
+}
+
+func (s *Server) Shutdown(ctx context.Context) error {
+	err := s.httpServer.Shutdown(ctx)
+	if s.openAPIServer != nil {
+		err = errors.Join(err, s.openAPIServer.Shutdown(ctx))
+	}
+	return errors.Join(err, s.conn.Close())
+}
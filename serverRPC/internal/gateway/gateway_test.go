@@ -0,0 +1,178 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"gRPC-server/pkg/parseCSV/grpcPb"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+type sortServer struct {
+	grpcPb.UnimplementedSortServiceServer
+	listReq *grpcPb.ListRequest
+	md      metadata.MD
+}
+
+func (s *sortServer) List(ctx context.Context, req *grpcPb.ListRequest) (*grpcPb.ListResponce, error) {
+	s.listReq = req
+	s.md, _ = metadata.FromIncomingContext(ctx)
+	grpc.SetHeader(ctx, metadata.Pairs("x-request-id", "req-1"))
+	return &grpcPb.ListResponce{Product: []*grpcPb.Product{{Id: 1, Name: "name", Price: "50.00"}}}, nil
+}
+
+func (s *sortServer) GetProduct(ctx context.Context, req *grpcPb.GetProductRequest) (*grpcPb.GetProductResponce, error) {
+	return nil, status.Error(codes.NotFound, "product not found")
+}
+
+func (s *sortServer) Fetch(ctx context.Context, req *grpcPb.FetchRequest) (*grpcPb.FethResponce, error) {
+	return &grpcPb.FethResponce{Status: "Success"}, nil
+}
+
+func newTestGateway(t *testing.T, srv *sortServer) *httptest.Server {
+	t.Helper()
+	listener := bufconn.Listen(1 << 20)
+	grpcServer := grpc.NewServer()
+	grpcPb.RegisterSortServiceServer(grpcServer, srv)
+	go grpcServer.Serve(listener)
+	t.Cleanup(grpcServer.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	handler, err := NewHandler(context.Background(), conn)
+	require.NoError(t, err)
+
+	mux := http.NewServeMux()
+	mux.Handle("/", handler)
+	mux.HandleFunc("/openapi.json", serveOpenAPI)
+	gw := httptest.NewServer(mux)
+	t.Cleanup(gw.Close)
+	return gw
+}
+
+func TestListQuery(t *testing.T) {
+	testTables := []struct {
+		name       string
+		query      string
+		want       *grpcPb.ListRequest
+		wantStatus int
+	}{
+		{
+			name:       "Short names",
+			query:      "sort=price&asc=true&offset=0&limit=10",
+			want:       &grpcPb.ListRequest{SortField: grpcPb.ListRequest_price, SortAsc: 1, PagingLimit: 10},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "Field names",
+			query:      "sort_field=name&sort_asc=-1&paging_offset=5&include_deleted=true",
+			want:       &grpcPb.ListRequest{SortField: grpcPb.ListRequest_name, SortAsc: -1, PagingOffset: 5, IncludeDeleted: true},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "Descending",
+			query:      "sort=id&asc=false",
+			want:       &grpcPb.ListRequest{SortField: grpcPb.ListRequest_id, SortAsc: -1},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "Bad asc",
+			query:      "asc=maybe",
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, table := range testTables {
+		t.Run(table.name, func(t *testing.T) {
+			srv := &sortServer{}
+			gw := newTestGateway(t, srv)
+
+			resp, err := http.Get(gw.URL + "/v1/products?" + table.query)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+
+			assert.Equal(t, table.wantStatus, resp.StatusCode)
+			if table.want != nil {
+				assert.Equal(t, table.want.String(), srv.listReq.String())
+			}
+		})
+	}
+}
+
+func TestGateway(t *testing.T) {
+	srv := &sortServer{}
+	gw := newTestGateway(t, srv)
+
+	t.Run("Headers", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodGet, gw.URL+"/v1/products", nil)
+		req.Header.Set("X-Api-Key", "secret")
+		req.Header.Set("Authorization", "Bearer token")
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		var body struct {
+			Product []struct {
+				Id    string
+				Name  string
+				Price string
+			}
+		}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+		assert.Equal(t, "name", body.Product[0].Name)
+		assert.Equal(t, "50.00", body.Product[0].Price)
+		assert.Equal(t, []string{"secret"}, srv.md.Get("x-api-key"))
+		assert.Equal(t, []string{"Bearer token"}, srv.md.Get("authorization"))
+		assert.Equal(t, "req-1", resp.Header.Get("X-Request-Id"))
+	})
+
+	t.Run("Fetch", func(t *testing.T) {
+		resp, err := http.Post(gw.URL+"/v1/products:fetch", "application/json", strings.NewReader(`{"Url":"http://web-app:8085/products/"}`))
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.JSONEq(t, `{"Status":"Success","rejected":[]}`, string(body))
+	})
+
+	t.Run("Status codes", func(t *testing.T) {
+		resp, err := http.Get(gw.URL + "/v1/products/42")
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	})
+
+	t.Run("OpenAPI", func(t *testing.T) {
+		resp, err := http.Get(gw.URL + "/openapi.json")
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		var doc struct {
+			Swagger string
+			Paths   map[string]interface{}
+		}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&doc))
+		assert.Equal(t, "2.0", doc.Swagger)
+		assert.Contains(t, doc.Paths, "/v1/products")
+		assert.Contains(t, doc.Paths, "/v1/products:fetch")
+	})
+}
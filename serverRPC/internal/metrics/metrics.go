@@ -0,0 +1,138 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const namespace = "sortservice"
+
+var (
+	GrpcRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "grpc",
+		Name:      "requests_total",
+		Help:      "Handled gRPC calls by method and status code.",
+	}, []string{"method", "code"})
+
+	GrpcLatency = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "grpc",
+		Name:      "request_duration_seconds",
+		Help:      "gRPC call latency by method and status code.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "code"})
+
+	MongoLatency = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "mongo",
+		Name:      "operation_duration_seconds",
+		Help:      "Mongo command latency by command name and result.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"operation", "result"})
+
+	ImportRowsParsed = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "import",
+		Name:      "rows_parsed_total",
+		Help:      "CSV rows parsed into products.",
+	})
+
+	ImportRowsRejected = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "import",
+		Name:      "rows_rejected_total",
+		Help:      "CSV rows skipped or rejected during import by the rule that rejected them.",
+	}, []string{"rule"})
+
+	ImportProductsInserted = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "import",
+		Name:      "products_inserted_total",
+		Help:      "Products inserted by committed imports.",
+	})
+
+	ImportProductsUpdated = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "import",
+		Name:      "products_updated_total",
+		Help:      "Products updated by committed imports.",
+	})
+
+	ImportBytesDownloaded = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "import",
+		Name:      "bytes_downloaded_total",
+		Help:      "Bytes of CSV downloaded from sources.",
+	})
+
+	ImportRunning = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "import",
+		Name:      "running",
+		Help:      "Imports currently downloading or applying.",
+	})
+
+	ImportQueued = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "import",
+		Name:      "queued",
+		Help:      "Imports waiting for a free slot.",
+	})
+
+	ImportRejected = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "import",
+		Name:      "rejected_total",
+		Help:      "Fetch calls rejected because the import queue was full.",
+	})
+
+	ImportShared = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "import",
+		Name:      "shared_total",
+		Help:      "Fetch calls answered by an import of the same URL started by another call.",
+	})
+
+	ScheduledRuns = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "scheduler",
+		Name:      "runs_total",
+		Help:      "Scheduled fetches by result: succeeded, failed or skipped.",
+	}, []string{"result"})
+
+	SchedulerLeader = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "scheduler",
+		Name:      "leader",
+		Help:      "1 while this replica holds the scheduler lease.",
+	})
+
+	AlertDeliveries = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "alerts",
+		Name:      "deliveries_total",
+		Help:      "Webhook delivery attempts by result: delivered, retried or dead_letter.",
+	}, []string{"result"})
+
+	RateLimited = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "grpc",
+		Name:      "rate_limited_total",
+		Help:      "Calls rejected by the rate limiter, by method and limit (global or caller).",
+	}, []string{"method", "limit"})
+
+	ListCacheRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "cache",
+		Name:      "list_requests_total",
+		Help:      "List calls by whether the cache answered them: hit or miss.",
+	}, []string{"result"})
+
+	ListCacheInvalidations = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "cache",
+		Name:      "list_invalidations_total",
+		Help:      "Times the List cache was cleared because a change to the catalog was committed.",
+	})
+)
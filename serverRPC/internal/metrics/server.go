@@ -0,0 +1,47 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gRPC-server/pkg/logger"
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/spf13/viper"
+)
+
+type Server struct {
+	httpServer *http.Server
+	logger     *logger.Logger
+}
+
+// NewServer serves /metrics on metrics.addr; with an empty address it returns nil and metrics are only collected.
+func NewServer(logger *logger.Logger) *Server {
+	addr := viper.GetString("metrics.addr")
+	if addr == "" {
+		return nil
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+
+	return &Server{
+		httpServer: &http.Server{
+			Addr:    addr,
+			Handler: mux,
+		},
+		logger: logger,
+	}
+}
+
+func (s *Server) ListenAndServe() {
+	fmt.Printf("Metrics server has been started on %s\n", s.httpServer.Addr)
+	if err := s.httpServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		s.logger.Errorf("Can't serve metrics: %s", err)
+	}
+}
+
+func (s *Server) Shutdown(ctx context.Context) error {
+	return s.httpServer.Shutdown(ctx)
+}
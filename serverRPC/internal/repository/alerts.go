@@ -0,0 +1,82 @@
+package repository
+
+import (
+	"context"
+	"gRPC-server/internal/domain"
+
+	"github.com/spf13/viper"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func (m *MongoBackend) alertRules() *mongo.Collection {
+	return m.db.Collection(viper.GetString("mongo.alert_rules"))
+}
+
+func (m *MongoBackend) deadLetters() *mongo.Collection {
+	return m.db.Collection(viper.GetString("mongo.dead_letters"))
+}
+
+func (m *MongoBackend) CreateAlertRule(ctx context.Context, rule domain.AlertRule) (domain.AlertRule, error) {
+	rule.Id = primitive.NewObjectID()
+	if _, err := m.alertRules().InsertOne(ctx, rule); err != nil {
+		m.logger.FromContext(ctx).Errorf("Can't create alert rule: %s", err)
+		return domain.AlertRule{}, err
+	}
+	return rule, nil
+}
+
+func (m *MongoBackend) ListAlertRules(ctx context.Context) ([]domain.AlertRule, error) {
+	cursor, err := m.alertRules().Find(ctx, bson.D{}, options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}}))
+	if err != nil {
+		m.logger.FromContext(ctx).Errorf("Can't list alert rules: %s", err)
+		return nil, err
+	}
+
+	var rules []domain.AlertRule
+	if err := cursor.All(ctx, &rules); err != nil {
+		m.logger.FromContext(ctx).Errorf("Can't decode alert rules: %s", err)
+		return nil, err
+	}
+	return rules, nil
+}
+
+func (m *MongoBackend) DeleteAlertRule(ctx context.Context, id primitive.ObjectID) error {
+	result, err := m.alertRules().DeleteOne(ctx, bson.D{{Key: "_id", Value: id}})
+	if err != nil {
+		m.logger.FromContext(ctx).Errorf("Can't delete alert rule: %s", err)
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return domain.ErrAlertRuleNotFound
+	}
+	return nil
+}
+
+func (m *MongoBackend) InsertDeadLetter(ctx context.Context, letter domain.DeadLetter) error {
+	letter.Id = primitive.NewObjectID()
+	if _, err := m.deadLetters().InsertOne(ctx, letter); err != nil {
+		m.logger.FromContext(ctx).Errorf("Can't write dead letter: %s", err)
+		return err
+	}
+	return nil
+}
+
+// ListDeadLetters returns the newest limit dead letters first.
+func (m *MongoBackend) ListDeadLetters(ctx context.Context, limit int) ([]domain.DeadLetter, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "failed_at", Value: -1}}).SetLimit(int64(limit))
+	cursor, err := m.deadLetters().Find(ctx, bson.D{}, opts)
+	if err != nil {
+		m.logger.FromContext(ctx).Errorf("Can't list dead letters: %s", err)
+		return nil, err
+	}
+
+	var letters []domain.DeadLetter
+	if err := cursor.All(ctx, &letters); err != nil {
+		m.logger.FromContext(ctx).Errorf("Can't decode dead letters: %s", err)
+		return nil, err
+	}
+	return letters, nil
+}
@@ -0,0 +1,606 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"gRPC-server/internal/domain"
+	"gRPC-server/pkg/logger"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// conformance is the behaviour every Sorting backend shares, so the service can't tell them apart.
+type conformance struct {
+	// newBackend returns an empty backend
+	newBackend func(t *testing.T) Sorting
+	// rollback is false for deployments that can't undo a failed unit of work
+	rollback bool
+}
+
+func TestMemoryConformance(t *testing.T) {
+	conformance{
+		newBackend: func(t *testing.T) Sorting {
+			return NewMemoryBackend(logger.GetLogger())
+		},
+		rollback: true,
+	}.run(t)
+}
+
+// TestMongoConformance needs a server, e.g. TEST_MONGO_URI=mongodb://localhost:27017.
+// Every test gets its own database, dropped when it ends.
+func TestMongoConformance(t *testing.T) {
+	uri := os.Getenv("TEST_MONGO_URI")
+	if uri == "" {
+		t.Skip("TEST_MONGO_URI is not set")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri))
+	require.NoError(t, err)
+	require.NoError(t, client.Ping(ctx, nil))
+	t.Cleanup(func() { client.Disconnect(context.Background()) })
+
+	for key, value := range map[string]string{
+		"mongo.collection":   "Products",
+		"mongo.history":      "ProductsHistory",
+		"mongo.schedules":    "Schedules",
+		"mongo.leases":       "Leases",
+		"mongo.alert_rules":  "AlertRules",
+		"mongo.dead_letters": "AlertDeadLetters",
+	} {
+		viper.Set(key, value)
+	}
+
+	probe := MongoInit(client.Database("conformance_probe"), logger.GetLogger())
+	conformance{
+		newBackend: func(t *testing.T) Sorting {
+			db := client.Database(fmt.Sprintf("conformance_%d", time.Now().UnixNano()))
+			t.Cleanup(func() { db.Drop(context.Background()) })
+
+			backend := MongoInit(db, logger.GetLogger())
+			require.NoError(t, backend.MigrateSources(context.Background(), "default"))
+			require.NoError(t, backend.MigrateWatch(context.Background()))
+			return backend
+		},
+		rollback: probe.isReplicated(ctx),
+	}.run(t)
+}
+
+// TestPostgresConformance needs a database the user can create schemas in, e.g.
+// TEST_POSTGRES_DSN="host=localhost user=postgres password=postgres sslmode=disable".
+// Every test migrates its own schema, dropped when it ends.
+func TestPostgresConformance(t *testing.T) {
+	dsn := os.Getenv("TEST_POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("TEST_POSTGRES_DSN is not set")
+	}
+
+	admin, err := sql.Open("postgres", dsn)
+	require.NoError(t, err)
+	require.NoError(t, admin.Ping())
+	t.Cleanup(func() { admin.Close() })
+
+	conformance{
+		newBackend: func(t *testing.T) Sorting {
+			schema := fmt.Sprintf("conformance_%d", time.Now().UnixNano())
+			_, err := admin.Exec("CREATE SCHEMA " + schema)
+			require.NoError(t, err)
+
+			db, err := sql.Open("postgres", withSearchPath(dsn, schema))
+			require.NoError(t, err)
+			t.Cleanup(func() {
+				db.Close()
+				admin.Exec("DROP SCHEMA " + schema + " CASCADE")
+			})
+
+			backend := PostgresInit(db, logger.GetLogger())
+			require.NoError(t, backend.Migrate(context.Background()))
+			return backend
+		},
+		rollback: true,
+	}.run(t)
+}
+
+// withSearchPath adds search_path to a key=value or URL dsn, lib/pq sends it to the server as is.
+func withSearchPath(dsn, schema string) string {
+	if !strings.Contains(dsn, "://") {
+		return dsn + " search_path=" + schema
+	}
+	if strings.Contains(dsn, "?") {
+		return dsn + "&search_path=" + schema
+	}
+	return dsn + "?search_path=" + schema
+}
+
+func (c conformance) run(t *testing.T) {
+	t.Run("Insert and get", c.testInsertAndGet)
+	t.Run("List sort and paging", c.testListSortAndPaging)
+	t.Run("List merged catalog", c.testListMerged)
+	t.Run("List ties across pages", c.testListTies)
+	t.Run("Export matches List", c.testExport)
+	t.Run("Update", c.testUpdate)
+	t.Run("Delete, restore and purge", c.testDeleteRestorePurge)
+	t.Run("Transaction", c.testTransaction)
+	t.Run("Stats", c.testStats)
+	t.Run("Schedules", c.testSchedules)
+	t.Run("Leases", c.testLeases)
+	t.Run("Alerts", c.testAlerts)
+}
+
+func price(s string) primitive.Decimal128 {
+	d, err := primitive.ParseDecimal128(s)
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
+
+func product(source string, id int, name, p string) domain.Product {
+	return domain.Product{Source: source, Id: id, Name: name, Price: price(p)}
+}
+
+func ids(products []domain.Product) []int {
+	out := make([]int, len(products))
+	for i, p := range products {
+		out[i] = p.Id
+	}
+	return out
+}
+
+func (c conformance) seeded(t *testing.T, products ...domain.Product) Sorting {
+	backend := c.newBackend(t)
+	require.NoError(t, backend.Insert(context.Background(), products))
+	return backend
+}
+
+func (c conformance) testInsertAndGet(t *testing.T) {
+	ctx := context.Background()
+	backend := c.seeded(t, product("a", 1, "Milk", "10.50"), product("b", 1, "Bread", "3"))
+
+	got, err := backend.GetByName(ctx, domain.Product{Source: "a", Id: 1})
+	assert.NoError(t, err)
+	assert.Equal(t, "Milk", got.Name)
+	assert.Equal(t, "10.50", got.Price.String())
+	assert.False(t, got.DateOfChange.IsZero())
+
+	// the id alone is not the identity
+	got, err = backend.GetByName(ctx, domain.Product{Source: "b", Id: 1})
+	assert.NoError(t, err)
+	assert.Equal(t, "Bread", got.Name)
+
+	_, err = backend.GetByName(ctx, domain.Product{Source: "a", Id: 2})
+	assert.ErrorIs(t, err, domain.ErrProductNotFound)
+
+	assert.ErrorIs(t, backend.Insert(ctx, nil), domain.ErrNoProducts)
+	assert.Error(t, backend.Insert(ctx, []domain.Product{product("a", 1, "Milk again", "1")}))
+
+	byIds, err := backend.GetByIds(ctx, "a", []int{1, 2})
+	assert.NoError(t, err)
+	assert.Equal(t, []int{1}, ids(byIds))
+}
+
+func (c conformance) testListSortAndPaging(t *testing.T) {
+	ctx := context.Background()
+	backend := c.seeded(t,
+		product("a", 3, "Cheese", "7.25"),
+		product("a", 1, "Apple", "100"),
+		product("a", 4, "Bread", "9.5"),
+		product("a", 2, "Donut", "20"),
+		product("b", 5, "Egg", "1"),
+	)
+
+	testTables := []struct {
+		name string
+		sort domain.SortParams
+		want []int
+	}{
+		{
+			name: "Id ascending",
+			sort: domain.SortParams{SortField: "id", SortAsc: 1, Source: "a"},
+			want: []int{1, 2, 3, 4},
+		},
+		{
+			name: "Name descending",
+			sort: domain.SortParams{SortField: "name", SortAsc: -1, Source: "a"},
+			want: []int{2, 3, 4, 1},
+		},
+		{
+			name: "Price is compared as a number",
+			sort: domain.SortParams{SortField: "price", SortAsc: 1, Source: "a"},
+			want: []int{3, 4, 2, 1},
+		},
+		{
+			name: "Page",
+			sort: domain.SortParams{SortField: "price", SortAsc: -1, PagingOffset: 1, PagingLimit: 2, Source: "a"},
+			want: []int{2, 4},
+		},
+		{
+			name: "Offset past the end",
+			sort: domain.SortParams{SortField: "id", SortAsc: 1, PagingOffset: 10, Source: "a"},
+			want: []int{},
+		},
+	}
+
+	for _, table := range testTables {
+		t.Run(table.name, func(t *testing.T) {
+			got, err := backend.List(ctx, table.sort)
+			assert.NoError(t, err)
+			assert.Equal(t, table.want, ids(got))
+		})
+	}
+}
+
+func (c conformance) testListMerged(t *testing.T) {
+	ctx := context.Background()
+	backend := c.seeded(t,
+		product("a", 1, "Milk", "10"),
+		product("b", 7, " milk", "8"),
+		product("a", 2, "Bread", "3"),
+		// the merge key lowers Unicode letters too, not only ASCII ones
+		product("a", 3, "МОЛОКО", "5"),
+		product("b", 8, "молоко ", "4"),
+	)
+
+	testTables := []struct {
+		name  string
+		merge domain.MergeRule
+		want  []string
+	}{
+		{
+			name:  "Priority",
+			merge: domain.MergeRule{Strategy: domain.MergePriority, Priorities: map[string]int{"a": 10, "b": 1}},
+			want:  []string{"a/2", "a/3", "a/1"},
+		},
+		{
+			name:  "Lowest price",
+			merge: domain.MergeRule{Strategy: domain.MergeLowestPrice, Priorities: map[string]int{"a": 10, "b": 1}},
+			want:  []string{"a/2", "b/8", "b/7"},
+		},
+	}
+
+	keys := func(products []domain.Product) []string {
+		out := make([]string, len(products))
+		for i, p := range products {
+			out[i] = fmt.Sprintf("%s/%d", p.Source, p.Id)
+		}
+		return out
+	}
+
+	for _, table := range testTables {
+		t.Run(table.name, func(t *testing.T) {
+			got, err := backend.List(ctx, domain.SortParams{SortField: "price", SortAsc: 1, Merge: table.merge})
+			assert.NoError(t, err)
+			assert.Equal(t, table.want, keys(got))
+		})
+	}
+
+	t.Run("Rename moves the product to another key", func(t *testing.T) {
+		require.NoError(t, backend.UpdateProduct(ctx, product("b", 8, "Кефир", "4")))
+
+		merge := domain.MergeRule{Strategy: domain.MergeLowestPrice}
+		got, err := backend.List(ctx, domain.SortParams{SortField: "price", SortAsc: 1, Merge: merge})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"a/2", "b/8", "a/3", "b/7"}, keys(got))
+	})
+}
+
+// testListTies pages over equal sort values, every backend must break the ties by (source, id)
+// so no product is skipped or repeated between pages.
+func (c conformance) testListTies(t *testing.T) {
+	ctx := context.Background()
+	backend := c.seeded(t,
+		product("b", 1, "Egg", "5"),
+		product("a", 4, "Milk", "5"),
+		product("a", 2, "Bread", "5"),
+		product("a", 3, "milk", "5"),
+		product("a", 1, "Cheese", "5"),
+	)
+
+	pages := func(sort domain.SortParams) [][]string {
+		var out [][]string
+		for offset := int32(0); offset < 6; offset += 2 {
+			sort.PagingOffset, sort.PagingLimit = offset, 2
+			got, err := backend.List(ctx, sort)
+			require.NoError(t, err)
+
+			keys := make([]string, len(got))
+			for i, p := range got {
+				keys[i] = fmt.Sprintf("%s/%d", p.Source, p.Id)
+			}
+			out = append(out, keys)
+		}
+		return out
+	}
+
+	t.Run("One source", func(t *testing.T) {
+		got := pages(domain.SortParams{SortField: "price", SortAsc: 1, Source: "a"})
+		assert.Equal(t, [][]string{{"a/1", "a/2"}, {"a/3", "a/4"}, {}}, got)
+	})
+
+	t.Run("Merged", func(t *testing.T) {
+		// both milks come from one source, so they are two products and not copies of one
+		merge := domain.MergeRule{Strategy: domain.MergeLowestPrice}
+		got := pages(domain.SortParams{SortField: "price", SortAsc: -1, Merge: merge})
+		assert.Equal(t, [][]string{{"a/1", "a/2"}, {"a/3", "a/4"}, {"b/1"}}, got)
+	})
+}
+
+func (c conformance) testExport(t *testing.T) {
+	ctx := context.Background()
+	backend := c.seeded(t, product("a", 1, "Milk", "10"), product("a", 2, "Bread", "3"), product("a", 3, "Egg", "1"))
+	sort := domain.SortParams{SortField: "price", SortAsc: 1, Source: "a", PagingLimit: 2}
+
+	listed, err := backend.List(ctx, sort)
+	assert.NoError(t, err)
+
+	var exported []domain.Product
+	assert.NoError(t, backend.Export(ctx, sort, func(product domain.Product) error {
+		exported = append(exported, product)
+		return nil
+	}))
+	assert.Equal(t, ids(listed), ids(exported))
+
+	stop := errors.New("stop")
+	calls := 0
+	err = backend.Export(ctx, sort, func(domain.Product) error {
+		calls++
+		return stop
+	})
+	assert.ErrorIs(t, err, stop)
+	assert.Equal(t, 1, calls)
+}
+
+func (c conformance) testUpdate(t *testing.T) {
+	ctx := context.Background()
+	backend := c.seeded(t, product("a", 1, "Milk", "10"))
+
+	assert.NoError(t, backend.UpdateProduct(ctx, product("a", 1, "Milk 1L", "12.40")))
+	got, err := backend.GetByName(ctx, domain.Product{Source: "a", Id: 1})
+	assert.NoError(t, err)
+	assert.Equal(t, "Milk 1L", got.Name)
+	assert.Equal(t, "12.40", got.Price.String())
+
+	assert.ErrorIs(t, backend.UpdateProduct(ctx, product("a", 2, "Bread", "1")), domain.ErrProductNotFound)
+}
+
+func (c conformance) testDeleteRestorePurge(t *testing.T) {
+	ctx := context.Background()
+	backend := c.seeded(t, product("a", 1, "Milk", "10"), product("a", 2, "Bread", "3"))
+	list := domain.SortParams{SortField: "id", SortAsc: 1, Source: "a"}
+
+	assert.NoError(t, backend.DeleteProduct(ctx, domain.Product{Source: "a", Id: 1}))
+	assert.ErrorIs(t, backend.DeleteProduct(ctx, domain.Product{Source: "a", Id: 1}), domain.ErrProductNotFound)
+	assert.ErrorIs(t, backend.DeleteProduct(ctx, domain.Product{Source: "a", Id: 9}), domain.ErrProductNotFound)
+
+	got, err := backend.List(ctx, list)
+	assert.NoError(t, err)
+	assert.Equal(t, []int{2}, ids(got))
+
+	list.IncludeDeleted = true
+	got, err = backend.List(ctx, list)
+	assert.NoError(t, err)
+	if assert.Equal(t, []int{1, 2}, ids(got)) {
+		assert.NotNil(t, got[0].DeletedAt)
+		assert.Nil(t, got[1].DeletedAt)
+	}
+
+	assert.NoError(t, backend.RestoreProduct(ctx, domain.Product{Source: "a", Id: 1}))
+	assert.ErrorIs(t, backend.RestoreProduct(ctx, domain.Product{Source: "a", Id: 1}), domain.ErrProductNotFound)
+
+	assert.NoError(t, backend.DeleteProduct(ctx, domain.Product{Source: "a", Id: 2}))
+	purged, err := backend.PurgeDeleted(ctx, time.Now().Add(-time.Hour))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), purged)
+
+	purged, err = backend.PurgeDeleted(ctx, time.Now().Add(time.Hour))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), purged)
+	_, err = backend.GetByName(ctx, domain.Product{Source: "a", Id: 2})
+	assert.ErrorIs(t, err, domain.ErrProductNotFound)
+}
+
+func (c conformance) testTransaction(t *testing.T) {
+	ctx := context.Background()
+	backend := c.seeded(t, product("a", 1, "Milk", "10"))
+
+	err := backend.WithTransaction(ctx, func(ctx context.Context) error {
+		if err := backend.UpdateProduct(ctx, product("a", 1, "Milk", "11")); err != nil {
+			return err
+		}
+		// a write is visible to the unit of work that made it
+		got, err := backend.GetByName(ctx, domain.Product{Source: "a", Id: 1})
+		if err != nil {
+			return err
+		}
+		assert.Equal(t, "11", got.Price.String())
+		return backend.Insert(ctx, []domain.Product{product("a", 2, "Bread", "3")})
+	})
+	assert.NoError(t, err)
+
+	got, err := backend.GetByIds(ctx, "a", []int{1, 2})
+	assert.NoError(t, err)
+	assert.Len(t, got, 2)
+
+	if !c.rollback {
+		return
+	}
+	failed := errors.New("failed")
+	err = backend.WithTransaction(ctx, func(ctx context.Context) error {
+		if err := backend.UpdateProduct(ctx, product("a", 1, "Milk", "99")); err != nil {
+			return err
+		}
+		if err := backend.Insert(ctx, []domain.Product{product("a", 3, "Egg", "1")}); err != nil {
+			return err
+		}
+		return failed
+	})
+	assert.ErrorIs(t, err, failed)
+
+	milk, err := backend.GetByName(ctx, domain.Product{Source: "a", Id: 1})
+	assert.NoError(t, err)
+	assert.Equal(t, "11", milk.Price.String())
+	_, err = backend.GetByName(ctx, domain.Product{Source: "a", Id: 3})
+	assert.ErrorIs(t, err, domain.ErrProductNotFound)
+}
+
+func (c conformance) testStats(t *testing.T) {
+	ctx := context.Background()
+	backend := c.seeded(t,
+		product("a", 1, "Milk", "10"),
+		product("a", 2, "Milkshake", "20"),
+		product("a", 3, "Bread", "30"),
+		product("a", 4, "Butter", "40"),
+		product("a", 5, "Gone", "1000"),
+	)
+	assert.NoError(t, backend.DeleteProduct(ctx, domain.Product{Source: "a", Id: 5}))
+	assert.NoError(t, backend.UpdateProduct(ctx, product("a", 3, "Bread", "30")))
+	assert.NoError(t, backend.UpdateProduct(ctx, product("a", 3, "Bread", "32")))
+	assert.NoError(t, backend.UpdateProduct(ctx, product("a", 1, "Milk", "12")))
+
+	stats, err := backend.GetStats(ctx, domain.StatsFilter{
+		HistogramBounds: []primitive.Decimal128{price("0"), price("25"), price("50")},
+		ChangedSince:    time.Now().Add(-time.Hour),
+		TopChanged:      1,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(4), stats.Total)
+	assert.Equal(t, "12", stats.MinPrice.String())
+	assert.Equal(t, "40", stats.MaxPrice.String())
+	assert.Equal(t, 0, compareDecimal(price("26"), stats.AvgPrice), stats.AvgPrice.String())
+	assert.Equal(t, 0, compareDecimal(price("26"), stats.MedianPrice), stats.MedianPrice.String())
+	assert.Equal(t, int64(2), stats.ChangedRecently)
+	if assert.Len(t, stats.MostChanged, 1) {
+		assert.Equal(t, 3, stats.MostChanged[0].Id)
+		assert.Equal(t, 2, stats.MostChanged[0].ChangesCount)
+	}
+	if assert.Len(t, stats.Histogram, 2) {
+		assert.Equal(t, int64(2), stats.Histogram[0].Count)
+		assert.Equal(t, int64(2), stats.Histogram[1].Count)
+	}
+
+	stats, err = backend.GetStats(ctx, domain.StatsFilter{Name: "MILK", HistogramBuckets: 2, TopChanged: 5})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), stats.Total)
+	var counted int64
+	for _, bucket := range stats.Histogram {
+		counted += bucket.Count
+	}
+	assert.Equal(t, int64(2), counted)
+}
+
+func (c conformance) testSchedules(t *testing.T) {
+	ctx := context.Background()
+	backend := c.newBackend(t)
+	now := time.Now().Truncate(time.Millisecond)
+
+	first, err := backend.CreateSchedule(ctx, domain.Schedule{Source: "a", Cron: "@hourly", NextRun: now.Add(-time.Minute), CreatedAt: now})
+	assert.NoError(t, err)
+	assert.False(t, first.Id.IsZero())
+	second, err := backend.CreateSchedule(ctx, domain.Schedule{Source: "b", Cron: "@daily", NextRun: now.Add(time.Hour), CreatedAt: now.Add(time.Second)})
+	assert.NoError(t, err)
+
+	schedules, err := backend.ListSchedules(ctx)
+	assert.NoError(t, err)
+	if assert.Len(t, schedules, 2) {
+		assert.Equal(t, first.Id, schedules[0].Id)
+	}
+
+	due, err := backend.DueSchedules(ctx, now)
+	assert.NoError(t, err)
+	if assert.Len(t, due, 1) {
+		assert.Equal(t, first.Id, due[0].Id)
+	}
+
+	claimed, err := backend.ClaimScheduleRun(ctx, first.Id, due[0].NextRun, now.Add(time.Hour))
+	assert.NoError(t, err)
+	assert.True(t, claimed)
+	claimed, err = backend.ClaimScheduleRun(ctx, first.Id, due[0].NextRun, now.Add(time.Hour))
+	assert.NoError(t, err)
+	assert.False(t, claimed, "a run is claimed once")
+
+	assert.NoError(t, backend.FinishScheduleRun(ctx, first.Id, now, "Success", ""))
+	got, err := backend.GetSchedule(ctx, first.Id)
+	assert.NoError(t, err)
+	assert.Equal(t, "Success", got.LastStatus)
+	assert.NotNil(t, got.LastRun)
+
+	paused, err := backend.SetSchedulePaused(ctx, second.Id, true, time.Time{})
+	assert.NoError(t, err)
+	assert.True(t, paused.Paused)
+	assert.True(t, paused.NextRun.Equal(second.NextRun), "pausing keeps next run")
+
+	assert.NoError(t, backend.DeleteSchedule(ctx, second.Id))
+	assert.ErrorIs(t, backend.DeleteSchedule(ctx, second.Id), domain.ErrScheduleNotFound)
+	_, err = backend.GetSchedule(ctx, second.Id)
+	assert.ErrorIs(t, err, domain.ErrScheduleNotFound)
+	_, err = backend.SetSchedulePaused(ctx, second.Id, false, now)
+	assert.ErrorIs(t, err, domain.ErrScheduleNotFound)
+}
+
+func (c conformance) testLeases(t *testing.T) {
+	ctx := context.Background()
+	backend := c.newBackend(t)
+
+	ok, err := backend.AcquireLease(ctx, "scheduler", "one", time.Minute)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	ok, err = backend.AcquireLease(ctx, "scheduler", "two", time.Minute)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+	ok, err = backend.AcquireLease(ctx, "scheduler", "one", time.Minute)
+	assert.NoError(t, err)
+	assert.True(t, ok, "the holder renews its lease")
+
+	assert.NoError(t, backend.ReleaseLease(ctx, "scheduler", "two"))
+	ok, err = backend.AcquireLease(ctx, "scheduler", "two", time.Minute)
+	assert.NoError(t, err)
+	assert.False(t, ok, "only the holder releases")
+
+	assert.NoError(t, backend.ReleaseLease(ctx, "scheduler", "one"))
+	ok, err = backend.AcquireLease(ctx, "scheduler", "two", time.Minute)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+}
+
+func (c conformance) testAlerts(t *testing.T) {
+	ctx := context.Background()
+	backend := c.newBackend(t)
+	now := time.Now().Truncate(time.Millisecond)
+
+	rule, err := backend.CreateAlertRule(ctx, domain.AlertRule{Name: "milk", WebhookUrl: "http://hooks", Secret: "key", CreatedAt: now})
+	assert.NoError(t, err)
+	rules, err := backend.ListAlertRules(ctx)
+	assert.NoError(t, err)
+	if assert.Len(t, rules, 1) {
+		assert.Equal(t, rule.Id, rules[0].Id)
+		assert.Equal(t, "key", rules[0].Secret)
+	}
+	assert.NoError(t, backend.DeleteAlertRule(ctx, rule.Id))
+	assert.ErrorIs(t, backend.DeleteAlertRule(ctx, rule.Id), domain.ErrAlertRuleNotFound)
+
+	for i := range 3 {
+		assert.NoError(t, backend.InsertDeadLetter(ctx, domain.DeadLetter{
+			RuleId:   rule.Id,
+			Attempts: i + 1,
+			FailedAt: now.Add(time.Duration(i) * time.Second),
+		}))
+	}
+	letters, err := backend.ListDeadLetters(ctx, 2)
+	assert.NoError(t, err)
+	if assert.Len(t, letters, 2) {
+		assert.Equal(t, 3, letters[0].Attempts)
+		assert.Equal(t, 2, letters[1].Attempts)
+	}
+}
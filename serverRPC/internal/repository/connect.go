@@ -3,14 +3,17 @@ package repository
 import (
 	"context"
 	"fmt"
+	"gRPC-server/internal/metrics"
 	"gRPC-server/pkg/logger"
 	"log"
 	"time"
 
 	"github.com/joho/godotenv"
 	"github.com/kelseyhightower/envconfig"
+	"go.mongodb.org/mongo-driver/event"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.opentelemetry.io/contrib/instrumentation/go.mongodb.org/mongo-driver/mongo/otelmongo"
 )
 
 type Config struct {
@@ -55,6 +58,7 @@ func NewMongoConnect() (*mongo.Database, error) { //добавить error
 
 	//Передать строку для подключения
 	opts.ApplyURI(cfg.db.URI)
+	opts.SetMonitor(commandMonitor())
 
 	//Установить аутентификационные данные
 	opts.SetAuth(options.Credential{
@@ -118,3 +122,21 @@ func connectWithRetry(ctx context.Context, cfg *Config, logger *logger.Logger, o
 
 	return nil, err
 }
+
+// commandMonitor feeds the latency of every command the driver runs into metrics.MongoLatency
+// and opens a span per command; the driver accepts only one monitor, so both share it.
+func commandMonitor() *event.CommandMonitor {
+	tracing := otelmongo.NewMonitor()
+
+	return &event.CommandMonitor{
+		Started: tracing.Started,
+		Succeeded: func(ctx context.Context, e *event.CommandSucceededEvent) {
+			metrics.MongoLatency.WithLabelValues(e.CommandName, "ok").Observe(e.Duration.Seconds())
+			tracing.Succeeded(ctx, e)
+		},
+		Failed: func(ctx context.Context, e *event.CommandFailedEvent) {
+			metrics.MongoLatency.WithLabelValues(e.CommandName, "error").Observe(e.Duration.Seconds())
+			tracing.Failed(ctx, e)
+		},
+	}
+}
@@ -0,0 +1,66 @@
+package repository
+
+import (
+	"context"
+	"gRPC-server/internal/domain"
+
+	"github.com/spf13/viper"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Export reads the products List would return straight off the cursor, send gets them one by one
+// in sort order and an error from it stops the export.
+func (m *MongoBackend) Export(ctx context.Context, sort domain.SortParams, send func(domain.Product) error) error {
+	cursor, err := m.products(ctx, sort)
+	if err != nil {
+		m.logger.FromContext(ctx).Errorf("Can't list products: %s", err)
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var product domain.Product
+		if err := cursor.Decode(&product); err != nil {
+			m.logger.FromContext(ctx).Errorf("Error decoding product: %s", err)
+			return err
+		}
+		if err := send(product); err != nil {
+			return err
+		}
+	}
+	return cursor.Err()
+}
+
+// products opens a cursor over one source or, without sort.Source, over the merged catalog.
+func (m *MongoBackend) products(ctx context.Context, sort domain.SortParams) (*mongo.Cursor, error) {
+	collection := m.db.Collection(viper.GetString("mongo.collection"))
+	if sort.Source == "" {
+		// the merge groups the whole catalog, an export of a big one doesn't fit the in-memory limit
+		return collection.Aggregate(ctx, mergedPipeline(sort), options.Aggregate().SetAllowDiskUse(true))
+	}
+
+	opts := options.Find().
+		SetSort(listSort(sort)).
+		SetSkip(int64(sort.PagingOffset)).
+		SetLimit(int64(sort.PagingLimit))
+
+	filter := bson.D{{Key: "source", Value: sort.Source}}
+	if !sort.IncludeDeleted {
+		filter = append(filter, bson.E{Key: "deleted_at", Value: nil})
+	}
+	return collection.Find(ctx, filter, opts)
+}
+
+// listSort orders by the requested field and then by (source, id) like the other backends,
+// so pages over equal values are stable between calls.
+func listSort(sort domain.SortParams) bson.D {
+	keys := bson.D{{Key: sort.SortField, Value: sort.SortAsc}}
+	for _, key := range []string{"source", "id"} {
+		if key != sort.SortField {
+			keys = append(keys, bson.E{Key: key, Value: 1})
+		}
+	}
+	return keys
+}
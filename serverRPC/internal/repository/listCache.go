@@ -0,0 +1,112 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"gRPC-server/internal/domain"
+	"gRPC-server/internal/metrics"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+)
+
+type unitOfWorkKey struct{}
+
+// unitOfWork remembers whether a transaction changed products, the List cache is cleared only
+// once it commits.
+type unitOfWork struct {
+	changed bool
+}
+
+// WithTransaction runs fn in the unit of work of the backend and clears the List cache after
+// a commit that changed products.
+func (r *Repository) WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if r.listCache == nil || ctx.Value(unitOfWorkKey{}) != nil {
+		return r.Sorting.WithTransaction(ctx, fn)
+	}
+
+	work := &unitOfWork{}
+	err := r.Sorting.WithTransaction(context.WithValue(ctx, unitOfWorkKey{}, work), fn)
+	if err == nil && work.changed {
+		r.invalidate(ctx)
+	}
+	return err
+}
+
+func (r *Repository) UpdateProduct(ctx context.Context, product domain.Product) error {
+	if err := r.Sorting.UpdateProduct(ctx, product); err != nil {
+		return err
+	}
+	r.changed(ctx)
+	return nil
+}
+
+func (r *Repository) DeleteProduct(ctx context.Context, product domain.Product) error {
+	if err := r.Sorting.DeleteProduct(ctx, product); err != nil {
+		return err
+	}
+	r.changed(ctx)
+	return nil
+}
+
+func (r *Repository) RestoreProduct(ctx context.Context, product domain.Product) error {
+	if err := r.Sorting.RestoreProduct(ctx, product); err != nil {
+		return err
+	}
+	r.changed(ctx)
+	return nil
+}
+
+func (r *Repository) PurgeDeleted(ctx context.Context, olderThan time.Time) (int64, error) {
+	purged, err := r.Sorting.PurgeDeleted(ctx, olderThan)
+	if err != nil {
+		return 0, err
+	}
+	if purged > 0 {
+		r.changed(ctx)
+	}
+	return purged, nil
+}
+
+// changed is called after a write to products: inside a unit of work it marks it, outside the
+// write is already committed and the cache is cleared at once.
+func (r *Repository) changed(ctx context.Context) {
+	if r.listCache == nil {
+		return
+	}
+	if work, ok := ctx.Value(unitOfWorkKey{}).(*unitOfWork); ok {
+		work.changed = true
+		return
+	}
+	r.invalidate(ctx)
+}
+
+// invalidate moves listVersion on before clearing, so a List that read the catalog before the
+// commit stores its result under a key no one asks for any more.
+func (r *Repository) invalidate(ctx context.Context) {
+	r.listVersion.Add(1)
+	r.listCache.Clear(ctx)
+	metrics.ListCacheInvalidations.Inc()
+}
+
+// listKey identifies a List call by the params that change its result; the merge rule only
+// matters for the merged catalog and priorities are written in source order.
+func listKey(version uint64, sort domain.SortParams) string {
+	var key strings.Builder
+	fmt.Fprintf(&key, "%d|%s|%d|%d|%d|%t|%q", version, sort.SortField, sort.SortAsc,
+		sort.PagingOffset, sort.PagingLimit, sort.IncludeDeleted, sort.Source)
+	if sort.Source != "" {
+		return key.String()
+	}
+
+	strategy := sort.Merge.Strategy
+	if strategy == "" {
+		strategy = domain.MergePriority
+	}
+	fmt.Fprintf(&key, "|%s", strategy)
+	for _, source := range slices.Sorted(maps.Keys(sort.Merge.Priorities)) {
+		fmt.Fprintf(&key, "|%q=%d", source, sort.Merge.Priorities[source])
+	}
+	return key.String()
+}
@@ -0,0 +1,109 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"gRPC-server/internal/cache"
+	"gRPC-server/internal/domain"
+	mock_repository "gRPC-server/internal/repository/mocks"
+	"gRPC-server/pkg/logger"
+	"gRPC-server/pkg/parseCSV/grpcPb"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestListCache(t *testing.T) {
+	products := []domain.Product{{Id: 1, Name: "milk", Source: "web-app"}}
+	req := &grpcPb.ListRequest{SortField: 1, SortAsc: 1, PagingLimit: 10, Source: "web-app"}
+	inTransaction := func(ctx context.Context, fn func(ctx context.Context) error) error {
+		return fn(ctx)
+	}
+
+	testTables := []struct {
+		name      string
+		write     func(m *mock_repository.MockSorting, repo *Repository) error
+		wantReads int
+	}{
+		{
+			name:      "Hit",
+			write:     func(m *mock_repository.MockSorting, repo *Repository) error { return nil },
+			wantReads: 1,
+		},
+		{
+			name: "Import commit invalidates",
+			write: func(m *mock_repository.MockSorting, repo *Repository) error {
+				m.EXPECT().WithTransaction(gomock.Any(), gomock.Any()).DoAndReturn(inTransaction)
+				m.EXPECT().Insert(gomock.Any(), products).Return(nil)
+				return repo.WithTransaction(context.Background(), func(ctx context.Context) error {
+					_, err := repo.Fetch(ctx, products)
+					return err
+				})
+			},
+			wantReads: 2,
+		},
+		{
+			name: "Failed transaction keeps the cache",
+			write: func(m *mock_repository.MockSorting, repo *Repository) error {
+				m.EXPECT().WithTransaction(gomock.Any(), gomock.Any()).DoAndReturn(inTransaction)
+				m.EXPECT().UpdateProduct(gomock.Any(), products[0]).Return(nil)
+				err := repo.WithTransaction(context.Background(), func(ctx context.Context) error {
+					if err := repo.UpdateProduct(ctx, products[0]); err != nil {
+						return err
+					}
+					return errors.New("rollback")
+				})
+				if err == nil {
+					return errors.New("transaction didn't fail")
+				}
+				return nil
+			},
+			wantReads: 1,
+		},
+		{
+			name: "Delete outside a transaction invalidates",
+			write: func(m *mock_repository.MockSorting, repo *Repository) error {
+				m.EXPECT().DeleteProduct(gomock.Any(), products[0]).Return(nil)
+				return repo.DeleteProduct(context.Background(), products[0])
+			},
+			wantReads: 2,
+		},
+	}
+
+	for _, table := range testTables {
+		t.Run(table.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			mockRepo := mock_repository.NewMockSorting(c)
+			repo := &Repository{Sorting: mockRepo, logger: logger.GetLogger(), listCache: cache.NewLRU(10, 0, 0)}
+			mockRepo.EXPECT().List(gomock.Any(), sortParams(req, domain.MergeRule{})).Return(products, nil).Times(table.wantReads)
+
+			_, err := repo.List(context.Background(), req, domain.MergeRule{})
+			assert.NoError(t, err)
+
+			assert.NoError(t, table.write(mockRepo, repo))
+
+			got, err := repo.List(context.Background(), req, domain.MergeRule{})
+			assert.NoError(t, err)
+			assert.Equal(t, products, got)
+		})
+	}
+}
+
+func TestListKey(t *testing.T) {
+	merged := domain.SortParams{
+		SortField: "name",
+		SortAsc:   1,
+		Merge:     domain.MergeRule{Priorities: map[string]int{"web-app": 10, "supplier": 5}},
+	}
+	samePriorities := merged
+	samePriorities.Merge = domain.MergeRule{Strategy: domain.MergePriority, Priorities: map[string]int{"supplier": 5, "web-app": 10}}
+	oneSource := domain.SortParams{SortField: "name", SortAsc: 1, Source: "web-app", Merge: merged.Merge}
+
+	assert.Equal(t, listKey(1, merged), listKey(1, samePriorities))
+	assert.NotEqual(t, listKey(1, merged), listKey(2, merged))
+	assert.NotEqual(t, listKey(1, merged), listKey(1, oneSource))
+	assert.Equal(t, listKey(1, oneSource), listKey(1, domain.SortParams{SortField: "name", SortAsc: 1, Source: "web-app"}))
+}
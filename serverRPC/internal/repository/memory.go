@@ -0,0 +1,519 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"gRPC-server/internal/domain"
+	"gRPC-server/pkg/logger"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type productKey struct {
+	source string
+	id     int
+}
+
+func keyOf(product domain.Product) productKey {
+	return productKey{source: product.Source, id: product.Id}
+}
+
+type memoryProduct struct {
+	product  domain.Product
+	mergeKey string
+	changes  int
+}
+
+// MemoryBackend keeps everything in the memory of the process, for local development and tests.
+// Nothing survives a restart and replicas don't share it, so the scheduler lease only
+// coordinates callers of the same backend.
+type MemoryBackend struct {
+	logger *logger.Logger
+
+	mu       sync.RWMutex
+	products map[productKey]memoryProduct
+	history  []domain.PriceChange
+	events   memoryEvents
+
+	// transactions run one at a time, a second one waits for the first to commit
+	txMu sync.Mutex
+
+	schedules   map[primitive.ObjectID]domain.Schedule
+	leases      map[string]memoryLease
+	alertRules  map[primitive.ObjectID]domain.AlertRule
+	deadLetters []domain.DeadLetter
+}
+
+func NewMemoryBackend(logger *logger.Logger) *MemoryBackend {
+	return &MemoryBackend{
+		logger:     logger,
+		products:   map[productKey]memoryProduct{},
+		events:     newMemoryEvents(),
+		schedules:  map[primitive.ObjectID]domain.Schedule{},
+		leases:     map[string]memoryLease{},
+		alertRules: map[primitive.ObjectID]domain.AlertRule{},
+	}
+}
+
+// memoryState is what product operations read and write: the committed products or
+// a running transaction on top of them.
+type memoryState interface {
+	get(key productKey) (memoryProduct, bool)
+	all() []memoryProduct
+	put(product memoryProduct)
+	remove(key productKey)
+	record(change domain.PriceChange)
+	emit(event domain.ProductEvent)
+}
+
+type committedState struct {
+	m *MemoryBackend
+}
+
+func (c committedState) get(key productKey) (memoryProduct, bool) {
+	product, ok := c.m.products[key]
+	return product, ok
+}
+
+func (c committedState) all() []memoryProduct {
+	return slices.Collect(maps.Values(c.m.products))
+}
+
+func (c committedState) put(product memoryProduct) {
+	c.m.products[keyOf(product.product)] = product
+}
+
+func (c committedState) remove(key productKey) {
+	delete(c.m.products, key)
+}
+
+func (c committedState) record(change domain.PriceChange) {
+	c.m.history = append(c.m.history, change)
+}
+
+func (c committedState) emit(event domain.ProductEvent) {
+	c.m.events.publish(event)
+}
+
+type memoryTxKey struct{}
+
+// memoryTx collects the writes of a transaction, a removed product is kept as a nil entry.
+type memoryTx struct {
+	m       *MemoryBackend
+	writes  map[productKey]*memoryProduct
+	history []domain.PriceChange
+	events  []domain.ProductEvent
+}
+
+func (t *memoryTx) get(key productKey) (memoryProduct, bool) {
+	if product, ok := t.writes[key]; ok {
+		if product == nil {
+			return memoryProduct{}, false
+		}
+		return *product, true
+	}
+	t.m.mu.RLock()
+	defer t.m.mu.RUnlock()
+	product, ok := t.m.products[key]
+	return product, ok
+}
+
+func (t *memoryTx) all() []memoryProduct {
+	t.m.mu.RLock()
+	merged := maps.Clone(t.m.products)
+	t.m.mu.RUnlock()
+
+	for key, product := range t.writes {
+		if product == nil {
+			delete(merged, key)
+			continue
+		}
+		merged[key] = *product
+	}
+	return slices.Collect(maps.Values(merged))
+}
+
+func (t *memoryTx) put(product memoryProduct) {
+	t.writes[keyOf(product.product)] = &product
+}
+
+func (t *memoryTx) remove(key productKey) {
+	t.writes[key] = nil
+}
+
+func (t *memoryTx) record(change domain.PriceChange) {
+	t.history = append(t.history, change)
+}
+
+func (t *memoryTx) emit(event domain.ProductEvent) {
+	t.events = append(t.events, event)
+}
+
+func (t *memoryTx) commit() {
+	t.m.mu.Lock()
+	defer t.m.mu.Unlock()
+
+	for key, product := range t.writes {
+		if product == nil {
+			delete(t.m.products, key)
+			continue
+		}
+		t.m.products[key] = *product
+	}
+	t.m.history = append(t.m.history, t.history...)
+	for _, event := range t.events {
+		t.m.events.publish(event)
+	}
+}
+
+// WithTransaction buffers the writes fn makes through ctx and applies them together once fn
+// returns nil; with an error nothing is applied. A call inside a transaction joins it.
+func (m *MemoryBackend) WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if tx, ok := ctx.Value(memoryTxKey{}).(*memoryTx); ok && tx.m == m {
+		return fn(ctx)
+	}
+
+	m.txMu.Lock()
+	defer m.txMu.Unlock()
+
+	tx := &memoryTx{m: m, writes: map[productKey]*memoryProduct{}}
+	if err := fn(context.WithValue(ctx, memoryTxKey{}, tx)); err != nil {
+		return err
+	}
+	tx.commit()
+	return nil
+}
+
+// read runs fn on the state ctx sees, fn must not keep what it gets past the call.
+func (m *MemoryBackend) read(ctx context.Context, fn func(state memoryState)) {
+	if tx, ok := ctx.Value(memoryTxKey{}).(*memoryTx); ok && tx.m == m {
+		fn(tx)
+		return
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	fn(committedState{m})
+}
+
+func (m *MemoryBackend) write(ctx context.Context, fn func(state memoryState) error) error {
+	if tx, ok := ctx.Value(memoryTxKey{}).(*memoryTx); ok && tx.m == m {
+		return fn(tx)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return fn(committedState{m})
+}
+
+// Insert adds all products or, when one of them already exists, none.
+func (m *MemoryBackend) Insert(ctx context.Context, products []domain.Product) error {
+	if len(products) == 0 {
+		return domain.ErrNoProducts
+	}
+
+	now := time.Now()
+	return m.write(ctx, func(state memoryState) error {
+		seen := make(map[productKey]bool, len(products))
+		for _, product := range products {
+			key := keyOf(product)
+			if _, ok := state.get(key); ok || seen[key] {
+				return fmt.Errorf("product %d of source %q already exists", product.Id, product.Source)
+			}
+			seen[key] = true
+		}
+
+		for _, product := range products {
+			product = cloneProduct(product)
+			if product.DateOfChange.IsZero() {
+				product.DateOfChange = now
+			}
+			state.put(memoryProduct{product: product, mergeKey: domain.MergeKey(product.Name)})
+			state.emit(domain.ProductEvent{Type: domain.EventInsert, Product: product, Time: now})
+		}
+		return nil
+	})
+}
+
+// List returns the products of sort.Source, or the merged catalog when no source is given.
+func (m *MemoryBackend) List(ctx context.Context, sort domain.SortParams) ([]domain.Product, error) {
+	var products []domain.Product
+	err := m.Export(ctx, sort, func(product domain.Product) error {
+		products = append(products, product)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return products, nil
+}
+
+// Export sends a snapshot taken when it starts, writes made while it sends are not seen.
+func (m *MemoryBackend) Export(ctx context.Context, sort domain.SortParams, send func(domain.Product) error) error {
+	var stored []memoryProduct
+	m.read(ctx, func(state memoryState) {
+		stored = state.all()
+	})
+
+	for _, product := range selectProducts(stored, sort) {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := send(product); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetByName finds the product by source and id, a missing one is domain.ErrProductNotFound.
+func (m *MemoryBackend) GetByName(ctx context.Context, product domain.Product) (domain.Product, error) {
+	var stored memoryProduct
+	var ok bool
+	m.read(ctx, func(state memoryState) {
+		stored, ok = state.get(keyOf(product))
+	})
+	if !ok {
+		return domain.Product{}, domain.ErrProductNotFound
+	}
+	return cloneProduct(stored.product), nil
+}
+
+func (m *MemoryBackend) GetByIds(ctx context.Context, source string, ids []int) ([]domain.Product, error) {
+	var products []domain.Product
+	m.read(ctx, func(state memoryState) {
+		for _, id := range ids {
+			if stored, ok := state.get(productKey{source: source, id: id}); ok {
+				products = append(products, cloneProduct(stored.product))
+			}
+		}
+	})
+	return products, nil
+}
+
+// UpdateProduct sets the new name and price and keeps the previous values in the history.
+func (m *MemoryBackend) UpdateProduct(ctx context.Context, product domain.Product) error {
+	now := time.Now()
+	return m.write(ctx, func(state memoryState) error {
+		stored, ok := state.get(keyOf(product))
+		if !ok {
+			return domain.ErrProductNotFound
+		}
+		before := stored.product
+
+		stored.product.Name = product.Name
+		stored.mergeKey = domain.MergeKey(product.Name)
+		stored.product.Price = product.Price
+		stored.product.DateOfChange = now
+		stored.changes++
+		state.put(stored)
+
+		state.record(domain.PriceChange{
+			Source:    product.Source,
+			Id:        product.Id,
+			OldName:   before.Name,
+			NewName:   product.Name,
+			OldPrice:  before.Price,
+			NewPrice:  product.Price,
+			ChangedAt: now,
+		})
+		state.emit(domain.ProductEvent{Type: domain.EventUpdate, Product: cloneProduct(stored.product), Time: now})
+		return nil
+	})
+}
+
+// DeleteProduct only marks the product as deleted, PurgeDeleted removes it for good.
+func (m *MemoryBackend) DeleteProduct(ctx context.Context, product domain.Product) error {
+	now := time.Now()
+	return m.write(ctx, func(state memoryState) error {
+		stored, ok := state.get(keyOf(product))
+		if !ok || stored.product.DeletedAt != nil {
+			return domain.ErrProductNotFound
+		}
+
+		deletedAt := now
+		stored.product.DeletedAt = &deletedAt
+		stored.product.DateOfChange = now
+		state.put(stored)
+		state.emit(domain.ProductEvent{Type: domain.EventDelete, Product: cloneProduct(stored.product), Time: now})
+		return nil
+	})
+}
+
+func (m *MemoryBackend) RestoreProduct(ctx context.Context, product domain.Product) error {
+	now := time.Now()
+	return m.write(ctx, func(state memoryState) error {
+		stored, ok := state.get(keyOf(product))
+		if !ok || stored.product.DeletedAt == nil {
+			return domain.ErrProductNotFound
+		}
+
+		stored.product.DeletedAt = nil
+		stored.product.DateOfChange = now
+		state.put(stored)
+		state.emit(domain.ProductEvent{Type: domain.EventUpdate, Product: cloneProduct(stored.product), Time: now})
+		return nil
+	})
+}
+
+// PurgeDeleted removes products that were soft-deleted before olderThan and returns how many were removed.
+func (m *MemoryBackend) PurgeDeleted(ctx context.Context, olderThan time.Time) (int64, error) {
+	now := time.Now()
+	var purged int64
+	err := m.write(ctx, func(state memoryState) error {
+		for _, stored := range state.all() {
+			if stored.product.DeletedAt == nil || !stored.product.DeletedAt.Before(olderThan) {
+				continue
+			}
+			state.remove(keyOf(stored.product))
+			state.emit(domain.ProductEvent{Type: domain.EventDelete, Product: cloneProduct(stored.product), Time: now})
+			purged++
+		}
+		return nil
+	})
+	return purged, err
+}
+
+func (m *MemoryBackend) Ping(ctx context.Context) error {
+	return nil
+}
+
+// selectProducts does in memory what List asks Mongo for: filter, merge, sort and page.
+func selectProducts(stored []memoryProduct, sort domain.SortParams) []domain.Product {
+	selected := make([]memoryProduct, 0, len(stored))
+	for _, item := range stored {
+		if sort.Source != "" && item.product.Source != sort.Source {
+			continue
+		}
+		if !sort.IncludeDeleted && item.product.DeletedAt != nil {
+			continue
+		}
+		selected = append(selected, item)
+	}
+	if sort.Source == "" {
+		selected = mergeProducts(selected, sort.Merge)
+	}
+
+	products := make([]domain.Product, len(selected))
+	for i, item := range selected {
+		products[i] = cloneProduct(item.product)
+	}
+
+	// equal sort values keep the (source, id) order, so pages are stable between calls
+	slices.SortFunc(products, func(a, b domain.Product) int {
+		if c := strings.Compare(a.Source, b.Source); c != 0 {
+			return c
+		}
+		return cmpInt(a.Id, b.Id)
+	})
+	slices.SortStableFunc(products, func(a, b domain.Product) int {
+		return int(sort.SortAsc) * compareField(a, b, sort.SortField)
+	})
+
+	offset := min(int(sort.PagingOffset), len(products))
+	products = products[offset:]
+	if sort.PagingLimit > 0 && int(sort.PagingLimit) < len(products) {
+		products = products[:sort.PagingLimit]
+	}
+	return products
+}
+
+// mergeProducts keeps one product per stored merge key, chosen the way mergedPipeline does. Only
+// copies from different sources merge, products of one source that share a key all stay.
+func mergeProducts(products []memoryProduct, merge domain.MergeRule) []memoryProduct {
+	groups := map[string][]memoryProduct{}
+	for _, item := range products {
+		groups[item.mergeKey] = append(groups[item.mergeKey], item)
+	}
+
+	var merged []memoryProduct
+	for _, group := range groups {
+		winner, singleSource := group[0], true
+		for _, item := range group[1:] {
+			singleSource = singleSource && item.product.Source == winner.product.Source
+			if mergeWins(item.product, winner.product, merge) {
+				winner = item
+			}
+		}
+		if singleSource {
+			merged = append(merged, group...)
+		} else {
+			merged = append(merged, winner)
+		}
+	}
+	return merged
+}
+
+func mergeWins(a, b domain.Product, merge domain.MergeRule) bool {
+	priority := cmpInt(merge.Priorities[b.Source], merge.Priorities[a.Source])
+	newest := b.DateOfChange.Compare(a.DateOfChange)
+
+	var order []int
+	switch merge.Strategy {
+	case domain.MergeLowestPrice:
+		order = []int{compareDecimal(a.Price, b.Price), priority}
+	case domain.MergeNewest:
+		order = []int{newest, priority}
+	default:
+		order = []int{priority, newest}
+	}
+	order = append(order, strings.Compare(a.Source, b.Source), cmpInt(a.Id, b.Id))
+
+	for _, c := range order {
+		if c != 0 {
+			return c < 0
+		}
+	}
+	return false
+}
+
+func compareField(a, b domain.Product, field string) int {
+	switch field {
+	case "name":
+		return strings.Compare(a.Name, b.Name)
+	case "price":
+		return compareDecimal(a.Price, b.Price)
+	default:
+		return cmpInt(a.Id, b.Id)
+	}
+}
+
+func cmpInt(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func toDecimal(d primitive.Decimal128) decimal.Decimal {
+	value, err := decimal.NewFromString(d.String())
+	if err != nil {
+		return decimal.Zero
+	}
+	return value
+}
+
+func fromDecimal(d decimal.Decimal) primitive.Decimal128 {
+	value, _ := primitive.ParseDecimal128(d.String())
+	return value
+}
+
+func compareDecimal(a, b primitive.Decimal128) int {
+	return toDecimal(a).Cmp(toDecimal(b))
+}
+
+// cloneProduct copies DeletedAt, so callers can't change what the backend stores.
+func cloneProduct(product domain.Product) domain.Product {
+	if product.DeletedAt != nil {
+		deletedAt := *product.DeletedAt
+		product.DeletedAt = &deletedAt
+	}
+	return product
+}
@@ -0,0 +1,202 @@
+package repository
+
+import (
+	"cmp"
+	"context"
+	"gRPC-server/internal/domain"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type memoryLease struct {
+	holder    string
+	expiresAt time.Time
+}
+
+func (m *MemoryBackend) CreateSchedule(ctx context.Context, schedule domain.Schedule) (domain.Schedule, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	schedule.Id = primitive.NewObjectID()
+	m.schedules[schedule.Id] = schedule
+	return schedule, nil
+}
+
+func (m *MemoryBackend) ListSchedules(ctx context.Context) ([]domain.Schedule, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	schedules := slices.Collect(maps.Values(m.schedules))
+	slices.SortFunc(schedules, func(a, b domain.Schedule) int {
+		return cmp.Or(a.CreatedAt.Compare(b.CreatedAt), strings.Compare(a.Id.Hex(), b.Id.Hex()))
+	})
+	return schedules, nil
+}
+
+func (m *MemoryBackend) GetSchedule(ctx context.Context, id primitive.ObjectID) (domain.Schedule, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	schedule, ok := m.schedules[id]
+	if !ok {
+		return domain.Schedule{}, domain.ErrScheduleNotFound
+	}
+	return schedule, nil
+}
+
+// SetSchedulePaused pauses or resumes a schedule; nextRun is only stored when resuming.
+func (m *MemoryBackend) SetSchedulePaused(ctx context.Context, id primitive.ObjectID, paused bool, nextRun time.Time) (domain.Schedule, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	schedule, ok := m.schedules[id]
+	if !ok {
+		return domain.Schedule{}, domain.ErrScheduleNotFound
+	}
+	schedule.Paused = paused
+	if !paused {
+		schedule.NextRun = nextRun
+	}
+	m.schedules[id] = schedule
+	return schedule, nil
+}
+
+func (m *MemoryBackend) DeleteSchedule(ctx context.Context, id primitive.ObjectID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.schedules[id]; !ok {
+		return domain.ErrScheduleNotFound
+	}
+	delete(m.schedules, id)
+	return nil
+}
+
+// DueSchedules returns active schedules whose next run is not after now.
+func (m *MemoryBackend) DueSchedules(ctx context.Context, now time.Time) ([]domain.Schedule, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var due []domain.Schedule
+	for _, schedule := range m.schedules {
+		if !schedule.Paused && !schedule.NextRun.After(now) {
+			due = append(due, schedule)
+		}
+	}
+	slices.SortFunc(due, func(a, b domain.Schedule) int {
+		return a.NextRun.Compare(b.NextRun)
+	})
+	return due, nil
+}
+
+// ClaimScheduleRun moves next_run from scheduled to next, only one caller can move it.
+func (m *MemoryBackend) ClaimScheduleRun(ctx context.Context, id primitive.ObjectID, scheduled, next time.Time) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	schedule, ok := m.schedules[id]
+	if !ok || !schedule.NextRun.Equal(scheduled) {
+		return false, nil
+	}
+	schedule.NextRun = next
+	m.schedules[id] = schedule
+	return true, nil
+}
+
+func (m *MemoryBackend) FinishScheduleRun(ctx context.Context, id primitive.ObjectID, at time.Time, status, runErr string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	schedule, ok := m.schedules[id]
+	if !ok {
+		return nil
+	}
+	schedule.LastRun = &at
+	schedule.LastStatus = status
+	schedule.LastError = runErr
+	m.schedules[id] = schedule
+	return nil
+}
+
+// AcquireLease takes or renews the lease name for holder until now+ttl, unless another
+// holder has a lease that hasn't expired.
+func (m *MemoryBackend) AcquireLease(ctx context.Context, name, holder string, ttl time.Duration) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	if lease, ok := m.leases[name]; ok && lease.holder != holder && !lease.expiresAt.Before(now) {
+		return false, nil
+	}
+	m.leases[name] = memoryLease{holder: holder, expiresAt: now.Add(ttl)}
+	return true, nil
+}
+
+func (m *MemoryBackend) ReleaseLease(ctx context.Context, name, holder string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if lease, ok := m.leases[name]; ok && lease.holder == holder {
+		delete(m.leases, name)
+	}
+	return nil
+}
+
+func (m *MemoryBackend) CreateAlertRule(ctx context.Context, rule domain.AlertRule) (domain.AlertRule, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	rule.Id = primitive.NewObjectID()
+	m.alertRules[rule.Id] = rule
+	return rule, nil
+}
+
+func (m *MemoryBackend) ListAlertRules(ctx context.Context) ([]domain.AlertRule, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	rules := slices.Collect(maps.Values(m.alertRules))
+	slices.SortFunc(rules, func(a, b domain.AlertRule) int {
+		return cmp.Or(a.CreatedAt.Compare(b.CreatedAt), strings.Compare(a.Id.Hex(), b.Id.Hex()))
+	})
+	return rules, nil
+}
+
+func (m *MemoryBackend) DeleteAlertRule(ctx context.Context, id primitive.ObjectID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.alertRules[id]; !ok {
+		return domain.ErrAlertRuleNotFound
+	}
+	delete(m.alertRules, id)
+	return nil
+}
+
+func (m *MemoryBackend) InsertDeadLetter(ctx context.Context, letter domain.DeadLetter) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	letter.Id = primitive.NewObjectID()
+	m.deadLetters = append(m.deadLetters, letter)
+	return nil
+}
+
+// ListDeadLetters returns the newest limit dead letters first.
+func (m *MemoryBackend) ListDeadLetters(ctx context.Context, limit int) ([]domain.DeadLetter, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	letters := slices.Clone(m.deadLetters)
+	slices.SortStableFunc(letters, func(a, b domain.DeadLetter) int {
+		return b.FailedAt.Compare(a.FailedAt)
+	})
+	if limit > 0 && limit < len(letters) {
+		letters = letters[:limit]
+	}
+	return letters, nil
+}
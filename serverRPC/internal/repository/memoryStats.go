@@ -0,0 +1,155 @@
+package repository
+
+import (
+	"cmp"
+	"context"
+	"gRPC-server/internal/domain"
+	"math"
+	"slices"
+	"strings"
+
+	"github.com/shopspring/decimal"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// GetStats computes in memory what the $facet pipeline of MongoBackend.GetStats returns.
+func (m *MemoryBackend) GetStats(ctx context.Context, filter domain.StatsFilter) (domain.Stats, error) {
+	var stored []memoryProduct
+	m.read(ctx, func(state memoryState) {
+		stored = state.all()
+	})
+
+	var matched []memoryProduct
+	for _, item := range stored {
+		if statsMatches(item.product, filter) {
+			matched = append(matched, item)
+		}
+	}
+
+	var stats domain.Stats
+	if len(matched) == 0 {
+		return stats, nil
+	}
+
+	// stored prices are returned as they are, only computed ones go through decimal
+	prices := make([]storedPrice, len(matched))
+	sum := decimal.Zero
+	for i, item := range matched {
+		prices[i] = storedPrice{stored: item.product.Price, value: toDecimal(item.product.Price)}
+		sum = sum.Add(prices[i].value)
+	}
+	slices.SortFunc(prices, func(a, b storedPrice) int { return a.value.Cmp(b.value) })
+
+	total := len(prices)
+	stats.Total = int64(total)
+	stats.MinPrice = prices[0].stored
+	stats.MaxPrice = prices[total-1].stored
+	stats.AvgPrice = fromDecimal(sum.Div(decimal.NewFromInt(int64(total))))
+
+	stats.MedianPrice = prices[(total-1)/2].stored
+	if total%2 == 0 {
+		stats.MedianPrice = fromDecimal(prices[(total-1)/2].value.Add(prices[total/2].value).Div(decimal.NewFromInt(2)))
+	}
+	stats.Histogram = memoryHistogram(prices, filter)
+
+	var changed []memoryProduct
+	for _, item := range matched {
+		if item.changes == 0 {
+			continue
+		}
+		changed = append(changed, item)
+		if !item.product.DateOfChange.Before(filter.ChangedSince) {
+			stats.ChangedRecently++
+		}
+	}
+	slices.SortFunc(changed, func(a, b memoryProduct) int {
+		if c := cmp.Compare(b.changes, a.changes); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.product.Id, b.product.Id)
+	})
+	for _, item := range changed[:min(len(changed), int(filter.TopChanged))] {
+		stats.MostChanged = append(stats.MostChanged, domain.ProductChanges{
+			Product:      cloneProduct(item.product),
+			ChangesCount: item.changes,
+		})
+	}
+
+	return stats, nil
+}
+
+// storedPrice keeps the price as stored next to its value, stats return stored prices unchanged.
+type storedPrice struct {
+	stored primitive.Decimal128
+	value  decimal.Decimal
+}
+
+func statsMatches(product domain.Product, filter domain.StatsFilter) bool {
+	if product.DeletedAt != nil {
+		return false
+	}
+	if filter.Name != "" && !strings.Contains(strings.ToLower(product.Name), strings.ToLower(filter.Name)) {
+		return false
+	}
+	if filter.MinPrice != nil && compareDecimal(product.Price, *filter.MinPrice) < 0 {
+		return false
+	}
+	if filter.MaxPrice != nil && compareDecimal(product.Price, *filter.MaxPrice) > 0 {
+		return false
+	}
+	return true
+}
+
+// memoryHistogram counts sorted prices into the explicit bounds, or splits them like bucketAuto.
+func memoryHistogram(prices []storedPrice, filter domain.StatsFilter) []domain.HistogramBucket {
+	if len(filter.HistogramBounds) < 2 {
+		return bucketAuto(prices, filter.HistogramBuckets)
+	}
+
+	histogram := make([]domain.HistogramBucket, len(filter.HistogramBounds)-1)
+	for i := range histogram {
+		lower, upper := toDecimal(filter.HistogramBounds[i]), toDecimal(filter.HistogramBounds[i+1])
+		histogram[i] = domain.HistogramBucket{
+			LowerBound: filter.HistogramBounds[i],
+			UpperBound: filter.HistogramBounds[i+1],
+		}
+		for _, price := range prices {
+			if price.value.Cmp(lower) >= 0 && price.value.Cmp(upper) < 0 {
+				histogram[i].Count++
+			}
+		}
+	}
+	return histogram
+}
+
+// bucketAuto splits sorted prices the way $bucketAuto does: about the same number of prices
+// per bucket, equal prices never split.
+func bucketAuto(prices []storedPrice, buckets int32) []domain.HistogramBucket {
+	if buckets <= 0 {
+		return []domain.HistogramBucket{}
+	}
+	size := max(int(math.Round(float64(len(prices))/float64(buckets))), 1)
+
+	histogram := []domain.HistogramBucket{}
+	for start := 0; start < len(prices); {
+		end := min(start+size, len(prices))
+		if len(histogram) == int(buckets)-1 {
+			end = len(prices)
+		}
+		for end < len(prices) && prices[end].value.Equal(prices[end-1].value) {
+			end++
+		}
+
+		upper := prices[len(prices)-1]
+		if end < len(prices) {
+			upper = prices[end]
+		}
+		histogram = append(histogram, domain.HistogramBucket{
+			LowerBound: prices[start].stored,
+			UpperBound: upper.stored,
+			Count:      int64(end - start),
+		})
+		start = end
+	}
+	return histogram
+}
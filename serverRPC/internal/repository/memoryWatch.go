@@ -0,0 +1,101 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"gRPC-server/internal/domain"
+	"strconv"
+	"strings"
+)
+
+const (
+	memoryTokenPrefix = "mem:"
+
+	// maxMemoryEvents is how many past events a resume token can reach back
+	maxMemoryEvents = 10000
+)
+
+type memoryEvent struct {
+	seq   uint64
+	event domain.ProductEvent
+}
+
+// memoryEvents is the change log Watch reads, guarded by MemoryBackend.mu.
+type memoryEvents struct {
+	log     []memoryEvent
+	nextSeq uint64
+	// changed is closed and replaced on every publish, so watchers can wait on it
+	changed chan struct{}
+}
+
+func newMemoryEvents() memoryEvents {
+	return memoryEvents{nextSeq: 1, changed: make(chan struct{})}
+}
+
+func (e *memoryEvents) publish(event domain.ProductEvent) {
+	event.ResumeToken = memoryTokenPrefix + strconv.FormatUint(e.nextSeq, 10)
+	e.log = append(e.log, memoryEvent{seq: e.nextSeq, event: event})
+	e.nextSeq++
+	if len(e.log) > maxMemoryEvents {
+		e.log = e.log[len(e.log)-maxMemoryEvents:]
+	}
+
+	close(e.changed)
+	e.changed = make(chan struct{})
+}
+
+// after returns the events that follow seq and a channel closed on the next publish.
+func (e *memoryEvents) after(seq uint64) ([]memoryEvent, chan struct{}, error) {
+	if len(e.log) > 0 && seq+1 < e.log[0].seq {
+		return nil, nil, fmt.Errorf("%w: resume token is too old", domain.ErrInvalidArgument)
+	}
+	start := len(e.log)
+	for i, event := range e.log {
+		if event.seq > seq {
+			start = i
+			break
+		}
+	}
+	return append([]memoryEvent(nil), e.log[start:]...), e.changed, nil
+}
+
+// Watch sends product changes until ctx is done or send fails. Without a resume token it
+// starts from now; a token of a change older than the kept log is rejected.
+func (m *MemoryBackend) Watch(ctx context.Context, resumeToken string, send func(domain.ProductEvent) error) error {
+	m.mu.RLock()
+	last := m.events.nextSeq - 1
+	m.mu.RUnlock()
+
+	if resumeToken != "" {
+		if !strings.HasPrefix(resumeToken, memoryTokenPrefix) {
+			return fmt.Errorf("%w: unknown resume token", domain.ErrInvalidArgument)
+		}
+		seq, err := strconv.ParseUint(strings.TrimPrefix(resumeToken, memoryTokenPrefix), 10, 64)
+		if err != nil {
+			return fmt.Errorf("%w: malformed resume token", domain.ErrInvalidArgument)
+		}
+		last = seq
+	}
+
+	for {
+		m.mu.RLock()
+		events, changed, err := m.events.after(last)
+		m.mu.RUnlock()
+		if err != nil {
+			return err
+		}
+
+		for _, event := range events {
+			if err := send(event.event); err != nil {
+				return err
+			}
+			last = event.seq
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-changed:
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"gRPC-server/internal/domain"
+	"gRPC-server/pkg/logger"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryWatch(t *testing.T) {
+	ctx := context.Background()
+	backend := NewMemoryBackend(logger.GetLogger())
+	require.NoError(t, backend.Insert(ctx, []domain.Product{product("a", 1, "Milk", "10")}))
+	require.NoError(t, backend.UpdateProduct(ctx, product("a", 1, "Milk", "12")))
+
+	// an event is published once its unit of work commits
+	failed := errors.New("failed")
+	backend.WithTransaction(ctx, func(ctx context.Context) error {
+		backend.DeleteProduct(ctx, domain.Product{Source: "a", Id: 1})
+		return failed
+	})
+
+	stop := errors.New("stop")
+	var events []domain.ProductEvent
+	collect := func(count int) func(domain.ProductEvent) error {
+		return func(event domain.ProductEvent) error {
+			events = append(events, event)
+			if len(events) == count {
+				return stop
+			}
+			return nil
+		}
+	}
+
+	err := backend.Watch(ctx, memoryTokenPrefix+"0", collect(2))
+	assert.ErrorIs(t, err, stop)
+	if assert.Len(t, events, 2) {
+		assert.Equal(t, domain.EventInsert, events[0].Type)
+		assert.Equal(t, domain.EventUpdate, events[1].Type)
+		assert.Equal(t, "12", events[1].Product.Price.String())
+	}
+
+	// resuming after the insert delivers the update and waits for the next change
+	resume := events[0].ResumeToken
+	events = nil
+	done := make(chan error, 1)
+	go func() { done <- backend.Watch(ctx, resume, collect(2)) }()
+
+	time.Sleep(10 * time.Millisecond)
+	require.NoError(t, backend.DeleteProduct(ctx, domain.Product{Source: "a", Id: 1}))
+	select {
+	case err := <-done:
+		assert.ErrorIs(t, err, stop)
+	case <-time.After(time.Second):
+		t.Fatal("watch didn't deliver the delete")
+	}
+	if assert.Len(t, events, 2) {
+		assert.Equal(t, domain.EventUpdate, events[0].Type)
+		assert.Equal(t, domain.EventDelete, events[1].Type)
+	}
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	assert.ErrorIs(t, backend.Watch(cancelled, "", collect(0)), context.Canceled)
+
+	for _, token := range []string{"poll:1:abc", "mem:x"} {
+		err := backend.Watch(ctx, token, collect(0))
+		assert.ErrorIs(t, err, domain.ErrInvalidArgument, token)
+	}
+}
+
+func TestMemoryEventsTooOld(t *testing.T) {
+	events := newMemoryEvents()
+	for range maxMemoryEvents + 5 {
+		events.publish(domain.ProductEvent{Type: domain.EventUpdate})
+	}
+
+	_, _, err := events.after(1)
+	assert.ErrorIs(t, err, domain.ErrInvalidArgument)
+
+	kept, _, err := events.after(5)
+	assert.NoError(t, err)
+	assert.Len(t, kept, maxMemoryEvents)
+}
@@ -0,0 +1,83 @@
+-- Товары: (source, id) - идентичность товара, как индекс source_id в mongoDB
+CREATE TABLE products (
+	source         TEXT        NOT NULL,
+	id             BIGINT      NOT NULL,
+	name           TEXT        NOT NULL,
+	price          NUMERIC     NOT NULL,
+	deleted_at     TIMESTAMPTZ,
+	date_of_change TIMESTAMPTZ NOT NULL,
+	changes_count  INTEGER     NOT NULL DEFAULT 0,
+	CONSTRAINT products_source_id PRIMARY KEY (source, id)
+);
+CREATE INDEX products_merge_key ON products (lower(btrim(name)));
+CREATE INDEX products_deleted_at ON products (deleted_at) WHERE deleted_at IS NOT NULL;
+
+CREATE TABLE product_history (
+	source     TEXT        NOT NULL,
+	id         BIGINT      NOT NULL,
+	old_name   TEXT        NOT NULL,
+	new_name   TEXT        NOT NULL,
+	old_price  NUMERIC     NOT NULL,
+	new_price  NUMERIC     NOT NULL,
+	changed_at TIMESTAMPTZ NOT NULL
+);
+CREATE INDEX product_history_source_id ON product_history (source, id);
+
+-- Журнал изменений для Watch, seq - токен продолжения
+CREATE TABLE product_events (
+	seq            BIGSERIAL   PRIMARY KEY,
+	type           TEXT        NOT NULL,
+	source         TEXT        NOT NULL,
+	id             BIGINT      NOT NULL,
+	name           TEXT        NOT NULL,
+	price          NUMERIC     NOT NULL,
+	deleted_at     TIMESTAMPTZ,
+	date_of_change TIMESTAMPTZ NOT NULL,
+	created_at     TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE INDEX product_events_created_at ON product_events (created_at);
+
+CREATE TABLE schedules (
+	id          CHAR(24)    PRIMARY KEY,
+	source      TEXT        NOT NULL,
+	url         TEXT        NOT NULL,
+	cron        TEXT        NOT NULL,
+	delimiter   INTEGER     NOT NULL,
+	skip_header BOOLEAN     NOT NULL,
+	paused      BOOLEAN     NOT NULL DEFAULT false,
+	next_run    TIMESTAMPTZ NOT NULL,
+	created_at  TIMESTAMPTZ NOT NULL,
+	last_run    TIMESTAMPTZ,
+	last_status TEXT        NOT NULL DEFAULT '',
+	last_error  TEXT        NOT NULL DEFAULT ''
+);
+CREATE INDEX schedules_next_run ON schedules (next_run) WHERE NOT paused;
+
+CREATE TABLE leases (
+	name       TEXT        PRIMARY KEY,
+	holder     TEXT        NOT NULL,
+	expires_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE TABLE alert_rules (
+	id                 CHAR(24)         PRIMARY KEY,
+	name               TEXT             NOT NULL,
+	source             TEXT             NOT NULL DEFAULT '',
+	product_id         BIGINT           NOT NULL DEFAULT 0,
+	min_change_percent DOUBLE PRECISION NOT NULL DEFAULT 0,
+	direction          TEXT             NOT NULL DEFAULT '',
+	webhook_url        TEXT             NOT NULL,
+	secret             TEXT             NOT NULL,
+	created_at         TIMESTAMPTZ      NOT NULL
+);
+
+CREATE TABLE alert_dead_letters (
+	id          CHAR(24)    PRIMARY KEY,
+	rule_id     CHAR(24)    NOT NULL,
+	webhook_url TEXT        NOT NULL,
+	payload     TEXT        NOT NULL,
+	attempts    INTEGER     NOT NULL,
+	last_error  TEXT        NOT NULL,
+	failed_at   TIMESTAMPTZ NOT NULL
+);
+CREATE INDEX alert_dead_letters_failed_at ON alert_dead_letters (failed_at DESC);
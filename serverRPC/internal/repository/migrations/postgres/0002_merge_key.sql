@@ -0,0 +1,5 @@
+-- Ключ объединения источников: domain.MergeKey от name, пишется вместе с name.
+-- lower() базы зависит от её локали, поэтому строки, добавленные до этой миграции, заполняет Migrate.
+ALTER TABLE products ADD COLUMN merge_key TEXT;
+DROP INDEX products_merge_key;
+CREATE INDEX products_merge_key ON products (merge_key);
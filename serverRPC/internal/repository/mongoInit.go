@@ -2,20 +2,27 @@ package repository
 
 import (
 	"context"
+	"errors"
 	"gRPC-server/internal/domain"
 	"gRPC-server/pkg/logger"
-	"log"
+	"sync"
 	"time"
 
 	"github.com/spf13/viper"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
 )
 
 type MongoBackend struct {
 	db     *mongo.Database
 	logger *logger.Logger
+
+	topologyMu sync.Mutex
+	replicated *bool
 }
 
 func MongoInit(db *mongo.Database, logger *logger.Logger) *MongoBackend {
@@ -25,106 +32,258 @@ func MongoInit(db *mongo.Database, logger *logger.Logger) *MongoBackend {
 	}
 }
 
-func (m *MongoBackend) Insert(ctx context.Context, product []domain.Product) error {
-	client := m.db.Client()
-	session, err := client.StartSession()
+// WithTransaction runs fn as one unit of work: every write made through the ctx passed to fn
+// commits or rolls back together. Standalone servers can't run transactions, so there fn runs
+// without one.
+func (m *MongoBackend) WithTransaction(ctx context.Context, fn func(ctx context.Context) error) (err error) {
+	ctx, span := otel.Tracer("gRPC-server/internal/repository").Start(ctx, "mongo.transaction")
+	defer func() {
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}()
+
+	if !m.isReplicated(ctx) {
+		return fn(ctx)
+	}
+
+	session, err := m.db.Client().StartSession()
 	if err != nil {
-		log.Fatalf("Ошибка создания сессии: %v", err)
+		m.logger.FromContext(ctx).Errorf("Can't start session: %s", err)
+		return err
 	}
 	defer session.EndSession(ctx)
 
-	transactionFunc := func(sessionCtx mongo.SessionContext) (interface{}, error) {
-		productsInterface := make([]interface{}, len(product))
-		for i, v := range product {
-			productsInterface[i] = v
-		}
-		if len(productsInterface) == 0 {
-			//m.logger.Error("No products to insert")
-			return nil, domain.ErrNoProducts
+	_, err = session.WithTransaction(ctx, func(sessionCtx mongo.SessionContext) (interface{}, error) {
+		return nil, fn(sessionCtx)
+	})
+	if err != nil {
+		if isTransactionNotSupported(err) {
+			m.logger.FromContext(ctx).Warnf("Transactions are not supported by deployment, running without transaction: %s", err)
+			m.setReplicated(false)
+			return fn(ctx)
 		}
-		_, err := m.db.Collection(viper.GetString("mongo.collection")).InsertMany(ctx, productsInterface)
-		if err != nil {
-			m.logger.Errorf("Can't Insert in collection: %s", err)
-			return nil, err
+		m.logger.FromContext(ctx).Errorf("Transaction failed: %s", err)
+		return err
+	}
+
+	return nil
+}
+
+// isReplicated asks the server once whether it is a replica set member or mongos, which
+// transactions and change streams both need. If the check itself fails the answer isn't cached.
+func (m *MongoBackend) isReplicated(ctx context.Context) bool {
+	m.topologyMu.Lock()
+	defer m.topologyMu.Unlock()
+
+	if m.replicated != nil {
+		return *m.replicated
+	}
+
+	var hello struct {
+		SetName string `bson:"setName"`
+		Msg     string `bson:"msg"`
+	}
+	if err := m.db.RunCommand(ctx, bson.D{{Key: "hello", Value: 1}}).Decode(&hello); err != nil {
+		m.logger.FromContext(ctx).Warnf("Can't detect deployment topology: %s", err)
+		return true
+	}
+
+	replicated := hello.SetName != "" || hello.Msg == "isdbgrid"
+	m.replicated = &replicated
+	return replicated
+}
+
+func (m *MongoBackend) setReplicated(replicated bool) {
+	m.topologyMu.Lock()
+	defer m.topologyMu.Unlock()
+	m.replicated = &replicated
+}
+
+func isTransactionNotSupported(err error) bool {
+	var cmdErr mongo.CommandError
+	if errors.As(err, &cmdErr) {
+		// IllegalOperation: "Transaction numbers are only allowed on a replica set member or mongos"
+		return cmdErr.Code == 20
+	}
+	return false
+}
+
+// Insert writes products through ctx, so inside WithTransaction it joins the running transaction.
+func (m *MongoBackend) Insert(ctx context.Context, product []domain.Product) error {
+	if len(product) == 0 {
+		return domain.ErrNoProducts
+	}
+
+	now := time.Now()
+	productsInterface := make([]interface{}, len(product))
+	for i, v := range product {
+		if v.DateOfChange.IsZero() {
+			v.DateOfChange = now
 		}
-		return nil, nil
+		productsInterface[i] = mongoProduct{Product: v, MergeKey: domain.MergeKey(v.Name)}
 	}
-	_, err = session.WithTransaction(ctx, transactionFunc)
+
+	_, err := m.db.Collection(viper.GetString("mongo.collection")).InsertMany(ctx, productsInterface)
 	if err != nil {
-		m.logger.Errorf("Транзакция не удалась: %s", err)
+		m.logger.FromContext(ctx).Errorf("Can't Insert in collection: %s", err)
 		return err
 	}
 
 	return nil
 }
 
+// List returns the products of sort.Source, or the merged catalog when no source is given.
 func (m *MongoBackend) List(ctx context.Context, sort domain.SortParams) ([]domain.Product, error) {
 	var products []domain.Product
-	opts := options.Find()
-	sortOpts := bson.D{{Key: sort.SortField, Value: sort.SortAsc}}
-
-	opts.SetSort(sortOpts)
-	opts.SetSkip(int64(sort.PagingOffset))
-	opts.SetLimit(int64(sort.PagingLimit))
-
-	cursor, err := m.db.Collection(viper.GetString("mongo.collection")).Find(ctx, bson.D{}, opts)
+	err := m.Export(ctx, sort, func(product domain.Product) error {
+		products = append(products, product)
+		return nil
+	})
 	if err != nil {
 		return nil, err
 	}
-	defer cursor.Close(ctx)
-
-	for cursor.Next(ctx) {
-		var product domain.Product
-		if err := cursor.Decode(&product); err != nil {
-			m.logger.Errorf("Error decoding product: %s", err)
-			return nil, err
-		}
-		products = append(products, product)
-	}
 	return products, nil
 }
 
 func (m *MongoBackend) GetByName(ctx context.Context, product domain.Product) (domain.Product, error) {
 	var prod domain.Product
-	filter := bson.D{{Key: "id", Value: product.Id}}
+	filter := productFilter(product)
 
 	result := m.db.Collection(viper.GetString("mongo.collection")).FindOne(ctx, filter)
 	if result.Err() == mongo.ErrNoDocuments {
-		m.logger.Errorf("GetByName err no documents: %s", result.Err())
-		return domain.Product{}, result.Err()
+		// a miss is a new row of an import or a plain 404 of GetProduct, not a failure
+		m.logger.FromContext(ctx).Debugf("GetByName no product %s/%d", product.Source, product.Id)
+		return domain.Product{}, domain.ErrProductNotFound
 	}
 
 	err := result.Decode(&prod)
 	if err != nil {
-		m.logger.Errorf("GetByName decode error: %s", err)
+		m.logger.FromContext(ctx).Errorf("GetByName decode error: %s", err)
 		return prod, err
 	}
 
 	return prod, nil
 }
 
+// GetByIds looks all ids of the source up with a single $in query, the order of the result is not defined.
+func (m *MongoBackend) GetByIds(ctx context.Context, source string, ids []int) ([]domain.Product, error) {
+	var products []domain.Product
+	filter := bson.D{{Key: "source", Value: source}, {Key: "id", Value: bson.D{{Key: "$in", Value: ids}}}}
+
+	cursor, err := m.db.Collection(viper.GetString("mongo.collection")).Find(ctx, filter)
+	if err != nil {
+		m.logger.FromContext(ctx).Errorf("GetByIds find error: %s", err)
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	if err := cursor.All(ctx, &products); err != nil {
+		m.logger.FromContext(ctx).Errorf("GetByIds decode error: %s", err)
+		return nil, err
+	}
+	return products, nil
+}
+
+// UpdateProduct sets the new name and price and appends the previous values to the history collection.
 func (m *MongoBackend) UpdateProduct(ctx context.Context, product domain.Product) error {
-	//var prod domain.Product
-	filter := bson.D{{Key: "id", Value: product.Id}}
-	update := bson.D{{Key: "$set", Value: bson.D{{Key: "price", Value: product.Price}}},
-		{Key: "$set", Value: bson.D{{Key: "name", Value: product.Name}}},
+	now := time.Now()
+	filter := productFilter(product)
+	update := bson.D{
+		{Key: "$set", Value: bson.D{
+			{Key: "price", Value: product.Price},
+			{Key: "name", Value: product.Name},
+			{Key: "merge_key", Value: domain.MergeKey(product.Name)},
+			{Key: "date_of_change", Value: now},
+		}},
 		{Key: "$inc", Value: bson.D{{Key: "changes_count", Value: 1}}},
-		{Key: "$set", Value: bson.D{{Key: "date_of_change", Value: time.Now()}}}}
-	_, err := m.db.Collection(viper.GetString("mongo.collection")).UpdateOne(ctx, filter, update)
+	}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.Before)
+
+	var before domain.Product
+	err := m.db.Collection(viper.GetString("mongo.collection")).FindOneAndUpdate(ctx, filter, update, opts).Decode(&before)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return domain.ErrProductNotFound
+	}
 	if err != nil {
-		m.logger.Errorf("Can't update product: %s", err)
+		m.logger.FromContext(ctx).Errorf("Can't update product: %s", err)
+		return err
+	}
+
+	change := domain.PriceChange{
+		Source:    product.Source,
+		Id:        product.Id,
+		OldName:   before.Name,
+		NewName:   product.Name,
+		OldPrice:  before.Price,
+		NewPrice:  product.Price,
+		ChangedAt: now,
+	}
+	if _, err := m.db.Collection(viper.GetString("mongo.history")).InsertOne(ctx, change); err != nil {
+		m.logger.FromContext(ctx).Errorf("Can't write product history: %s", err)
 		return err
 	}
 	return nil
 }
 
-// доделать удаление
+// DeleteProduct only marks the product with deleted_at, PurgeDeleted removes it for good.
 func (m *MongoBackend) DeleteProduct(ctx context.Context, product domain.Product) error {
-	filter := bson.D{{Key: "name", Value: product.Name}}
-	_, err := m.db.Collection(viper.GetString("mongo.collection")).DeleteOne(ctx, filter)
+	now := time.Now()
+	filter := append(productFilter(product), bson.E{Key: "deleted_at", Value: nil})
+	update := bson.D{{Key: "$set", Value: bson.D{
+		{Key: "deleted_at", Value: now},
+		{Key: "date_of_change", Value: now},
+	}}}
+
+	result, err := m.db.Collection(viper.GetString("mongo.collection")).UpdateOne(ctx, filter, update)
 	if err != nil {
-		m.logger.Errorf("Can't delete product: %s", err)
+		m.logger.FromContext(ctx).Errorf("Can't delete product: %s", err)
 		return err
 	}
+	if result.MatchedCount == 0 {
+		return domain.ErrProductNotFound
+	}
 	return nil
 }
+
+// RestoreProduct sets restored_at, so Watch without a change stream reports the restore as an update.
+func (m *MongoBackend) RestoreProduct(ctx context.Context, product domain.Product) error {
+	now := time.Now()
+	filter := append(productFilter(product), bson.E{Key: "deleted_at", Value: bson.D{{Key: "$ne", Value: nil}}})
+	update := bson.D{
+		{Key: "$unset", Value: bson.D{{Key: "deleted_at", Value: ""}}},
+		{Key: "$set", Value: bson.D{
+			{Key: "restored_at", Value: now},
+			{Key: "date_of_change", Value: now},
+		}},
+	}
+
+	result, err := m.db.Collection(viper.GetString("mongo.collection")).UpdateOne(ctx, filter, update)
+	if err != nil {
+		m.logger.FromContext(ctx).Errorf("Can't restore product: %s", err)
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return domain.ErrProductNotFound
+	}
+	return nil
+}
+
+// PurgeDeleted removes products that were soft-deleted before olderThan and returns how many were removed.
+func (m *MongoBackend) PurgeDeleted(ctx context.Context, olderThan time.Time) (int64, error) {
+	filter := bson.D{{Key: "deleted_at", Value: bson.D{{Key: "$lt", Value: olderThan}}}}
+
+	result, err := m.db.Collection(viper.GetString("mongo.collection")).DeleteMany(ctx, filter)
+	if err != nil {
+		m.logger.FromContext(ctx).Errorf("Can't purge deleted products: %s", err)
+		return 0, err
+	}
+	return result.DeletedCount, nil
+}
+
+// Ping checks that the primary is reachable, the health service reports it.
+func (m *MongoBackend) Ping(ctx context.Context) error {
+	return m.db.Client().Ping(ctx, readpref.Primary())
+}
@@ -0,0 +1,45 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"gRPC-server/pkg/logger"
+
+	"github.com/spf13/viper"
+)
+
+// Open returns the backend storage.backend names: mongo, the default, postgres or memory.
+// For mongo it also moves products without a source under defaultSource, postgres gets
+// its migrations applied.
+func Open(ctx context.Context, defaultSource string, logger *logger.Logger) (Sorting, error) {
+	switch backend := viper.GetString("storage.backend"); backend {
+	case "", "mongo":
+		db, err := NewMongoConnect()
+		if err != nil {
+			return nil, err
+		}
+		mongo := MongoInit(db, logger)
+		if err := mongo.MigrateSources(ctx, defaultSource); err != nil {
+			return nil, err
+		}
+		if err := mongo.MigrateWatch(ctx); err != nil {
+			return nil, err
+		}
+		return mongo, nil
+	case "postgres":
+		db, err := NewPostgresConnect()
+		if err != nil {
+			return nil, err
+		}
+		postgres := PostgresInit(db, logger)
+		if err := postgres.Migrate(ctx); err != nil {
+			return nil, err
+		}
+		return postgres, nil
+	case "memory":
+		logger.Warn("Storage backend is memory, the catalog is lost on restart")
+		return NewMemoryBackend(logger), nil
+	default:
+		return nil, fmt.Errorf("unknown storage.backend %q", backend)
+	}
+}
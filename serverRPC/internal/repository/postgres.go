@@ -0,0 +1,438 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"gRPC-server/internal/domain"
+	"gRPC-server/pkg/logger"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+)
+
+// pgProductColumns is the order scanProduct reads a product row in.
+const pgProductColumns = "source, id, name, price, deleted_at, date_of_change"
+
+// pgEventsLock is the advisory lock every product write takes before it appends to product_events.
+// Writers queue on it, so seq values commit in order and Watch never skips past a seq whose
+// transaction is still running.
+const pgEventsLock = 4096001
+
+// pgInsertBatch keeps an Insert statement well under the 65535 parameters postgres accepts.
+const pgInsertBatch = 1000
+
+// PostgresBackend keeps the catalog in PostgreSQL, Migrate creates its tables.
+type PostgresBackend struct {
+	db     *sql.DB
+	logger *logger.Logger
+}
+
+func PostgresInit(db *sql.DB, logger *logger.Logger) *PostgresBackend {
+	return &PostgresBackend{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// pgQuerier is what *sql.DB and *sql.Tx share, statements run on the one ctx carries.
+type pgQuerier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+type pgTxKey struct{}
+
+type pgTx struct {
+	*sql.Tx
+	backend *PostgresBackend
+}
+
+func (p *PostgresBackend) conn(ctx context.Context) pgQuerier {
+	if tx, ok := ctx.Value(pgTxKey{}).(*pgTx); ok && tx.backend == p {
+		return tx
+	}
+	return p.db
+}
+
+// WithTransaction runs fn as one unit of work: every write made through the ctx passed to fn
+// commits or rolls back together. A call inside a transaction joins it.
+func (p *PostgresBackend) WithTransaction(ctx context.Context, fn func(ctx context.Context) error) (err error) {
+	if tx, ok := ctx.Value(pgTxKey{}).(*pgTx); ok && tx.backend == p {
+		return fn(ctx)
+	}
+
+	ctx, span := otel.Tracer("gRPC-server/internal/repository").Start(ctx, "postgres.transaction")
+	defer func() {
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}()
+
+	tx, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		p.logger.FromContext(ctx).Errorf("Transaction not started: %s", err)
+		return err
+	}
+
+	if err = fn(context.WithValue(ctx, pgTxKey{}, &pgTx{Tx: tx, backend: p})); err != nil {
+		if rollbackErr := tx.Rollback(); rollbackErr != nil {
+			p.logger.FromContext(ctx).Errorf("Error rolling back transaction: %s", rollbackErr)
+		}
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		p.logger.FromContext(ctx).Errorf("Error committing transaction: %s", err)
+		return err
+	}
+	return nil
+}
+
+// writeProducts runs fn in a transaction that holds pgEventsLock.
+func (p *PostgresBackend) writeProducts(ctx context.Context, fn func(ctx context.Context, q pgQuerier) error) error {
+	return p.WithTransaction(ctx, func(ctx context.Context) error {
+		q := p.conn(ctx)
+		if _, err := q.ExecContext(ctx, `SELECT pg_advisory_xact_lock($1)`, pgEventsLock); err != nil {
+			p.logger.FromContext(ctx).Errorf("Can't take events lock: %s", err)
+			return err
+		}
+		return fn(ctx, q)
+	})
+}
+
+// emitting wraps a statement that changes products, so the same statement appends an event of
+// the type in parameter eventParam for every row it changed.
+func emitting(statement string, eventParam int) string {
+	return fmt.Sprintf(`WITH changed AS (%s RETURNING %s)
+INSERT INTO product_events (type, %s) SELECT $%d::text, %s FROM changed`,
+		statement, pgProductColumns, pgProductColumns, eventParam, pgProductColumns)
+}
+
+type pgScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanProduct(row pgScanner, extra ...any) (domain.Product, error) {
+	var product domain.Product
+	var price string
+	var deletedAt sql.NullTime
+
+	dest := append([]any{&product.Source, &product.Id, &product.Name, &price, &deletedAt, &product.DateOfChange}, extra...)
+	if err := row.Scan(dest...); err != nil {
+		return domain.Product{}, err
+	}
+
+	var err error
+	if product.Price, err = primitive.ParseDecimal128(price); err != nil {
+		return domain.Product{}, err
+	}
+	if deletedAt.Valid {
+		product.DeletedAt = &deletedAt.Time
+	}
+	return product, nil
+}
+
+// nullTime stores a nil *time.Time as NULL.
+func nullTime(t *time.Time) sql.NullTime {
+	if t == nil {
+		return sql.NullTime{}
+	}
+	return sql.NullTime{Time: *t, Valid: true}
+}
+
+// Insert adds all products or, when one of them already exists, none.
+func (p *PostgresBackend) Insert(ctx context.Context, product []domain.Product) error {
+	if len(product) == 0 {
+		return domain.ErrNoProducts
+	}
+
+	now := time.Now()
+	return p.writeProducts(ctx, func(ctx context.Context, q pgQuerier) error {
+		for batch := range slices.Chunk(product, pgInsertBatch) {
+			args := []any{domain.EventInsert}
+			rows := make([]string, len(batch))
+			for i, v := range batch {
+				if v.DateOfChange.IsZero() {
+					v.DateOfChange = now
+				}
+				n := len(args)
+				rows[i] = fmt.Sprintf("($%d, $%d, $%d, $%d, $%d, $%d, $%d)", n+1, n+2, n+3, n+4, n+5, n+6, n+7)
+				args = append(args, v.Source, v.Id, v.Name, v.Price.String(), nullTime(v.DeletedAt), v.DateOfChange, domain.MergeKey(v.Name))
+			}
+
+			statement := `INSERT INTO products (` + pgProductColumns + `, merge_key) VALUES ` + strings.Join(rows, ", ")
+			if _, err := q.ExecContext(ctx, emitting(statement, 1), args...); err != nil {
+				p.logger.FromContext(ctx).Errorf("Can't Insert in products: %s", err)
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// List returns the products of sort.Source, or the merged catalog when no source is given.
+func (p *PostgresBackend) List(ctx context.Context, sort domain.SortParams) ([]domain.Product, error) {
+	var products []domain.Product
+	err := p.Export(ctx, sort, func(product domain.Product) error {
+		products = append(products, product)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return products, nil
+}
+
+// Export reads the products List would return row by row, send gets them one by one in sort
+// order and an error from it stops the export.
+func (p *PostgresBackend) Export(ctx context.Context, sort domain.SortParams, send func(domain.Product) error) error {
+	query, args := listQuery(sort)
+	rows, err := p.conn(ctx).QueryContext(ctx, query, args...)
+	if err != nil {
+		p.logger.FromContext(ctx).Errorf("Can't list products: %s", err)
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		product, err := scanProduct(rows)
+		if err != nil {
+			p.logger.FromContext(ctx).Errorf("Error decoding product: %s", err)
+			return err
+		}
+		if err := send(product); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// listQuery sorts and pages like MongoBackend.List. Names compare byte by byte as in Mongo,
+// and equal sort values are ordered by (source, id) so pages are stable between calls.
+func listQuery(sort domain.SortParams) (string, []any) {
+	var args []any
+	arg := func(value any) string {
+		args = append(args, value)
+		return "$" + strconv.Itoa(len(args))
+	}
+
+	var where []string
+	if !sort.IncludeDeleted {
+		where = append(where, "deleted_at IS NULL")
+	}
+
+	var from string
+	if sort.Source != "" {
+		where = append(where, "source = "+arg(sort.Source))
+		from = "products WHERE " + strings.Join(where, " AND ")
+	} else {
+		from = "(" + mergedQuery(sort.Merge, where, arg) + ") merged"
+	}
+
+	column := "id"
+	switch sort.SortField {
+	case "name":
+		column = `name COLLATE "C"`
+	case "price":
+		column = "price"
+	}
+	direction := "ASC"
+	if sort.SortAsc < 0 {
+		direction = "DESC"
+	}
+
+	var limit any
+	if sort.PagingLimit > 0 {
+		limit = sort.PagingLimit
+	}
+	query := fmt.Sprintf("SELECT %s FROM %s ORDER BY %s %s, source, id OFFSET %s LIMIT %s",
+		pgProductColumns, from, column, direction, arg(sort.PagingOffset), arg(limit))
+	return query, args
+}
+
+// mergedQuery keeps one copy of every product, picked by merge the way mergedPipeline does.
+func mergedQuery(merge domain.MergeRule, where []string, arg func(any) string) string {
+	// priorities are looked up at query time, a changed registry applies without re-importing.
+	// Without any every source has priority 0, which orders nothing: a bare 0 in ORDER BY
+	// would even be read as a column position.
+	var priority []string
+	if len(merge.Priorities) > 0 {
+		var branches strings.Builder
+		for _, source := range slices.Sorted(maps.Keys(merge.Priorities)) {
+			fmt.Fprintf(&branches, " WHEN %s THEN %d", arg(source), merge.Priorities[source])
+		}
+		priority = []string{"CASE source" + branches.String() + " ELSE 0 END DESC"}
+	}
+
+	var winner []string
+	switch merge.Strategy {
+	case domain.MergeLowestPrice:
+		winner = append([]string{"price ASC"}, priority...)
+	case domain.MergeNewest:
+		winner = append([]string{"date_of_change DESC"}, priority...)
+	default:
+		winner = append(priority, "date_of_change DESC")
+	}
+
+	// ties are broken by source and id like in mergedPipeline
+	window := "PARTITION BY merge_key ORDER BY " + strings.Join(winner, ", ") + ", source, id"
+	query := "SELECT " + pgProductColumns + ", row_number() OVER (" + window + ") AS merge_rank, " +
+		"min(source) OVER (PARTITION BY merge_key) = max(source) OVER (PARTITION BY merge_key) AS single_source FROM products"
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	// only copies from different sources merge, products of one source that share a key all stay
+	return "SELECT " + pgProductColumns + " FROM (" + query + ") ranked WHERE merge_rank = 1 OR single_source"
+}
+
+// GetByName finds the product by source and id, a missing one is domain.ErrProductNotFound.
+func (p *PostgresBackend) GetByName(ctx context.Context, product domain.Product) (domain.Product, error) {
+	row := p.conn(ctx).QueryRowContext(ctx,
+		`SELECT `+pgProductColumns+` FROM products WHERE source = $1 AND id = $2`, product.Source, product.Id)
+
+	prod, err := scanProduct(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.Product{}, domain.ErrProductNotFound
+	}
+	if err != nil {
+		p.logger.FromContext(ctx).Errorf("GetByName error: %s", err)
+		return domain.Product{}, err
+	}
+	return prod, nil
+}
+
+// GetByIds looks all ids of the source up with a single query, the order of the result is not defined.
+func (p *PostgresBackend) GetByIds(ctx context.Context, source string, ids []int) ([]domain.Product, error) {
+	rows, err := p.conn(ctx).QueryContext(ctx,
+		`SELECT `+pgProductColumns+` FROM products WHERE source = $1 AND id = ANY($2)`, source, pq.Array(pgInts(ids)))
+	if err != nil {
+		p.logger.FromContext(ctx).Errorf("GetByIds find error: %s", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var products []domain.Product
+	for rows.Next() {
+		product, err := scanProduct(rows)
+		if err != nil {
+			p.logger.FromContext(ctx).Errorf("GetByIds decode error: %s", err)
+			return nil, err
+		}
+		products = append(products, product)
+	}
+	return products, rows.Err()
+}
+
+// UpdateProduct sets the new name and price and appends the previous values to product_history.
+func (p *PostgresBackend) UpdateProduct(ctx context.Context, product domain.Product) error {
+	now := time.Now()
+	return p.writeProducts(ctx, func(ctx context.Context, q pgQuerier) error {
+		result, err := q.ExecContext(ctx, `
+WITH before AS (
+	SELECT source, id, name, price FROM products WHERE source = $1 AND id = $2 FOR UPDATE
+), changed AS (
+	UPDATE products SET name = $3, merge_key = $7, price = $4, date_of_change = $5, changes_count = changes_count + 1
+	FROM before WHERE products.source = before.source AND products.id = before.id
+	RETURNING products.source, products.id, products.name, products.price, products.deleted_at,
+		products.date_of_change, before.name AS old_name, before.price AS old_price
+), history AS (
+	INSERT INTO product_history (source, id, old_name, new_name, old_price, new_price, changed_at)
+	SELECT source, id, old_name, name, old_price, price, $5 FROM changed
+)
+INSERT INTO product_events (type, `+pgProductColumns+`) SELECT $6::text, `+pgProductColumns+` FROM changed`,
+			product.Source, product.Id, product.Name, product.Price.String(), now, domain.EventUpdate, domain.MergeKey(product.Name))
+		if err != nil {
+			p.logger.FromContext(ctx).Errorf("Can't update product: %s", err)
+			return err
+		}
+		if affected, err := result.RowsAffected(); err != nil {
+			return err
+		} else if affected == 0 {
+			return domain.ErrProductNotFound
+		}
+		return nil
+	})
+}
+
+// DeleteProduct only marks the product with deleted_at, PurgeDeleted removes it for good.
+func (p *PostgresBackend) DeleteProduct(ctx context.Context, product domain.Product) error {
+	statement := `UPDATE products SET deleted_at = $3, date_of_change = $3
+		WHERE source = $1 AND id = $2 AND deleted_at IS NULL`
+	return p.changeOne(ctx, emitting(statement, 4), product.Source, product.Id, time.Now(), domain.EventDelete)
+}
+
+func (p *PostgresBackend) RestoreProduct(ctx context.Context, product domain.Product) error {
+	statement := `UPDATE products SET deleted_at = NULL, date_of_change = $3
+		WHERE source = $1 AND id = $2 AND deleted_at IS NOT NULL`
+	return p.changeOne(ctx, emitting(statement, 4), product.Source, product.Id, time.Now(), domain.EventUpdate)
+}
+
+// changeOne runs a statement meant to change one product and reports domain.ErrProductNotFound
+// when it changed none.
+func (p *PostgresBackend) changeOne(ctx context.Context, statement string, args ...any) error {
+	return p.writeProducts(ctx, func(ctx context.Context, q pgQuerier) error {
+		result, err := q.ExecContext(ctx, statement, args...)
+		if err != nil {
+			p.logger.FromContext(ctx).Errorf("Can't change product: %s", err)
+			return err
+		}
+		if affected, err := result.RowsAffected(); err != nil {
+			return err
+		} else if affected == 0 {
+			return domain.ErrProductNotFound
+		}
+		return nil
+	})
+}
+
+// PurgeDeleted removes products that were soft-deleted before olderThan and returns how many were
+// removed. The change log Watch reads is trimmed separately, to watch.events_retention.
+func (p *PostgresBackend) PurgeDeleted(ctx context.Context, olderThan time.Time) (int64, error) {
+	var purged int64
+	err := p.writeProducts(ctx, func(ctx context.Context, q pgQuerier) error {
+		result, err := q.ExecContext(ctx, emitting(`DELETE FROM products WHERE deleted_at < $1`, 2), olderThan, domain.EventDelete)
+		if err != nil {
+			p.logger.FromContext(ctx).Errorf("Can't purge deleted products: %s", err)
+			return err
+		}
+		if purged, err = result.RowsAffected(); err != nil {
+			return err
+		}
+
+		if _, err := q.ExecContext(ctx, `DELETE FROM product_events WHERE created_at < $1`, eventsCutoff()); err != nil {
+			p.logger.FromContext(ctx).Errorf("Can't trim product events: %s", err)
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return purged, nil
+}
+
+// Ping checks that the database is reachable, the health service reports it.
+func (p *PostgresBackend) Ping(ctx context.Context) error {
+	return p.db.PingContext(ctx)
+}
+
+// pgInts widens ids to the type pq.Array sends as bigint[].
+func pgInts(ids []int) []int64 {
+	values := make([]int64, len(ids))
+	for i, id := range ids {
+		values[i] = int64(id)
+	}
+	return values
+}
@@ -0,0 +1,70 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"gRPC-server/pkg/logger"
+	"time"
+
+	"github.com/joho/godotenv"
+	"github.com/kelseyhightower/envconfig"
+	_ "github.com/lib/pq"
+)
+
+// Postgres is read from PG_* variables, the same fields web-app reads from DB_*.
+type Postgres struct {
+	Host     string
+	Port     string `default:"5432"`
+	Username string
+	Password string
+	Dbname   string
+	Sslmode  string `default:"disable"`
+}
+
+func (p Postgres) dsn() string {
+	return "host=" + p.Host + " port=" + p.Port + " user=" + p.Username +
+		" password=" + p.Password + " dbname=" + p.Dbname + " sslmode=" + p.Sslmode
+}
+
+// NewPostgresConnect opens the database described by .env and pings it, retrying like NewMongoConnect.
+func NewPostgresConnect() (*sql.DB, error) {
+	logger := logger.GetLogger()
+	cfg := ConfigInicialize()
+
+	// in a container the variables may come from the environment itself
+	if err := godotenv.Load(".env"); err != nil {
+		logger.Warn(fmt.Sprintf("godotenv can't load env: %s", err))
+	}
+
+	var pg Postgres
+	if err := envconfig.Process("pg", &pg); err != nil {
+		logger.Error(fmt.Sprintf("envconfig cant parse to struct: %s", err))
+		return nil, err
+	}
+
+	db, err := sql.Open("postgres", pg.dsn())
+	if err != nil {
+		logger.Error(fmt.Sprintf("Open connection to postgres failed: %s", err))
+		return nil, err
+	}
+
+	for i := 0; ; i++ {
+		ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+		err = db.PingContext(ctx)
+		cancel()
+		if err == nil {
+			return db, nil
+		}
+		if i == cfg.maxRetries {
+			break
+		}
+
+		logger.Error(fmt.Sprintf("ping postgres failed: %s", err))
+		time.Sleep(cfg.retryDelation)
+	}
+
+	logger.Error(fmt.Sprintf("Retry connect to postgres failed: %s", err))
+	db.Close()
+	return nil, err
+}
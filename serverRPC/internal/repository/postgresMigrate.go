@@ -0,0 +1,109 @@
+package repository
+
+import (
+	"context"
+	"embed"
+	"gRPC-server/internal/domain"
+	"io/fs"
+	"path"
+	"slices"
+
+	"github.com/lib/pq"
+)
+
+//go:embed migrations/postgres/*.sql
+var pgMigrations embed.FS
+
+// pgMigrationsLock makes replicas that start together apply the migrations one after another.
+const pgMigrationsLock = 4096002
+
+// Migrate applies the files of migrations/postgres that schema_migrations doesn't list yet, in
+// name order and each in its own transaction. New migrations go into new files, an applied file
+// is never run again. It is safe to run on every start.
+func (p *PostgresBackend) Migrate(ctx context.Context) error {
+	_, err := p.db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version    TEXT        PRIMARY KEY,
+		applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
+	)`)
+	if err != nil {
+		p.logger.FromContext(ctx).Errorf("Can't create schema_migrations: %s", err)
+		return err
+	}
+
+	files, err := fs.Glob(pgMigrations, "migrations/postgres/*.sql")
+	if err != nil {
+		return err
+	}
+	slices.Sort(files)
+
+	for _, file := range files {
+		version := path.Base(file)
+		err := p.WithTransaction(ctx, func(ctx context.Context) error {
+			q := p.conn(ctx)
+			if _, err := q.ExecContext(ctx, `SELECT pg_advisory_xact_lock($1)`, pgMigrationsLock); err != nil {
+				return err
+			}
+
+			var applied bool
+			err := q.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM schema_migrations WHERE version = $1)`, version).Scan(&applied)
+			if err != nil || applied {
+				return err
+			}
+
+			script, err := pgMigrations.ReadFile(file)
+			if err != nil {
+				return err
+			}
+			if _, err := q.ExecContext(ctx, string(script)); err != nil {
+				return err
+			}
+			if _, err := q.ExecContext(ctx, `INSERT INTO schema_migrations (version) VALUES ($1)`, version); err != nil {
+				return err
+			}
+
+			p.logger.FromContext(ctx).Infof("Applied postgres migration %s", version)
+			return nil
+		})
+		if err != nil {
+			p.logger.FromContext(ctx).Errorf("Can't apply postgres migration %s: %s", version, err)
+			return err
+		}
+	}
+	return p.backfillMergeKeys(ctx)
+}
+
+// backfillMergeKeys fills merge_key of the rows written before 0002_merge_key.sql, the key is
+// computed in Go so it is the same as the one the writes store.
+func (p *PostgresBackend) backfillMergeKeys(ctx context.Context) error {
+	rows, err := p.db.QueryContext(ctx, `SELECT source, id, name FROM products WHERE merge_key IS NULL`)
+	if err != nil {
+		p.logger.FromContext(ctx).Errorf("Can't find products without merge_key: %s", err)
+		return err
+	}
+	defer rows.Close()
+
+	var sources, keys []string
+	var ids []int64
+	for rows.Next() {
+		var source, name string
+		var id int64
+		if err := rows.Scan(&source, &id, &name); err != nil {
+			return err
+		}
+		sources, ids, keys = append(sources, source), append(ids, id), append(keys, domain.MergeKey(name))
+	}
+	if err := rows.Err(); err != nil || len(keys) == 0 {
+		return err
+	}
+
+	_, err = p.db.ExecContext(ctx, `UPDATE products SET merge_key = backfill.merge_key
+	FROM unnest($1::text[], $2::bigint[], $3::text[]) AS backfill (source, id, merge_key)
+	WHERE products.source = backfill.source AND products.id = backfill.id AND products.merge_key IS NULL`,
+		pq.Array(sources), pq.Array(ids), pq.Array(keys))
+	if err != nil {
+		p.logger.FromContext(ctx).Errorf("Can't backfill merge_key: %s", err)
+		return err
+	}
+	p.logger.FromContext(ctx).Infof("Filled merge_key of %d products", len(keys))
+	return nil
+}
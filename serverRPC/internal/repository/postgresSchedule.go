@@ -0,0 +1,263 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"gRPC-server/internal/domain"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const pgScheduleColumns = "id, source, url, cron, delimiter, skip_header, paused, next_run, created_at, last_run, last_status, last_error"
+
+func scanSchedule(row pgScanner) (domain.Schedule, error) {
+	var schedule domain.Schedule
+	var id string
+	var lastRun sql.NullTime
+
+	err := row.Scan(&id, &schedule.Source, &schedule.Url, &schedule.Cron, &schedule.Format.Delimiter,
+		&schedule.Format.SkipHeader, &schedule.Paused, &schedule.NextRun, &schedule.CreatedAt,
+		&lastRun, &schedule.LastStatus, &schedule.LastError)
+	if err != nil {
+		return domain.Schedule{}, err
+	}
+	if schedule.Id, err = primitive.ObjectIDFromHex(id); err != nil {
+		return domain.Schedule{}, err
+	}
+	if lastRun.Valid {
+		schedule.LastRun = &lastRun.Time
+	}
+	return schedule, nil
+}
+
+func (p *PostgresBackend) querySchedules(ctx context.Context, query string, args ...any) ([]domain.Schedule, error) {
+	rows, err := p.conn(ctx).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var schedules []domain.Schedule
+	for rows.Next() {
+		schedule, err := scanSchedule(rows)
+		if err != nil {
+			return nil, err
+		}
+		schedules = append(schedules, schedule)
+	}
+	return schedules, rows.Err()
+}
+
+func (p *PostgresBackend) CreateSchedule(ctx context.Context, schedule domain.Schedule) (domain.Schedule, error) {
+	schedule.Id = primitive.NewObjectID()
+	_, err := p.conn(ctx).ExecContext(ctx, `INSERT INTO schedules (`+pgScheduleColumns+`)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)`,
+		schedule.Id.Hex(), schedule.Source, schedule.Url, schedule.Cron, schedule.Format.Delimiter,
+		schedule.Format.SkipHeader, schedule.Paused, schedule.NextRun, schedule.CreatedAt,
+		nullTime(schedule.LastRun), schedule.LastStatus, schedule.LastError)
+	if err != nil {
+		p.logger.FromContext(ctx).Errorf("Can't create schedule: %s", err)
+		return domain.Schedule{}, err
+	}
+	return schedule, nil
+}
+
+func (p *PostgresBackend) ListSchedules(ctx context.Context) ([]domain.Schedule, error) {
+	schedules, err := p.querySchedules(ctx, `SELECT `+pgScheduleColumns+` FROM schedules ORDER BY created_at, id`)
+	if err != nil {
+		p.logger.FromContext(ctx).Errorf("Can't list schedules: %s", err)
+		return nil, err
+	}
+	return schedules, nil
+}
+
+func (p *PostgresBackend) GetSchedule(ctx context.Context, id primitive.ObjectID) (domain.Schedule, error) {
+	row := p.conn(ctx).QueryRowContext(ctx, `SELECT `+pgScheduleColumns+` FROM schedules WHERE id = $1`, id.Hex())
+	schedule, err := scanSchedule(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.Schedule{}, domain.ErrScheduleNotFound
+	}
+	if err != nil {
+		p.logger.FromContext(ctx).Errorf("Can't get schedule: %s", err)
+		return domain.Schedule{}, err
+	}
+	return schedule, nil
+}
+
+// SetSchedulePaused pauses or resumes a schedule; nextRun is only stored when resuming.
+func (p *PostgresBackend) SetSchedulePaused(ctx context.Context, id primitive.ObjectID, paused bool, nextRun time.Time) (domain.Schedule, error) {
+	row := p.conn(ctx).QueryRowContext(ctx, `UPDATE schedules
+		SET paused = $2, next_run = CASE WHEN $2 THEN next_run ELSE $3 END
+		WHERE id = $1 RETURNING `+pgScheduleColumns, id.Hex(), paused, nextRun)
+	schedule, err := scanSchedule(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.Schedule{}, domain.ErrScheduleNotFound
+	}
+	if err != nil {
+		p.logger.FromContext(ctx).Errorf("Can't update schedule: %s", err)
+		return domain.Schedule{}, err
+	}
+	return schedule, nil
+}
+
+func (p *PostgresBackend) DeleteSchedule(ctx context.Context, id primitive.ObjectID) error {
+	result, err := p.conn(ctx).ExecContext(ctx, `DELETE FROM schedules WHERE id = $1`, id.Hex())
+	if err != nil {
+		p.logger.FromContext(ctx).Errorf("Can't delete schedule: %s", err)
+		return err
+	}
+	if affected, err := result.RowsAffected(); err != nil {
+		return err
+	} else if affected == 0 {
+		return domain.ErrScheduleNotFound
+	}
+	return nil
+}
+
+// DueSchedules returns active schedules whose next run is not after now.
+func (p *PostgresBackend) DueSchedules(ctx context.Context, now time.Time) ([]domain.Schedule, error) {
+	return p.querySchedules(ctx, `SELECT `+pgScheduleColumns+` FROM schedules
+		WHERE NOT paused AND next_run <= $1 ORDER BY next_run`, now)
+}
+
+// ClaimScheduleRun moves next_run from scheduled to next. Only one caller can move it,
+// so a run is never started twice even if two replicas briefly both think they lead.
+func (p *PostgresBackend) ClaimScheduleRun(ctx context.Context, id primitive.ObjectID, scheduled, next time.Time) (bool, error) {
+	result, err := p.conn(ctx).ExecContext(ctx,
+		`UPDATE schedules SET next_run = $3 WHERE id = $1 AND next_run = $2`, id.Hex(), scheduled, next)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	return affected == 1, err
+}
+
+func (p *PostgresBackend) FinishScheduleRun(ctx context.Context, id primitive.ObjectID, at time.Time, status, runErr string) error {
+	_, err := p.conn(ctx).ExecContext(ctx,
+		`UPDATE schedules SET last_run = $2, last_status = $3, last_error = $4 WHERE id = $1`,
+		id.Hex(), at, status, runErr)
+	return err
+}
+
+// AcquireLease takes or renews the lease name for holder until now+ttl. It fails without
+// an error when another holder has a lease that hasn't expired.
+func (p *PostgresBackend) AcquireLease(ctx context.Context, name, holder string, ttl time.Duration) (bool, error) {
+	now := time.Now()
+	// with the lease held by someone else the conflict update is skipped and no row is affected
+	result, err := p.conn(ctx).ExecContext(ctx, `INSERT INTO leases (name, holder, expires_at) VALUES ($1, $2, $3)
+		ON CONFLICT (name) DO UPDATE SET holder = excluded.holder, expires_at = excluded.expires_at
+		WHERE leases.holder = excluded.holder OR leases.expires_at < $4`, name, holder, now.Add(ttl), now)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	return affected == 1, err
+}
+
+func (p *PostgresBackend) ReleaseLease(ctx context.Context, name, holder string) error {
+	_, err := p.conn(ctx).ExecContext(ctx, `DELETE FROM leases WHERE name = $1 AND holder = $2`, name, holder)
+	return err
+}
+
+const pgAlertRuleColumns = "id, name, source, product_id, min_change_percent, direction, webhook_url, secret, created_at"
+
+func (p *PostgresBackend) CreateAlertRule(ctx context.Context, rule domain.AlertRule) (domain.AlertRule, error) {
+	rule.Id = primitive.NewObjectID()
+	_, err := p.conn(ctx).ExecContext(ctx, `INSERT INTO alert_rules (`+pgAlertRuleColumns+`)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`,
+		rule.Id.Hex(), rule.Name, rule.Source, rule.ProductId, rule.MinChangePercent, rule.Direction,
+		rule.WebhookUrl, rule.Secret, rule.CreatedAt)
+	if err != nil {
+		p.logger.FromContext(ctx).Errorf("Can't create alert rule: %s", err)
+		return domain.AlertRule{}, err
+	}
+	return rule, nil
+}
+
+func (p *PostgresBackend) ListAlertRules(ctx context.Context) ([]domain.AlertRule, error) {
+	rows, err := p.conn(ctx).QueryContext(ctx, `SELECT `+pgAlertRuleColumns+` FROM alert_rules ORDER BY created_at, id`)
+	if err != nil {
+		p.logger.FromContext(ctx).Errorf("Can't list alert rules: %s", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var rules []domain.AlertRule
+	for rows.Next() {
+		var rule domain.AlertRule
+		var id string
+		err := rows.Scan(&id, &rule.Name, &rule.Source, &rule.ProductId, &rule.MinChangePercent,
+			&rule.Direction, &rule.WebhookUrl, &rule.Secret, &rule.CreatedAt)
+		if err != nil {
+			p.logger.FromContext(ctx).Errorf("Can't decode alert rules: %s", err)
+			return nil, err
+		}
+		if rule.Id, err = primitive.ObjectIDFromHex(id); err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+	return rules, rows.Err()
+}
+
+func (p *PostgresBackend) DeleteAlertRule(ctx context.Context, id primitive.ObjectID) error {
+	result, err := p.conn(ctx).ExecContext(ctx, `DELETE FROM alert_rules WHERE id = $1`, id.Hex())
+	if err != nil {
+		p.logger.FromContext(ctx).Errorf("Can't delete alert rule: %s", err)
+		return err
+	}
+	if affected, err := result.RowsAffected(); err != nil {
+		return err
+	} else if affected == 0 {
+		return domain.ErrAlertRuleNotFound
+	}
+	return nil
+}
+
+func (p *PostgresBackend) InsertDeadLetter(ctx context.Context, letter domain.DeadLetter) error {
+	letter.Id = primitive.NewObjectID()
+	_, err := p.conn(ctx).ExecContext(ctx, `INSERT INTO alert_dead_letters
+		(id, rule_id, webhook_url, payload, attempts, last_error, failed_at) VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+		letter.Id.Hex(), letter.RuleId.Hex(), letter.WebhookUrl, letter.Payload, letter.Attempts, letter.LastError, letter.FailedAt)
+	if err != nil {
+		p.logger.FromContext(ctx).Errorf("Can't insert dead letter: %s", err)
+		return err
+	}
+	return nil
+}
+
+// ListDeadLetters returns the newest limit dead letters first.
+func (p *PostgresBackend) ListDeadLetters(ctx context.Context, limit int) ([]domain.DeadLetter, error) {
+	var pageLimit any
+	if limit > 0 {
+		pageLimit = limit
+	}
+	rows, err := p.conn(ctx).QueryContext(ctx, `SELECT id, rule_id, webhook_url, payload, attempts, last_error, failed_at
+		FROM alert_dead_letters ORDER BY failed_at DESC LIMIT $1`, pageLimit)
+	if err != nil {
+		p.logger.FromContext(ctx).Errorf("Can't list dead letters: %s", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var letters []domain.DeadLetter
+	for rows.Next() {
+		var letter domain.DeadLetter
+		var id, ruleId string
+		err := rows.Scan(&id, &ruleId, &letter.WebhookUrl, &letter.Payload, &letter.Attempts, &letter.LastError, &letter.FailedAt)
+		if err != nil {
+			p.logger.FromContext(ctx).Errorf("Can't decode dead letters: %s", err)
+			return nil, err
+		}
+		if letter.Id, err = primitive.ObjectIDFromHex(id); err != nil {
+			return nil, err
+		}
+		if letter.RuleId, err = primitive.ObjectIDFromHex(ruleId); err != nil {
+			return nil, err
+		}
+		letters = append(letters, letter)
+	}
+	return letters, rows.Err()
+}
@@ -0,0 +1,167 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"gRPC-server/internal/domain"
+	"strconv"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// GetStats returns what MongoBackend.GetStats does. Totals and explicit buckets are counted by
+// postgres; $bucketAuto has no SQL counterpart, so for automatic buckets the sorted prices are
+// read and split by bucketAuto.
+func (p *PostgresBackend) GetStats(ctx context.Context, filter domain.StatsFilter) (domain.Stats, error) {
+	where, args := statsWhere(filter)
+	q := p.conn(ctx)
+
+	var stats domain.Stats
+	var minPrice, maxPrice, avgPrice sql.NullString
+	// trim_scale drops the zeros avg pads its result with, Decimal128 division doesn't add them
+	err := q.QueryRowContext(ctx, fmt.Sprintf(`SELECT count(*), min(price), max(price), trim_scale(avg(price)),
+		count(*) FILTER (WHERE changes_count > 0 AND date_of_change >= $%d)
+		FROM products WHERE %s`, len(args)+1, where), append(args, filter.ChangedSince)...,
+	).Scan(&stats.Total, &minPrice, &maxPrice, &avgPrice, &stats.ChangedRecently)
+	if err != nil {
+		p.logger.FromContext(ctx).Errorf("Stats query error: %s", err)
+		return domain.Stats{}, err
+	}
+	if stats.Total == 0 {
+		return domain.Stats{}, nil
+	}
+
+	for _, price := range []struct {
+		value sql.NullString
+		dest  *primitive.Decimal128
+	}{{minPrice, &stats.MinPrice}, {maxPrice, &stats.MaxPrice}, {avgPrice, &stats.AvgPrice}} {
+		if *price.dest, err = primitive.ParseDecimal128(price.value.String); err != nil {
+			return domain.Stats{}, err
+		}
+	}
+
+	if stats.MedianPrice, err = p.medianPrice(ctx, where, args, stats.Total); err != nil {
+		return domain.Stats{}, err
+	}
+	if stats.Histogram, err = p.histogram(ctx, where, args, filter); err != nil {
+		return domain.Stats{}, err
+	}
+
+	rows, err := q.QueryContext(ctx, fmt.Sprintf(`SELECT %s, changes_count FROM products
+		WHERE %s AND changes_count > 0 ORDER BY changes_count DESC, id LIMIT $%d`,
+		pgProductColumns, where, len(args)+1), append(args, filter.TopChanged)...)
+	if err != nil {
+		p.logger.FromContext(ctx).Errorf("Most changed query error: %s", err)
+		return domain.Stats{}, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var changes domain.ProductChanges
+		if changes.Product, err = scanProduct(rows, &changes.ChangesCount); err != nil {
+			return domain.Stats{}, err
+		}
+		stats.MostChanged = append(stats.MostChanged, changes)
+	}
+	if err := rows.Err(); err != nil {
+		return domain.Stats{}, err
+	}
+
+	return stats, nil
+}
+
+// medianPrice averages the one or two middle prices, so it stays an exact decimal. A single
+// middle price is returned as stored.
+func (p *PostgresBackend) medianPrice(ctx context.Context, where string, args []any, total int64) (primitive.Decimal128, error) {
+	skip, limit := (total-1)/2, 1
+	if total%2 == 0 {
+		limit = 2
+	}
+
+	var median string
+	err := p.conn(ctx).QueryRowContext(ctx, fmt.Sprintf(`SELECT CASE WHEN count(*) = 1 THEN min(price) ELSE trim_scale(avg(price)) END
+		FROM (SELECT price FROM products WHERE %s ORDER BY price OFFSET %d LIMIT %d) middle`, where, skip, limit),
+		args...).Scan(&median)
+	if err != nil {
+		p.logger.FromContext(ctx).Errorf("Median query error: %s", err)
+		return primitive.Decimal128{}, err
+	}
+	return primitive.ParseDecimal128(median)
+}
+
+func (p *PostgresBackend) histogram(ctx context.Context, where string, args []any, filter domain.StatsFilter) ([]domain.HistogramBucket, error) {
+	if len(filter.HistogramBounds) < 2 {
+		prices, err := p.sortedPrices(ctx, where, args)
+		if err != nil {
+			return nil, err
+		}
+		return bucketAuto(prices, filter.HistogramBuckets), nil
+	}
+
+	// prices outside the bounds are not counted, every range is reported even when empty
+	histogram := make([]domain.HistogramBucket, len(filter.HistogramBounds)-1)
+	counts := make([]string, len(histogram))
+	dest := make([]any, len(histogram))
+	for i := range histogram {
+		histogram[i] = domain.HistogramBucket{
+			LowerBound: filter.HistogramBounds[i],
+			UpperBound: filter.HistogramBounds[i+1],
+		}
+		args = append(args, filter.HistogramBounds[i].String(), filter.HistogramBounds[i+1].String())
+		counts[i] = fmt.Sprintf("count(*) FILTER (WHERE price >= $%d AND price < $%d)", len(args)-1, len(args))
+		dest[i] = &histogram[i].Count
+	}
+
+	query := "SELECT " + strings.Join(counts, ", ") + " FROM products WHERE " + where
+	if err := p.conn(ctx).QueryRowContext(ctx, query, args...).Scan(dest...); err != nil {
+		p.logger.FromContext(ctx).Errorf("Histogram query error: %s", err)
+		return nil, err
+	}
+	return histogram, nil
+}
+
+func (p *PostgresBackend) sortedPrices(ctx context.Context, where string, args []any) ([]storedPrice, error) {
+	rows, err := p.conn(ctx).QueryContext(ctx, "SELECT price FROM products WHERE "+where+" ORDER BY price", args...)
+	if err != nil {
+		p.logger.FromContext(ctx).Errorf("Histogram query error: %s", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var prices []storedPrice
+	for rows.Next() {
+		var value string
+		if err := rows.Scan(&value); err != nil {
+			return nil, err
+		}
+		stored, err := primitive.ParseDecimal128(value)
+		if err != nil {
+			return nil, err
+		}
+		prices = append(prices, storedPrice{stored: stored, value: toDecimal(stored)})
+	}
+	return prices, rows.Err()
+}
+
+// statsWhere is statsMatch for postgres: live products, name containing filter.Name in any case
+// and the price range.
+func statsWhere(filter domain.StatsFilter) (string, []any) {
+	where := []string{"deleted_at IS NULL"}
+	var args []any
+	arg := func(value any) string {
+		args = append(args, value)
+		return "$" + strconv.Itoa(len(args))
+	}
+
+	if filter.Name != "" {
+		where = append(where, "strpos(lower(name), lower("+arg(filter.Name)+")) > 0")
+	}
+	if filter.MinPrice != nil {
+		where = append(where, "price >= "+arg(filter.MinPrice.String()))
+	}
+	if filter.MaxPrice != nil {
+		where = append(where, "price <= "+arg(filter.MaxPrice.String()))
+	}
+	return strings.Join(where, " AND "), args
+}
@@ -0,0 +1,121 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"gRPC-server/internal/domain"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+const (
+	pgTokenPrefix = "pg:"
+
+	// pgEventsPage is how many events one poll reads, a full page is followed by the next at once
+	pgEventsPage = 1000
+
+	defaultEventsRetention = 7 * 24 * time.Hour
+)
+
+// eventsCutoff returns the time product_events older than which are trimmed, resume tokens of those
+// are rejected afterwards.
+func eventsCutoff() time.Time {
+	retention := viper.GetDuration("watch.events_retention")
+	if retention <= 0 {
+		retention = defaultEventsRetention
+	}
+	return time.Now().Add(-retention)
+}
+
+// Watch sends product changes from product_events until ctx is done or send fails, polling
+// every watch.poll_interval. Without a resume token it starts from now; a token of a change
+// PurgeDeleted already trimmed is rejected.
+func (p *PostgresBackend) Watch(ctx context.Context, resumeToken string, send func(domain.ProductEvent) error) error {
+	last, err := p.watchStart(ctx, resumeToken)
+	if err != nil {
+		return err
+	}
+
+	interval := viper.GetDuration("watch.poll_interval")
+	if interval <= 0 {
+		interval = defaultPollInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		read, err := p.sendEvents(ctx, &last, send)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return err
+		}
+		if read == pgEventsPage {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// watchStart returns the seq Watch continues after.
+func (p *PostgresBackend) watchStart(ctx context.Context, resumeToken string) (int64, error) {
+	var first, newest sql.NullInt64
+	err := p.db.QueryRowContext(ctx, `SELECT min(seq), max(seq) FROM product_events`).Scan(&first, &newest)
+	if err != nil {
+		p.logger.FromContext(ctx).Errorf("Can't read product events: %s", err)
+		return 0, err
+	}
+	if resumeToken == "" {
+		return newest.Int64, nil
+	}
+
+	if !strings.HasPrefix(resumeToken, pgTokenPrefix) {
+		return 0, fmt.Errorf("%w: unknown resume token", domain.ErrInvalidArgument)
+	}
+	last, err := strconv.ParseInt(strings.TrimPrefix(resumeToken, pgTokenPrefix), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%w: malformed resume token", domain.ErrInvalidArgument)
+	}
+	if first.Valid && last+1 < first.Int64 {
+		return 0, fmt.Errorf("%w: resume token is too old", domain.ErrInvalidArgument)
+	}
+	return last, nil
+}
+
+// sendEvents sends one page of events after *last and moves *last past the ones it sent.
+func (p *PostgresBackend) sendEvents(ctx context.Context, last *int64, send func(domain.ProductEvent) error) (int, error) {
+	rows, err := p.db.QueryContext(ctx, `SELECT `+pgProductColumns+`, seq, type, created_at
+		FROM product_events WHERE seq > $1 ORDER BY seq LIMIT $2`, *last, pgEventsPage)
+	if err != nil {
+		p.logger.FromContext(ctx).Errorf("Poll product events error: %s", err)
+		return 0, err
+	}
+	defer rows.Close()
+
+	read := 0
+	for rows.Next() {
+		var seq int64
+		var event domain.ProductEvent
+		if event.Product, err = scanProduct(rows, &seq, &event.Type, &event.Time); err != nil {
+			return read, err
+		}
+
+		event.ResumeToken = pgTokenPrefix + strconv.FormatInt(seq, 10)
+		if err := send(event); err != nil {
+			return read, err
+		}
+		*last = seq
+		read++
+	}
+	return read, rows.Err()
+}
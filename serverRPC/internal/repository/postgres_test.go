@@ -0,0 +1,87 @@
+package repository
+
+import (
+	"gRPC-server/internal/domain"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestListQuery(t *testing.T) {
+	testTables := []struct {
+		name      string
+		sort      domain.SortParams
+		wantQuery string
+		wantArgs  []any
+	}{
+		{
+			name: "One source",
+			sort: domain.SortParams{SortField: "name", SortAsc: -1, PagingOffset: 20, PagingLimit: 10, Source: "web-app"},
+			wantQuery: `SELECT source, id, name, price, deleted_at, date_of_change FROM products ` +
+				`WHERE deleted_at IS NULL AND source = $1 ORDER BY name COLLATE "C" DESC, source, id OFFSET $2 LIMIT $3`,
+			wantArgs: []any{"web-app", int32(20), int32(10)},
+		},
+		{
+			name: "Without limit and with deleted",
+			sort: domain.SortParams{SortField: "price", SortAsc: 1, IncludeDeleted: true, Source: "web-app"},
+			wantQuery: `SELECT source, id, name, price, deleted_at, date_of_change FROM products ` +
+				`WHERE source = $1 ORDER BY price ASC, source, id OFFSET $2 LIMIT $3`,
+			wantArgs: []any{"web-app", int32(0), nil},
+		},
+		{
+			name: "Merged by priority",
+			sort: domain.SortParams{
+				SortField: "id",
+				SortAsc:   1,
+				Merge: domain.MergeRule{
+					Strategy:   domain.MergePriority,
+					Priorities: map[string]int{"web-app": 10, "supplier": 5},
+				},
+			},
+			wantQuery: `SELECT source, id, name, price, deleted_at, date_of_change FROM (` +
+				`SELECT source, id, name, price, deleted_at, date_of_change FROM (` +
+				`SELECT source, id, name, price, deleted_at, date_of_change, row_number() OVER (PARTITION BY merge_key ORDER BY ` +
+				`CASE source WHEN $1 THEN 5 WHEN $2 THEN 10 ELSE 0 END DESC, date_of_change DESC, source, id) AS merge_rank, ` +
+				`min(source) OVER (PARTITION BY merge_key) = max(source) OVER (PARTITION BY merge_key) AS single_source ` +
+				`FROM products WHERE deleted_at IS NULL` +
+				`) ranked WHERE merge_rank = 1 OR single_source) merged ORDER BY id ASC, source, id OFFSET $3 LIMIT $4`,
+			wantArgs: []any{"supplier", "web-app", int32(0), nil},
+		},
+		{
+			name: "Merged by lowest price without priorities",
+			sort: domain.SortParams{
+				SortField:      "price",
+				SortAsc:        -1,
+				IncludeDeleted: true,
+				Merge:          domain.MergeRule{Strategy: domain.MergeLowestPrice},
+			},
+			wantQuery: `SELECT source, id, name, price, deleted_at, date_of_change FROM (` +
+				`SELECT source, id, name, price, deleted_at, date_of_change FROM (` +
+				`SELECT source, id, name, price, deleted_at, date_of_change, row_number() OVER (PARTITION BY merge_key ORDER BY ` +
+				`price ASC, source, id) AS merge_rank, ` +
+				`min(source) OVER (PARTITION BY merge_key) = max(source) OVER (PARTITION BY merge_key) AS single_source ` +
+				`FROM products` +
+				`) ranked WHERE merge_rank = 1 OR single_source) merged ORDER BY price DESC, source, id OFFSET $1 LIMIT $2`,
+			wantArgs: []any{int32(0), nil},
+		},
+	}
+
+	for _, table := range testTables {
+		t.Run(table.name, func(t *testing.T) {
+			query, args := listQuery(table.sort)
+
+			assert.Equal(t, table.wantQuery, query)
+			assert.Equal(t, table.wantArgs, args)
+		})
+	}
+}
+
+func TestStatsWhere(t *testing.T) {
+	minPrice, _ := primitive.ParseDecimal128("10.5")
+
+	where, args := statsWhere(domain.StatsFilter{Name: "milk", MinPrice: &minPrice})
+
+	assert.Equal(t, "deleted_at IS NULL AND strpos(lower(name), lower($1)) > 0 AND price >= $2", where)
+	assert.Equal(t, []any{"milk", "10.5"}, args)
+}
@@ -2,28 +2,79 @@ package repository
 
 import (
 	"context"
+	"gRPC-server/internal/cache"
 	"gRPC-server/internal/domain"
+	"gRPC-server/internal/metrics"
 	"gRPC-server/pkg/logger"
 	"gRPC-server/pkg/parseCSV/grpcPb"
+	"sync/atomic"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 //go:generate mockgen -source=repository.go -destination=mocks/mock.go
+type UnitOfWork interface {
+	WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
 type Sorting interface {
+	UnitOfWork
 	Insert(ctx context.Context, product []domain.Product) error
 	List(ctx context.Context, sortParams domain.SortParams) ([]domain.Product, error)
+	Export(ctx context.Context, sortParams domain.SortParams, send func(domain.Product) error) error
+	// GetByName and UpdateProduct report a missing product with domain.ErrProductNotFound
 	GetByName(ctx context.Context, product domain.Product) (domain.Product, error)
+	GetByIds(ctx context.Context, source string, ids []int) ([]domain.Product, error)
 	UpdateProduct(ctx context.Context, product domain.Product) error
+	GetStats(ctx context.Context, filter domain.StatsFilter) (domain.Stats, error)
+	Watch(ctx context.Context, resumeToken string, send func(domain.ProductEvent) error) error
+	DeleteProduct(ctx context.Context, product domain.Product) error
+	RestoreProduct(ctx context.Context, product domain.Product) error
+	PurgeDeleted(ctx context.Context, olderThan time.Time) (int64, error)
+	Ping(ctx context.Context) error
+	Schedules
+	Alerts
+}
+
+// Schedules stores recurring fetches and the lease that picks the replica running them.
+type Schedules interface {
+	CreateSchedule(ctx context.Context, schedule domain.Schedule) (domain.Schedule, error)
+	ListSchedules(ctx context.Context) ([]domain.Schedule, error)
+	GetSchedule(ctx context.Context, id primitive.ObjectID) (domain.Schedule, error)
+	SetSchedulePaused(ctx context.Context, id primitive.ObjectID, paused bool, nextRun time.Time) (domain.Schedule, error)
+	DeleteSchedule(ctx context.Context, id primitive.ObjectID) error
+	DueSchedules(ctx context.Context, now time.Time) ([]domain.Schedule, error)
+	ClaimScheduleRun(ctx context.Context, id primitive.ObjectID, scheduled, next time.Time) (bool, error)
+	FinishScheduleRun(ctx context.Context, id primitive.ObjectID, at time.Time, status, runErr string) error
+	AcquireLease(ctx context.Context, name, holder string, ttl time.Duration) (bool, error)
+	ReleaseLease(ctx context.Context, name, holder string) error
+}
+
+// Alerts stores price alert rules and the webhook deliveries that gave up.
+type Alerts interface {
+	CreateAlertRule(ctx context.Context, rule domain.AlertRule) (domain.AlertRule, error)
+	ListAlertRules(ctx context.Context) ([]domain.AlertRule, error)
+	DeleteAlertRule(ctx context.Context, id primitive.ObjectID) error
+	InsertDeadLetter(ctx context.Context, letter domain.DeadLetter) error
+	ListDeadLetters(ctx context.Context, limit int) ([]domain.DeadLetter, error)
 }
 
 type Repository struct {
 	Sorting
 	logger *logger.Logger
+
+	// listCache is nil unless cache.list is enabled
+	listCache cache.Cache
+	// listVersion is part of every cache key, see invalidate
+	listVersion atomic.Uint64
 }
 
 func NewRepo(sort Sorting, logger *logger.Logger) *Repository {
 	return &Repository{
-		Sorting: sort,
-		logger:  logger,
+		Sorting:   sort,
+		logger:    logger,
+		listCache: cache.New(),
 	}
 }
 
@@ -31,20 +82,53 @@ func (r *Repository) Fetch(ctx context.Context, req []domain.Product) (domain.St
 	if err := r.Sorting.Insert(ctx, req); err != nil {
 		return domain.Status{}, err
 	}
+	r.changed(ctx)
 	return domain.Status{}, nil
 }
 
-func (r *Repository) List(ctx context.Context, req *grpcPb.ListRequest) ([]domain.Product, error) {
-	sortParams := domain.SortParams{
-		SortField:    req.GetSortField().String(),
-		SortAsc:      req.GetSortAsc(),
-		PagingOffset: req.GetPagingOffset(),
-		PagingLimit:  req.GetPagingLimit(),
+// List reads one source when req names it, otherwise the catalog merged by merge. With
+// cache.list enabled the result is read through the cache.
+func (r *Repository) List(ctx context.Context, req *grpcPb.ListRequest, merge domain.MergeRule) ([]domain.Product, error) {
+	sort := sortParams(req, merge)
+
+	// a unit of work may see changes that aren't committed, those must not get into the cache
+	cached := r.listCache != nil && ctx.Value(unitOfWorkKey{}) == nil
+	var key string
+	if cached {
+		key = listKey(r.listVersion.Load(), sort)
+		if products, ok := r.listCache.Get(ctx, key); ok {
+			metrics.ListCacheRequests.WithLabelValues("hit").Inc()
+			return products, nil
+		}
+		metrics.ListCacheRequests.WithLabelValues("miss").Inc()
 	}
-	products, err := r.Sorting.List(ctx, sortParams)
+
+	products, err := r.Sorting.List(ctx, sort)
 	if err != nil {
-		r.logger.Errorf("Can't list sortParams: %s", err)
+		r.logger.FromContext(ctx).Errorf("Can't list sortParams: %s", err)
 		return []domain.Product{}, err
 	}
+	if cached {
+		r.listCache.Set(ctx, key, products)
+	}
 	return products, nil
 }
+
+// Export streams what List would return for req to send.
+func (r *Repository) Export(ctx context.Context, req *grpcPb.ListRequest, merge domain.MergeRule, send func(domain.Product) error) error {
+	return r.Sorting.Export(ctx, sortParams(req, merge), send)
+}
+
+func sortParams(req *grpcPb.ListRequest, merge domain.MergeRule) domain.SortParams {
+	return domain.SortParams{
+		SortField:    req.GetSortField().String(),
+		SortAsc:      req.GetSortAsc(),
+		PagingOffset: req.GetPagingOffset(),
+		PagingLimit:  req.GetPagingLimit(),
+
+		IncludeDeleted: req.GetIncludeDeleted(),
+
+		Source: req.GetSource(),
+		Merge:  merge,
+	}
+}
@@ -174,6 +174,28 @@ func TestList(t *testing.T) {
 			},
 			isErr: false,
 		},
+		{
+			name: "Include deleted",
+			mockBehavior: func(m *mock_repository.MockSorting, ctx context.Context, sortParams domain.SortParams, products []domain.Product) {
+				m.EXPECT().List(ctx, sortParams).Return(products, nil)
+			},
+			products: []domain.Product{},
+			sortParams: domain.SortParams{
+				SortField:      "price",
+				SortAsc:        -1,
+				PagingOffset:   0,
+				PagingLimit:    10,
+				IncludeDeleted: true,
+			},
+			req: &grpcPb.ListRequest{
+				SortField:      2,
+				SortAsc:        -1,
+				PagingOffset:   0,
+				PagingLimit:    10,
+				IncludeDeleted: true,
+			},
+			isErr: false,
+		},
 		{
 			name: "Repository error",
 			mockBehavior: func(m *mock_repository.MockSorting, ctx context.Context, sortParams domain.SortParams, products []domain.Product) {
@@ -206,7 +228,7 @@ func TestList(t *testing.T) {
 
 			table.mockBehavior(mockRepo, table.ctx, table.sortParams, table.products)
 
-			got, err := repo.List(table.ctx, table.req)
+			got, err := repo.List(table.ctx, table.req, domain.MergeRule{})
 
 			if table.isErr {
 				assert.Error(t, err)
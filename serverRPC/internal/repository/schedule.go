@@ -0,0 +1,166 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"gRPC-server/internal/domain"
+	"time"
+
+	"github.com/spf13/viper"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func (m *MongoBackend) schedules() *mongo.Collection {
+	return m.db.Collection(viper.GetString("mongo.schedules"))
+}
+
+func (m *MongoBackend) CreateSchedule(ctx context.Context, schedule domain.Schedule) (domain.Schedule, error) {
+	schedule.Id = primitive.NewObjectID()
+	if _, err := m.schedules().InsertOne(ctx, schedule); err != nil {
+		m.logger.FromContext(ctx).Errorf("Can't create schedule: %s", err)
+		return domain.Schedule{}, err
+	}
+	return schedule, nil
+}
+
+func (m *MongoBackend) ListSchedules(ctx context.Context) ([]domain.Schedule, error) {
+	cursor, err := m.schedules().Find(ctx, bson.D{}, options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}}))
+	if err != nil {
+		m.logger.FromContext(ctx).Errorf("Can't list schedules: %s", err)
+		return nil, err
+	}
+
+	var schedules []domain.Schedule
+	if err := cursor.All(ctx, &schedules); err != nil {
+		m.logger.FromContext(ctx).Errorf("Can't decode schedules: %s", err)
+		return nil, err
+	}
+	return schedules, nil
+}
+
+func (m *MongoBackend) GetSchedule(ctx context.Context, id primitive.ObjectID) (domain.Schedule, error) {
+	var schedule domain.Schedule
+	err := m.schedules().FindOne(ctx, bson.D{{Key: "_id", Value: id}}).Decode(&schedule)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return domain.Schedule{}, domain.ErrScheduleNotFound
+	}
+	if err != nil {
+		m.logger.FromContext(ctx).Errorf("Can't get schedule: %s", err)
+		return domain.Schedule{}, err
+	}
+	return schedule, nil
+}
+
+// SetSchedulePaused pauses or resumes a schedule; nextRun is only stored when resuming.
+func (m *MongoBackend) SetSchedulePaused(ctx context.Context, id primitive.ObjectID, paused bool, nextRun time.Time) (domain.Schedule, error) {
+	set := bson.D{{Key: "paused", Value: paused}}
+	if !paused {
+		set = append(set, bson.E{Key: "next_run", Value: nextRun})
+	}
+
+	var schedule domain.Schedule
+	err := m.schedules().FindOneAndUpdate(ctx,
+		bson.D{{Key: "_id", Value: id}},
+		bson.D{{Key: "$set", Value: set}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&schedule)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return domain.Schedule{}, domain.ErrScheduleNotFound
+	}
+	if err != nil {
+		m.logger.FromContext(ctx).Errorf("Can't update schedule: %s", err)
+		return domain.Schedule{}, err
+	}
+	return schedule, nil
+}
+
+func (m *MongoBackend) DeleteSchedule(ctx context.Context, id primitive.ObjectID) error {
+	result, err := m.schedules().DeleteOne(ctx, bson.D{{Key: "_id", Value: id}})
+	if err != nil {
+		m.logger.FromContext(ctx).Errorf("Can't delete schedule: %s", err)
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return domain.ErrScheduleNotFound
+	}
+	return nil
+}
+
+// DueSchedules returns active schedules whose next run is not after now.
+func (m *MongoBackend) DueSchedules(ctx context.Context, now time.Time) ([]domain.Schedule, error) {
+	filter := bson.D{
+		{Key: "paused", Value: false},
+		{Key: "next_run", Value: bson.D{{Key: "$lte", Value: now}}},
+	}
+	cursor, err := m.schedules().Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "next_run", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+
+	var schedules []domain.Schedule
+	if err := cursor.All(ctx, &schedules); err != nil {
+		return nil, err
+	}
+	return schedules, nil
+}
+
+// ClaimScheduleRun moves next_run from scheduled to next. Only one caller can move it,
+// so a run is never started twice even if two replicas briefly both think they lead.
+func (m *MongoBackend) ClaimScheduleRun(ctx context.Context, id primitive.ObjectID, scheduled, next time.Time) (bool, error) {
+	result, err := m.schedules().UpdateOne(ctx,
+		bson.D{{Key: "_id", Value: id}, {Key: "next_run", Value: scheduled}},
+		bson.D{{Key: "$set", Value: bson.D{{Key: "next_run", Value: next}}}},
+	)
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount == 1, nil
+}
+
+func (m *MongoBackend) FinishScheduleRun(ctx context.Context, id primitive.ObjectID, at time.Time, status, runErr string) error {
+	_, err := m.schedules().UpdateOne(ctx,
+		bson.D{{Key: "_id", Value: id}},
+		bson.D{{Key: "$set", Value: bson.D{
+			{Key: "last_run", Value: at},
+			{Key: "last_status", Value: status},
+			{Key: "last_error", Value: runErr},
+		}}},
+	)
+	return err
+}
+
+// AcquireLease takes or renews the lease name for holder until now+ttl. It fails without
+// an error when another holder has a lease that hasn't expired.
+func (m *MongoBackend) AcquireLease(ctx context.Context, name, holder string, ttl time.Duration) (bool, error) {
+	now := time.Now()
+	filter := bson.D{
+		{Key: "_id", Value: name},
+		{Key: "$or", Value: bson.A{
+			bson.D{{Key: "holder", Value: holder}},
+			bson.D{{Key: "expires_at", Value: bson.D{{Key: "$lt", Value: now}}}},
+		}},
+	}
+	update := bson.D{{Key: "$set", Value: bson.D{
+		{Key: "holder", Value: holder},
+		{Key: "expires_at", Value: now.Add(ttl)},
+	}}}
+
+	// with the lease held by someone else the filter misses and the upsert hits the _id index
+	_, err := m.db.Collection(viper.GetString("mongo.leases")).UpdateOne(ctx, filter, update, options.Update().SetUpsert(true))
+	if mongo.IsDuplicateKeyError(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (m *MongoBackend) ReleaseLease(ctx context.Context, name, holder string) error {
+	_, err := m.db.Collection(viper.GetString("mongo.leases")).DeleteOne(ctx,
+		bson.D{{Key: "_id", Value: name}, {Key: "holder", Value: holder}})
+	return err
+}
@@ -0,0 +1,151 @@
+package repository
+
+import (
+	"context"
+	"gRPC-server/internal/domain"
+	"maps"
+	"slices"
+
+	"github.com/spf13/viper"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// productFilter matches a product by its identity, the id alone is only unique within a source.
+func productFilter(product domain.Product) bson.D {
+	return bson.D{{Key: "source", Value: product.Source}, {Key: "id", Value: product.Id}}
+}
+
+// mongoProduct is a product as the collection stores it, merge_key is what mergedPipeline groups by.
+type mongoProduct struct {
+	domain.Product `bson:",inline"`
+	MergeKey       string `bson:"merge_key"`
+}
+
+// MigrateSources moves products and history written before sources existed under
+// defaultSource, makes (source, id) unique and fills merge_key of products written before it
+// was stored. It is safe to run on every start.
+func (m *MongoBackend) MigrateSources(ctx context.Context, defaultSource string) error {
+	filter := bson.D{{Key: "source", Value: bson.D{{Key: "$exists", Value: false}}}}
+	update := bson.D{{Key: "$set", Value: bson.D{{Key: "source", Value: defaultSource}}}}
+
+	for _, collection := range []string{viper.GetString("mongo.collection"), viper.GetString("mongo.history")} {
+		result, err := m.db.Collection(collection).UpdateMany(ctx, filter, update)
+		if err != nil {
+			m.logger.FromContext(ctx).Errorf("Can't migrate %s to sources: %s", collection, err)
+			return err
+		}
+		if result.ModifiedCount > 0 {
+			m.logger.FromContext(ctx).Infof("Moved %d documents of %s to source %q", result.ModifiedCount, collection, defaultSource)
+		}
+	}
+
+	_, err := m.db.Collection(viper.GetString("mongo.collection")).Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "source", Value: 1}, {Key: "id", Value: 1}},
+		Options: options.Index().SetUnique(true).SetName("source_id"),
+	})
+	if err != nil {
+		m.logger.FromContext(ctx).Errorf("Can't create source_id index: %s", err)
+		return err
+	}
+	return m.backfillMergeKeys(ctx)
+}
+
+// backfillMergeKeys sets merge_key where it is missing. $toLower only lowers ASCII, so the key
+// is computed in Go, the same way the writes compute it.
+func (m *MongoBackend) backfillMergeKeys(ctx context.Context) error {
+	collection := m.db.Collection(viper.GetString("mongo.collection"))
+	filter := bson.D{{Key: "merge_key", Value: bson.D{{Key: "$exists", Value: false}}}}
+	cursor, err := collection.Find(ctx, filter, options.Find().SetProjection(bson.D{{Key: "name", Value: 1}}))
+	if err != nil {
+		m.logger.FromContext(ctx).Errorf("Can't find products without merge_key: %s", err)
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	var writes []mongo.WriteModel
+	for cursor.Next(ctx) {
+		var doc struct {
+			Id   primitive.ObjectID `bson:"_id"`
+			Name string             `bson:"name"`
+		}
+		if err := cursor.Decode(&doc); err != nil {
+			return err
+		}
+		writes = append(writes, mongo.NewUpdateOneModel().
+			SetFilter(bson.D{{Key: "_id", Value: doc.Id}}).
+			SetUpdate(bson.D{{Key: "$set", Value: bson.D{{Key: "merge_key", Value: domain.MergeKey(doc.Name)}}}}))
+	}
+	if err := cursor.Err(); err != nil || len(writes) == 0 {
+		return err
+	}
+
+	if _, err := collection.BulkWrite(ctx, writes, options.BulkWrite().SetOrdered(false)); err != nil {
+		m.logger.FromContext(ctx).Errorf("Can't backfill merge_key: %s", err)
+		return err
+	}
+	m.logger.FromContext(ctx).Infof("Filled merge_key of %d products", len(writes))
+	return nil
+}
+
+// mergedPipeline keeps one copy of every product, picked by sort.Merge, and then sorts and pages
+// the result like List does for a single source.
+func mergedPipeline(sort domain.SortParams) mongo.Pipeline {
+	var pipeline mongo.Pipeline
+	if !sort.IncludeDeleted {
+		pipeline = append(pipeline, bson.D{{Key: "$match", Value: bson.D{{Key: "deleted_at", Value: nil}}}})
+	}
+
+	// priorities are looked up at query time, a changed registry applies without re-importing
+	branches := bson.A{}
+	for _, source := range slices.Sorted(maps.Keys(sort.Merge.Priorities)) {
+		branches = append(branches, bson.D{
+			{Key: "case", Value: bson.D{{Key: "$eq", Value: bson.A{"$source", source}}}},
+			{Key: "then", Value: sort.Merge.Priorities[source]},
+		})
+	}
+	priority := interface{}(0)
+	if len(branches) > 0 {
+		priority = bson.D{{Key: "$switch", Value: bson.D{{Key: "branches", Value: branches}, {Key: "default", Value: 0}}}}
+	}
+
+	var winner bson.D
+	switch sort.Merge.Strategy {
+	case domain.MergeLowestPrice:
+		winner = bson.D{{Key: "price", Value: 1}, {Key: "_priority", Value: -1}}
+	case domain.MergeNewest:
+		winner = bson.D{{Key: "date_of_change", Value: -1}, {Key: "_priority", Value: -1}}
+	default:
+		winner = bson.D{{Key: "_priority", Value: -1}, {Key: "date_of_change", Value: -1}}
+	}
+	// ties are broken by source and id so pages stay stable between calls
+	winner = append(winner, bson.E{Key: "source", Value: 1}, bson.E{Key: "id", Value: 1})
+
+	pipeline = append(pipeline,
+		bson.D{{Key: "$addFields", Value: bson.D{{Key: "_priority", Value: priority}}}},
+		bson.D{{Key: "$sort", Value: append(bson.D{{Key: "merge_key", Value: 1}}, winner...)}},
+		bson.D{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: "$merge_key"},
+			{Key: "products", Value: bson.D{{Key: "$push", Value: "$$ROOT"}}},
+			{Key: "sources", Value: bson.D{{Key: "$addToSet", Value: "$source"}}},
+		}}},
+		// only copies from different sources merge, products of one source that share a key are
+		// different products and all stay
+		bson.D{{Key: "$project", Value: bson.D{{Key: "products", Value: bson.D{{Key: "$cond", Value: bson.A{
+			bson.D{{Key: "$gt", Value: bson.A{bson.D{{Key: "$size", Value: "$sources"}}, 1}}},
+			bson.D{{Key: "$slice", Value: bson.A{"$products", 1}}},
+			"$products",
+		}}}}}}},
+		bson.D{{Key: "$unwind", Value: "$products"}},
+		bson.D{{Key: "$replaceRoot", Value: bson.D{{Key: "newRoot", Value: "$products"}}}},
+		bson.D{{Key: "$unset", Value: bson.A{"_priority"}}},
+		bson.D{{Key: "$sort", Value: listSort(sort)}},
+		bson.D{{Key: "$skip", Value: int64(sort.PagingOffset)}},
+	)
+	if sort.PagingLimit > 0 {
+		pipeline = append(pipeline, bson.D{{Key: "$limit", Value: int64(sort.PagingLimit)}})
+	}
+	return pipeline
+}
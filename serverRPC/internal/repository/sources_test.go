@@ -0,0 +1,70 @@
+package repository
+
+import (
+	"gRPC-server/internal/domain"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestMergedPipeline(t *testing.T) {
+	stage := func(pipeline []bson.D, name string) interface{} {
+		for _, s := range pipeline {
+			if s[0].Key == name {
+				return s[0].Value
+			}
+		}
+		return nil
+	}
+	sorts := func(pipeline []bson.D) []bson.D {
+		var out []bson.D
+		for _, s := range pipeline {
+			if s[0].Key == "$sort" {
+				out = append(out, s[0].Value.(bson.D))
+			}
+		}
+		return out
+	}
+
+	t.Run("Priority", func(t *testing.T) {
+		pipeline := mergedPipeline(domain.SortParams{
+			SortField:   "price",
+			SortAsc:     -1,
+			PagingLimit: 10,
+			Merge: domain.MergeRule{
+				Strategy:   domain.MergePriority,
+				Priorities: map[string]int{"web-app": 10, "supplier": 5},
+			},
+		})
+
+		assert.Equal(t, bson.D{{Key: "deleted_at", Value: nil}}, stage(pipeline, "$match"))
+		assert.Equal(t, []bson.D{
+			{{Key: "merge_key", Value: 1}, {Key: "_priority", Value: -1}, {Key: "date_of_change", Value: -1}, {Key: "source", Value: 1}, {Key: "id", Value: 1}},
+			{{Key: "price", Value: int32(-1)}, {Key: "source", Value: 1}, {Key: "id", Value: 1}},
+		}, sorts(pipeline))
+		assert.Equal(t, int64(10), stage(pipeline, "$limit"))
+		assert.Equal(t, "$products", stage(pipeline, "$unwind"))
+
+		priority := stage(pipeline, "$addFields").(bson.D)[0].Value.(bson.D)[0].Value.(bson.D)
+		branches := priority[0].Value.(bson.A)
+		if assert.Len(t, branches, 2) {
+			assert.Equal(t, bson.A{"$source", "supplier"}, branches[0].(bson.D)[0].Value.(bson.D)[0].Value)
+			assert.Equal(t, 5, branches[0].(bson.D)[1].Value)
+		}
+	})
+
+	t.Run("Lowest price with deleted", func(t *testing.T) {
+		pipeline := mergedPipeline(domain.SortParams{
+			SortField:      "id",
+			SortAsc:        1,
+			IncludeDeleted: true,
+			Merge:          domain.MergeRule{Strategy: domain.MergeLowestPrice},
+		})
+
+		assert.Nil(t, stage(pipeline, "$match"))
+		assert.Nil(t, stage(pipeline, "$limit"))
+		assert.Equal(t, bson.D{{Key: "merge_key", Value: 1}, {Key: "price", Value: 1}, {Key: "_priority", Value: -1}, {Key: "source", Value: 1}, {Key: "id", Value: 1}}, sorts(pipeline)[0])
+		assert.Equal(t, 0, stage(pipeline, "$addFields").(bson.D)[0].Value)
+	})
+}
@@ -0,0 +1,233 @@
+package repository
+
+import (
+	"context"
+	"gRPC-server/internal/domain"
+	"regexp"
+
+	"github.com/spf13/viper"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type statsFacets struct {
+	Summary []struct {
+		Total    int64                `bson:"total"`
+		MinPrice primitive.Decimal128 `bson:"min_price"`
+		MaxPrice primitive.Decimal128 `bson:"max_price"`
+		AvgPrice primitive.Decimal128 `bson:"avg_price"`
+	} `bson:"summary"`
+	Histogram []struct {
+		Id    bson.RawValue `bson:"_id"`
+		Count int64         `bson:"count"`
+	} `bson:"histogram"`
+	ChangedRecently []struct {
+		Count int64 `bson:"count"`
+	} `bson:"changed_recently"`
+	MostChanged []domain.ProductChanges `bson:"most_changed"`
+}
+
+// GetStats collects everything except the median in one $facet pass over the filtered products;
+// the median needs the total first, so it is a second pipeline.
+func (m *MongoBackend) GetStats(ctx context.Context, filter domain.StatsFilter) (domain.Stats, error) {
+	collection := m.db.Collection(viper.GetString("mongo.collection"))
+	match := bson.D{{Key: "$match", Value: statsMatch(filter)}}
+
+	pipeline := mongo.Pipeline{
+		match,
+		{{Key: "$facet", Value: bson.D{
+			{Key: "summary", Value: bson.A{
+				bson.D{{Key: "$group", Value: bson.D{
+					{Key: "_id", Value: nil},
+					{Key: "total", Value: bson.D{{Key: "$sum", Value: 1}}},
+					{Key: "min_price", Value: bson.D{{Key: "$min", Value: "$price"}}},
+					{Key: "max_price", Value: bson.D{{Key: "$max", Value: "$price"}}},
+					{Key: "avg_price", Value: bson.D{{Key: "$avg", Value: "$price"}}},
+				}}},
+			}},
+			{Key: "histogram", Value: histogramStages(filter)},
+			{Key: "changed_recently", Value: bson.A{
+				bson.D{{Key: "$match", Value: bson.D{
+					{Key: "date_of_change", Value: bson.D{{Key: "$gte", Value: filter.ChangedSince}}},
+					{Key: "changes_count", Value: bson.D{{Key: "$gt", Value: 0}}},
+				}}},
+				bson.D{{Key: "$count", Value: "count"}},
+			}},
+			{Key: "most_changed", Value: bson.A{
+				bson.D{{Key: "$match", Value: bson.D{{Key: "changes_count", Value: bson.D{{Key: "$gt", Value: 0}}}}}},
+				bson.D{{Key: "$sort", Value: bson.D{{Key: "changes_count", Value: -1}, {Key: "id", Value: 1}}}},
+				bson.D{{Key: "$limit", Value: filter.TopChanged}},
+			}},
+		}}},
+	}
+
+	cursor, err := collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		m.logger.FromContext(ctx).Errorf("Stats aggregation error: %s", err)
+		return domain.Stats{}, err
+	}
+	defer cursor.Close(ctx)
+
+	var facets []statsFacets
+	if err := cursor.All(ctx, &facets); err != nil {
+		m.logger.FromContext(ctx).Errorf("Stats decode error: %s", err)
+		return domain.Stats{}, err
+	}
+
+	var stats domain.Stats
+	if len(facets) == 0 || len(facets[0].Summary) == 0 {
+		return stats, nil
+	}
+	result := facets[0]
+
+	stats.Total = result.Summary[0].Total
+	stats.MinPrice = result.Summary[0].MinPrice
+	stats.MaxPrice = result.Summary[0].MaxPrice
+	stats.AvgPrice = result.Summary[0].AvgPrice
+	stats.MostChanged = result.MostChanged
+	if len(result.ChangedRecently) > 0 {
+		stats.ChangedRecently = result.ChangedRecently[0].Count
+	}
+
+	stats.Histogram, err = decodeHistogram(filter, result)
+	if err != nil {
+		m.logger.FromContext(ctx).Errorf("Stats histogram decode error: %s", err)
+		return domain.Stats{}, err
+	}
+
+	stats.MedianPrice, err = m.medianPrice(ctx, match, stats.Total)
+	if err != nil {
+		return domain.Stats{}, err
+	}
+
+	return stats, nil
+}
+
+// medianPrice averages the one or two middle prices, so it stays an exact decimal.
+func (m *MongoBackend) medianPrice(ctx context.Context, match bson.D, total int64) (primitive.Decimal128, error) {
+	skip, limit := (total-1)/2, int64(1)
+	if total%2 == 0 {
+		limit = 2
+	}
+
+	pipeline := mongo.Pipeline{
+		match,
+		{{Key: "$sort", Value: bson.D{{Key: "price", Value: 1}}}},
+		{{Key: "$skip", Value: skip}},
+		{{Key: "$limit", Value: limit}},
+		{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: nil},
+			{Key: "median", Value: bson.D{{Key: "$avg", Value: "$price"}}},
+		}}},
+	}
+
+	cursor, err := m.db.Collection(viper.GetString("mongo.collection")).Aggregate(ctx, pipeline)
+	if err != nil {
+		m.logger.FromContext(ctx).Errorf("Median aggregation error: %s", err)
+		return primitive.Decimal128{}, err
+	}
+	defer cursor.Close(ctx)
+
+	var result []struct {
+		Median primitive.Decimal128 `bson:"median"`
+	}
+	if err := cursor.All(ctx, &result); err != nil {
+		m.logger.FromContext(ctx).Errorf("Median decode error: %s", err)
+		return primitive.Decimal128{}, err
+	}
+	if len(result) == 0 {
+		return primitive.Decimal128{}, nil
+	}
+	return result[0].Median, nil
+}
+
+func statsMatch(filter domain.StatsFilter) bson.D {
+	match := bson.D{{Key: "deleted_at", Value: nil}}
+	if filter.Name != "" {
+		match = append(match, bson.E{Key: "name", Value: primitive.Regex{
+			Pattern: regexp.QuoteMeta(filter.Name),
+			Options: "i",
+		}})
+	}
+
+	price := bson.D{}
+	if filter.MinPrice != nil {
+		price = append(price, bson.E{Key: "$gte", Value: *filter.MinPrice})
+	}
+	if filter.MaxPrice != nil {
+		price = append(price, bson.E{Key: "$lte", Value: *filter.MaxPrice})
+	}
+	if len(price) > 0 {
+		match = append(match, bson.E{Key: "price", Value: price})
+	}
+	return match
+}
+
+// histogramStages uses $bucket when explicit bounds are given and $bucketAuto otherwise.
+// Prices outside explicit bounds are not counted.
+func histogramStages(filter domain.StatsFilter) bson.A {
+	if len(filter.HistogramBounds) < 2 {
+		return bson.A{
+			bson.D{{Key: "$bucketAuto", Value: bson.D{
+				{Key: "groupBy", Value: "$price"},
+				{Key: "buckets", Value: filter.HistogramBuckets},
+			}}},
+		}
+	}
+
+	bounds := make(bson.A, len(filter.HistogramBounds))
+	for i, bound := range filter.HistogramBounds {
+		bounds[i] = bound
+	}
+	return bson.A{
+		bson.D{{Key: "$match", Value: bson.D{{Key: "price", Value: bson.D{
+			{Key: "$gte", Value: filter.HistogramBounds[0]},
+			{Key: "$lt", Value: filter.HistogramBounds[len(filter.HistogramBounds)-1]},
+		}}}}},
+		bson.D{{Key: "$bucket", Value: bson.D{
+			{Key: "groupBy", Value: "$price"},
+			{Key: "boundaries", Value: bounds},
+		}}},
+	}
+}
+
+func decodeHistogram(filter domain.StatsFilter, result statsFacets) ([]domain.HistogramBucket, error) {
+	histogram := make([]domain.HistogramBucket, 0, len(result.Histogram))
+
+	if len(filter.HistogramBounds) >= 2 {
+		// $bucket omits empty buckets, so fill every range from the bounds and add the counts
+		counts := make(map[string]int64, len(result.Histogram))
+		for _, bucket := range result.Histogram {
+			lower, ok := bucket.Id.Decimal128OK()
+			if !ok {
+				continue
+			}
+			counts[lower.String()] = bucket.Count
+		}
+		for i := 0; i < len(filter.HistogramBounds)-1; i++ {
+			histogram = append(histogram, domain.HistogramBucket{
+				LowerBound: filter.HistogramBounds[i],
+				UpperBound: filter.HistogramBounds[i+1],
+				Count:      counts[filter.HistogramBounds[i].String()],
+			})
+		}
+		return histogram, nil
+	}
+
+	for _, bucket := range result.Histogram {
+		var bounds struct {
+			Min primitive.Decimal128 `bson:"min"`
+			Max primitive.Decimal128 `bson:"max"`
+		}
+		if err := bucket.Id.Unmarshal(&bounds); err != nil {
+			return nil, err
+		}
+		histogram = append(histogram, domain.HistogramBucket{
+			LowerBound: bounds.Min,
+			UpperBound: bounds.Max,
+			Count:      bucket.Count,
+		})
+	}
+	return histogram, nil
+}
@@ -0,0 +1,70 @@
+package repository
+
+import (
+	"gRPC-server/internal/domain"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestDecodeHistogram(t *testing.T) {
+	price := func(v string) primitive.Decimal128 {
+		got, _ := primitive.ParseDecimal128(v)
+		return got
+	}
+	rawId := func(v interface{}) bson.RawValue {
+		t, data, err := bson.MarshalValue(v)
+		if err != nil {
+			panic(err)
+		}
+		return bson.RawValue{Type: t, Value: data}
+	}
+
+	t.Run("Explicit bounds keep empty buckets", func(t *testing.T) {
+		filter := domain.StatsFilter{
+			HistogramBounds: []primitive.Decimal128{price("0"), price("10"), price("100")},
+		}
+		var result statsFacets
+		result.Histogram = append(result.Histogram, struct {
+			Id    bson.RawValue `bson:"_id"`
+			Count int64         `bson:"count"`
+		}{Id: rawId(price("10")), Count: 4})
+
+		got, err := decodeHistogram(filter, result)
+
+		assert.NoError(t, err)
+		assert.Equal(t, []domain.HistogramBucket{
+			{LowerBound: price("0"), UpperBound: price("10"), Count: 0},
+			{LowerBound: price("10"), UpperBound: price("100"), Count: 4},
+		}, got)
+	})
+
+	t.Run("Auto buckets", func(t *testing.T) {
+		var result statsFacets
+		result.Histogram = append(result.Histogram, struct {
+			Id    bson.RawValue `bson:"_id"`
+			Count int64         `bson:"count"`
+		}{Id: rawId(bson.D{{Key: "min", Value: price("1.50")}, {Key: "max", Value: price("20")}}), Count: 2})
+
+		got, err := decodeHistogram(domain.StatsFilter{HistogramBuckets: 1}, result)
+
+		assert.NoError(t, err)
+		assert.Equal(t, []domain.HistogramBucket{
+			{LowerBound: price("1.50"), UpperBound: price("20"), Count: 2},
+		}, got)
+	})
+}
+
+func TestStatsMatch(t *testing.T) {
+	min, _ := primitive.ParseDecimal128("5")
+
+	got := statsMatch(domain.StatsFilter{Name: "a.b", MinPrice: &min})
+
+	assert.Equal(t, bson.D{
+		{Key: "deleted_at", Value: nil},
+		{Key: "name", Value: primitive.Regex{Pattern: `a\.b`, Options: "i"}},
+		{Key: "price", Value: bson.D{{Key: "$gte", Value: min}}},
+	}, got)
+}
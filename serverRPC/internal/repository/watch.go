@@ -0,0 +1,337 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gRPC-server/internal/domain"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Resume tokens are opaque for clients, the prefix only tells which mode issued them.
+const (
+	changeStreamTokenPrefix = "cs:"
+	pollTokenPrefix         = "poll:"
+
+	defaultPollInterval = 2 * time.Second
+	defaultPollOverlap  = time.Minute
+)
+
+// Watch sends product changes until ctx is done or send fails. It tails a change stream when the
+// deployment has one and otherwise polls by date_of_change. Purged products are reported only by
+// a change stream with pre-images, see MigrateWatch.
+func (m *MongoBackend) Watch(ctx context.Context, resumeToken string, send func(domain.ProductEvent) error) error {
+	switch {
+	case strings.HasPrefix(resumeToken, pollTokenPrefix):
+		return m.pollChanges(ctx, resumeToken, send)
+	case !m.isReplicated(ctx):
+		if resumeToken != "" {
+			return fmt.Errorf("%w: change stream resume token on deployment without change streams", domain.ErrInvalidArgument)
+		}
+		return m.pollChanges(ctx, resumeToken, send)
+	default:
+		return m.watchChangeStream(ctx, resumeToken, send)
+	}
+}
+
+// MigrateWatch creates the index polling reads by and turns pre-images on for the products
+// collection, so a change stream reports what PurgeDeleted removed. Deployments older than Mongo 6
+// can't, there Watch keeps working without reporting purges. It is safe to run on every start.
+func (m *MongoBackend) MigrateWatch(ctx context.Context) error {
+	_, err := m.db.Collection(viper.GetString("mongo.collection")).Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "date_of_change", Value: 1}, {Key: "_id", Value: 1}},
+		Options: options.Index().SetName("date_of_change_id"),
+	})
+	if err != nil {
+		m.logger.FromContext(ctx).Errorf("Can't create date_of_change_id index: %s", err)
+		return err
+	}
+
+	err = m.db.RunCommand(ctx, bson.D{
+		{Key: "collMod", Value: viper.GetString("mongo.collection")},
+		{Key: "changeStreamPreAndPostImages", Value: bson.D{{Key: "enabled", Value: true}}},
+	}).Err()
+	if err != nil {
+		m.logger.FromContext(ctx).Warnf("Can't enable change stream pre-images, Watch won't report purged products: %s", err)
+	}
+	return nil
+}
+
+type changeEvent struct {
+	Id                 bson.Raw            `bson:"_id"`
+	OperationType      string              `bson:"operationType"`
+	ClusterTime        primitive.Timestamp `bson:"clusterTime"`
+	FullDocument       *domain.Product     `bson:"fullDocument"`
+	FullDocumentBefore *domain.Product     `bson:"fullDocumentBeforeChange"`
+	UpdateDescription  struct {
+		UpdatedFields bson.Raw `bson:"updatedFields"`
+	} `bson:"updateDescription"`
+}
+
+func (m *MongoBackend) watchChangeStream(ctx context.Context, resumeToken string, send func(domain.ProductEvent) error) error {
+	opts := options.ChangeStream().
+		SetFullDocument(options.UpdateLookup).
+		SetFullDocumentBeforeChange(options.WhenAvailable)
+	if resumeToken != "" {
+		if !strings.HasPrefix(resumeToken, changeStreamTokenPrefix) {
+			return fmt.Errorf("%w: unknown resume token", domain.ErrInvalidArgument)
+		}
+		opts.SetResumeAfter(bson.D{{Key: "_data", Value: strings.TrimPrefix(resumeToken, changeStreamTokenPrefix)}})
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.D{{Key: "operationType", Value: bson.D{
+			{Key: "$in", Value: bson.A{"insert", "update", "replace", "delete"}},
+		}}}}},
+	}
+
+	stream, err := m.db.Collection(viper.GetString("mongo.collection")).Watch(ctx, pipeline, opts)
+	if err != nil {
+		m.logger.FromContext(ctx).Errorf("Can't open change stream: %s", err)
+		return err
+	}
+	defer stream.Close(context.Background())
+
+	for stream.Next(ctx) {
+		var change changeEvent
+		if err := stream.Decode(&change); err != nil {
+			m.logger.FromContext(ctx).Errorf("Change stream decode error: %s", err)
+			return err
+		}
+
+		event, ok := toProductEvent(change)
+		if !ok {
+			continue
+		}
+		data, _ := change.Id.Lookup("_data").StringValueOK()
+		event.ResumeToken = changeStreamTokenPrefix + data
+
+		if err := send(event); err != nil {
+			return err
+		}
+	}
+
+	if err := stream.Err(); err != nil && !errors.Is(err, context.Canceled) {
+		m.logger.FromContext(ctx).Errorf("Change stream error: %s", err)
+		return err
+	}
+	return ctx.Err()
+}
+
+func toProductEvent(change changeEvent) (domain.ProductEvent, bool) {
+	event := domain.ProductEvent{
+		Time: time.Unix(int64(change.ClusterTime.T), 0),
+	}
+
+	switch change.OperationType {
+	case "insert":
+		event.Type = domain.EventInsert
+	case "update", "replace":
+		event.Type = domain.EventUpdate
+		// soft delete is an update that sets deleted_at
+		if _, err := change.UpdateDescription.UpdatedFields.LookupErr("deleted_at"); err == nil {
+			event.Type = domain.EventDelete
+		}
+	case "delete":
+		event.Type = domain.EventDelete
+	default:
+		return domain.ProductEvent{}, false
+	}
+
+	switch {
+	case change.FullDocument != nil:
+		event.Product = *change.FullDocument
+	case change.FullDocumentBefore != nil:
+		event.Product = *change.FullDocumentBefore
+	default:
+		// a purge without a pre-image only has the _id, which means nothing to clients; the
+		// product was already reported as deleted when it was soft-deleted
+		return domain.ProductEvent{}, false
+	}
+	return event, true
+}
+
+type polledProduct struct {
+	ObjectId       primitive.ObjectID `bson:"_id"`
+	domain.Product `bson:",inline"`
+	ChangesCount   int        `bson:"changes_count"`
+	RestoredAt     *time.Time `bson:"restored_at"`
+}
+
+// eventType tells the change from the document alone: a product that was never updated nor
+// restored can only have been inserted.
+func (p polledProduct) eventType() string {
+	switch {
+	case p.DeletedAt != nil:
+		return domain.EventDelete
+	case p.ChangesCount == 0 && p.RestoredAt == nil:
+		return domain.EventInsert
+	default:
+		return domain.EventUpdate
+	}
+}
+
+// pollCursor remembers how far polling got. date_of_change is set before the write commits, so a
+// slow transaction shows up behind products already sent: every poll re-reads the last overlap
+// and drops the versions it has sent before.
+type pollCursor struct {
+	lastTime time.Time
+	lastId   primitive.ObjectID
+	overlap  time.Duration
+	// floor keeps a fresh Watch from replaying changes made before it started
+	floor time.Time
+	sent  map[primitive.ObjectID]time.Time
+}
+
+func newPollCursor(lastTime time.Time, lastId primitive.ObjectID, floor time.Time, overlap time.Duration) *pollCursor {
+	return &pollCursor{
+		lastTime: lastTime,
+		lastId:   lastId,
+		overlap:  overlap,
+		floor:    floor,
+		sent:     make(map[primitive.ObjectID]time.Time),
+	}
+}
+
+// since is where the next poll starts reading.
+func (c *pollCursor) since() time.Time {
+	since := c.lastTime.Add(-c.overlap)
+	if since.Before(c.floor) {
+		return c.floor
+	}
+	return since
+}
+
+// next tells whether the product's version hasn't been sent yet and moves the position forward;
+// it never moves back, so a late product gets the token of the newest one already sent.
+func (c *pollCursor) next(product polledProduct) bool {
+	if sent, ok := c.sent[product.ObjectId]; ok && sent.Equal(product.DateOfChange) {
+		return false
+	}
+	c.sent[product.ObjectId] = product.DateOfChange
+
+	if product.DateOfChange.After(c.lastTime) ||
+		(product.DateOfChange.Equal(c.lastTime) && product.ObjectId.Hex() > c.lastId.Hex()) {
+		c.lastTime, c.lastId = product.DateOfChange, product.ObjectId
+	}
+	return true
+}
+
+// prune forgets products the next poll won't read again.
+func (c *pollCursor) prune() {
+	since := c.since()
+	for id, at := range c.sent {
+		if at.Before(since) {
+			delete(c.sent, id)
+		}
+	}
+}
+
+func (c *pollCursor) token() string {
+	return pollToken(c.lastTime, c.lastId)
+}
+
+// pollChanges pages through products ordered by (date_of_change, _id) from the position stored
+// in the token minus watch.poll_overlap; without a token it starts from now. A resumed Watch may
+// repeat events from the overlap, and a write committing later than the overlap is missed.
+func (m *MongoBackend) pollChanges(ctx context.Context, resumeToken string, send func(domain.ProductEvent) error) error {
+	lastTime, lastId, err := parsePollToken(resumeToken)
+	if err != nil {
+		return err
+	}
+	var floor time.Time
+	if resumeToken == "" {
+		lastTime = time.Now()
+		floor = lastTime
+	}
+
+	interval := viper.GetDuration("watch.poll_interval")
+	if interval <= 0 {
+		interval = defaultPollInterval
+	}
+	overlap := viper.GetDuration("watch.poll_overlap")
+	if overlap <= 0 {
+		overlap = defaultPollOverlap
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	collection := m.db.Collection(viper.GetString("mongo.collection"))
+	opts := options.Find().SetSort(bson.D{{Key: "date_of_change", Value: 1}, {Key: "_id", Value: 1}})
+	position := newPollCursor(lastTime, lastId, floor, overlap)
+
+	for {
+		filter := bson.D{{Key: "date_of_change", Value: bson.D{{Key: "$gte", Value: position.since()}}}}
+
+		cursor, err := collection.Find(ctx, filter, opts)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			m.logger.FromContext(ctx).Errorf("Poll changes find error: %s", err)
+			return err
+		}
+
+		var changed []polledProduct
+		err = cursor.All(ctx, &changed)
+		cursor.Close(context.Background())
+		if err != nil {
+			m.logger.FromContext(ctx).Errorf("Poll changes decode error: %s", err)
+			return err
+		}
+
+		for _, product := range changed {
+			if !position.next(product) {
+				continue
+			}
+			event := domain.ProductEvent{
+				Type:        product.eventType(),
+				Product:     product.Product,
+				Time:        product.DateOfChange,
+				ResumeToken: position.token(),
+			}
+			if err := send(event); err != nil {
+				return err
+			}
+		}
+		position.prune()
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+func pollToken(lastTime time.Time, lastId primitive.ObjectID) string {
+	return pollTokenPrefix + strconv.FormatInt(lastTime.UnixMilli(), 10) + ":" + lastId.Hex()
+}
+
+func parsePollToken(token string) (time.Time, primitive.ObjectID, error) {
+	if token == "" {
+		return time.Time{}, primitive.NilObjectID, nil
+	}
+
+	parts := strings.SplitN(strings.TrimPrefix(token, pollTokenPrefix), ":", 2)
+	if len(parts) != 2 {
+		return time.Time{}, primitive.NilObjectID, fmt.Errorf("%w: malformed resume token", domain.ErrInvalidArgument)
+	}
+	millis, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return time.Time{}, primitive.NilObjectID, fmt.Errorf("%w: malformed resume token", domain.ErrInvalidArgument)
+	}
+	id, err := primitive.ObjectIDFromHex(parts[1])
+	if err != nil {
+		return time.Time{}, primitive.NilObjectID, fmt.Errorf("%w: malformed resume token", domain.ErrInvalidArgument)
+	}
+	return time.UnixMilli(millis), id, nil
+}
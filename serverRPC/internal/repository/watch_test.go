@@ -0,0 +1,148 @@
+package repository
+
+import (
+	"gRPC-server/internal/domain"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestPollToken(t *testing.T) {
+	lastTime := time.UnixMilli(1735689600123)
+	lastId := primitive.NewObjectID()
+
+	gotTime, gotId, err := parsePollToken(pollToken(lastTime, lastId))
+
+	assert.NoError(t, err)
+	assert.True(t, lastTime.Equal(gotTime))
+	assert.Equal(t, lastId, gotId)
+
+	for _, token := range []string{"poll:abc", "poll:123", "poll:123:zz"} {
+		_, _, err := parsePollToken(token)
+		assert.ErrorIs(t, err, domain.ErrInvalidArgument, token)
+	}
+}
+
+func TestToProductEvent(t *testing.T) {
+	marshal := func(v interface{}) bson.Raw {
+		data, err := bson.Marshal(v)
+		if err != nil {
+			panic(err)
+		}
+		return data
+	}
+	product := &domain.Product{Id: 1, Name: "name"}
+
+	testTables := []struct {
+		name   string
+		change changeEvent
+		want   string
+		ok     bool
+	}{
+		{
+			name:   "Insert",
+			change: changeEvent{OperationType: "insert", FullDocument: product},
+			want:   domain.EventInsert,
+			ok:     true,
+		},
+		{
+			name: "Update",
+			change: func() changeEvent {
+				change := changeEvent{OperationType: "update", FullDocument: product}
+				change.UpdateDescription.UpdatedFields = marshal(bson.D{{Key: "price", Value: 1}})
+				return change
+			}(),
+			want: domain.EventUpdate,
+			ok:   true,
+		},
+		{
+			name: "Soft delete",
+			change: func() changeEvent {
+				change := changeEvent{OperationType: "update", FullDocument: product}
+				change.UpdateDescription.UpdatedFields = marshal(bson.D{{Key: "deleted_at", Value: time.Now()}})
+				return change
+			}(),
+			want: domain.EventDelete,
+			ok:   true,
+		},
+		{
+			name:   "Purge",
+			change: changeEvent{OperationType: "delete", FullDocumentBefore: product},
+			want:   domain.EventDelete,
+			ok:     true,
+		},
+		{
+			name:   "Purge without pre-image",
+			change: changeEvent{OperationType: "delete"},
+			ok:     false,
+		},
+		{
+			name:   "Drop",
+			change: changeEvent{OperationType: "drop"},
+			ok:     false,
+		},
+	}
+
+	for _, table := range testTables {
+		t.Run(table.name, func(t *testing.T) {
+			got, ok := toProductEvent(table.change)
+
+			assert.Equal(t, table.ok, ok)
+			if table.ok {
+				assert.Equal(t, table.want, got.Type)
+				assert.Equal(t, *product, got.Product)
+			}
+		})
+	}
+}
+
+func TestPolledEventType(t *testing.T) {
+	now := time.Now()
+
+	assert.Equal(t, domain.EventInsert, polledProduct{}.eventType())
+	assert.Equal(t, domain.EventUpdate, polledProduct{ChangesCount: 1}.eventType())
+	assert.Equal(t, domain.EventUpdate, polledProduct{RestoredAt: &now}.eventType())
+	assert.Equal(t, domain.EventDelete, polledProduct{Product: domain.Product{DeletedAt: &now}, RestoredAt: &now}.eventType())
+}
+
+func TestPollCursor(t *testing.T) {
+	start := time.UnixMilli(1735689600000)
+	product := func(id primitive.ObjectID, at time.Time) polledProduct {
+		return polledProduct{ObjectId: id, Product: domain.Product{DateOfChange: at}}
+	}
+	first, late := primitive.NewObjectID(), primitive.NewObjectID()
+
+	t.Run("Fresh start", func(t *testing.T) {
+		position := newPollCursor(start, primitive.NilObjectID, start, time.Minute)
+
+		assert.True(t, start.Equal(position.since()))
+	})
+
+	t.Run("Late commit", func(t *testing.T) {
+		position := newPollCursor(start, primitive.NilObjectID, start, time.Minute)
+
+		assert.True(t, position.next(product(first, start.Add(10*time.Second))))
+		assert.True(t, position.since().Equal(start), "floor")
+		// the next poll reads the first product again along with one committed after it
+		assert.False(t, position.next(product(first, start.Add(10*time.Second))))
+		assert.True(t, position.next(product(late, start.Add(5*time.Second))))
+		assert.Equal(t, pollToken(start.Add(10*time.Second), first), position.token())
+		// a newer version of a sent product is a new event
+		assert.True(t, position.next(product(first, start.Add(20*time.Second))))
+	})
+
+	t.Run("Prune", func(t *testing.T) {
+		position := newPollCursor(start, primitive.NilObjectID, time.Time{}, time.Minute)
+
+		position.next(product(first, start))
+		position.next(product(late, start.Add(2*time.Minute)))
+		position.prune()
+
+		assert.True(t, start.Add(time.Minute).Equal(position.since()))
+		assert.NotContains(t, position.sent, first)
+		assert.Contains(t, position.sent, late)
+	})
+}
@@ -0,0 +1,200 @@
+package rules
+
+import (
+	"fmt"
+	"gRPC-server/internal/domain"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/shopspring/decimal"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	CollapseSpaces = "collapse_spaces"
+	NameCase       = "name_case"
+	RoundPrice     = "round_price"
+	PositivePrice  = "positive_price"
+	MaxNameLength  = "max_name_length"
+)
+
+// Rule normalizes a product in place or rejects it with a reason.
+type Rule interface {
+	Name() string
+	Apply(product *domain.Product) (reason string, ok bool)
+}
+
+// Config is one entry of a rules list in config, only the fields of its rule are used.
+type Config struct {
+	Rule     string `mapstructure:"rule"`
+	Name     string `mapstructure:"name"` // имя в отчёте, по умолчанию rule
+	Case     string `mapstructure:"case"`
+	Places   int32  `mapstructure:"places"`
+	Max      int    `mapstructure:"max"`
+	Truncate bool   `mapstructure:"truncate"`
+}
+
+// Pipeline applies rules in order, the first rejection stops it.
+type Pipeline []Rule
+
+// Apply returns the normalized product or the rejection of the rule that refused it.
+func (p Pipeline) Apply(product domain.Product) (domain.Product, *domain.Rejection) {
+	for _, rule := range p {
+		if reason, ok := rule.Apply(&product); !ok {
+			return domain.Product{}, &domain.Rejection{
+				Id:     product.Id,
+				Name:   product.Name,
+				Rule:   rule.Name(),
+				Reason: reason,
+			}
+		}
+	}
+	return product, nil
+}
+
+func Build(configs []Config) (Pipeline, error) {
+	pipeline := make(Pipeline, 0, len(configs))
+	for _, c := range configs {
+		name := c.Name
+		if name == "" {
+			name = c.Rule
+		}
+
+		var rule Rule
+		switch c.Rule {
+		case CollapseSpaces:
+			rule = collapseSpaces{name: name}
+		case NameCase:
+			switch c.Case {
+			case "lower", "upper", "title", "sentence":
+			default:
+				return nil, fmt.Errorf("rule %s: case must be lower, upper, title or sentence, got %q", name, c.Case)
+			}
+			rule = nameCase{name: name, mode: c.Case}
+		case RoundPrice:
+			if c.Places < 0 {
+				return nil, fmt.Errorf("rule %s: places must not be negative", name)
+			}
+			rule = roundPrice{name: name, places: c.Places}
+		case PositivePrice:
+			rule = positivePrice{name: name}
+		case MaxNameLength:
+			if c.Max <= 0 {
+				return nil, fmt.Errorf("rule %s: max must be positive", name)
+			}
+			rule = maxNameLength{name: name, max: c.Max, truncate: c.Truncate}
+		default:
+			return nil, fmt.Errorf("unknown rule %q", c.Rule)
+		}
+		pipeline = append(pipeline, rule)
+	}
+	return pipeline, nil
+}
+
+// collapseSpaces trims the name and turns every run of whitespace into a single space.
+type collapseSpaces struct {
+	name string
+}
+
+func (r collapseSpaces) Name() string { return r.name }
+
+func (r collapseSpaces) Apply(product *domain.Product) (string, bool) {
+	product.Name = strings.Join(strings.Fields(product.Name), " ")
+	if product.Name == "" {
+		return "name is empty", false
+	}
+	return "", true
+}
+
+type nameCase struct {
+	name string
+	mode string
+}
+
+func (r nameCase) Name() string { return r.name }
+
+func (r nameCase) Apply(product *domain.Product) (string, bool) {
+	switch r.mode {
+	case "lower":
+		product.Name = strings.ToLower(product.Name)
+	case "upper":
+		product.Name = strings.ToUpper(product.Name)
+	case "title":
+		words := strings.Split(strings.ToLower(product.Name), " ")
+		for i, word := range words {
+			words[i] = upperFirst(word)
+		}
+		product.Name = strings.Join(words, " ")
+	case "sentence":
+		product.Name = upperFirst(strings.ToLower(product.Name))
+	}
+	return "", true
+}
+
+func upperFirst(s string) string {
+	r, size := utf8.DecodeRuneInString(s)
+	if size == 0 {
+		return s
+	}
+	return string(unicode.ToUpper(r)) + s[size:]
+}
+
+// roundPrice rounds half away from zero, prices that aren't numbers are rejected.
+type roundPrice struct {
+	name   string
+	places int32
+}
+
+func (r roundPrice) Name() string { return r.name }
+
+func (r roundPrice) Apply(product *domain.Product) (string, bool) {
+	price, err := decimal.NewFromString(product.Price.String())
+	if err != nil {
+		return fmt.Sprintf("price %s is not a number", product.Price), false
+	}
+	rounded, err := primitive.ParseDecimal128(price.StringFixed(r.places))
+	if err != nil {
+		return fmt.Sprintf("price %s can't be stored: %s", product.Price, err), false
+	}
+	product.Price = rounded
+	return "", true
+}
+
+type positivePrice struct {
+	name string
+}
+
+func (r positivePrice) Name() string { return r.name }
+
+func (r positivePrice) Apply(product *domain.Product) (string, bool) {
+	price, err := decimal.NewFromString(product.Price.String())
+	if err != nil {
+		return fmt.Sprintf("price %s is not a number", product.Price), false
+	}
+	if !price.IsPositive() {
+		return fmt.Sprintf("price %s must be greater than zero", product.Price), false
+	}
+	return "", true
+}
+
+// maxNameLength counts characters, not bytes, and either cuts the name or rejects it.
+type maxNameLength struct {
+	name     string
+	max      int
+	truncate bool
+}
+
+func (r maxNameLength) Name() string { return r.name }
+
+func (r maxNameLength) Apply(product *domain.Product) (string, bool) {
+	length := utf8.RuneCountInString(product.Name)
+	if length <= r.max {
+		return "", true
+	}
+	if !r.truncate {
+		return fmt.Sprintf("name is %d characters long, at most %d allowed", length, r.max), false
+	}
+	product.Name = strings.TrimSpace(string([]rune(product.Name)[:r.max]))
+	return "", true
+}
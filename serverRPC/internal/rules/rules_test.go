@@ -0,0 +1,119 @@
+package rules
+
+import (
+	"gRPC-server/internal/domain"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func price(v string) primitive.Decimal128 {
+	got, _ := primitive.ParseDecimal128(v)
+	return got
+}
+
+func TestPipeline(t *testing.T) {
+	pipeline, err := Build([]Config{
+		{Rule: CollapseSpaces},
+		{Rule: NameCase, Case: "title"},
+		{Rule: RoundPrice, Places: 2},
+		{Rule: PositivePrice, Name: "no_free_products"},
+		{Rule: MaxNameLength, Max: 10},
+	})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	testTables := []struct {
+		name    string
+		product domain.Product
+		want    domain.Product
+		rule    string
+	}{
+		{
+			name:    "Normalized",
+			product: domain.Product{Id: 1, Name: "  green   TEA\t", Price: price("10.005")},
+			want:    domain.Product{Id: 1, Name: "Green Tea", Price: price("10.01")},
+		},
+		{
+			name:    "Rounded to places",
+			product: domain.Product{Id: 2, Name: "tea", Price: price("7")},
+			want:    domain.Product{Id: 2, Name: "Tea", Price: price("7.00")},
+		},
+		{
+			name:    "Zero price",
+			product: domain.Product{Id: 3, Name: "tea", Price: price("0.001")},
+			rule:    "no_free_products",
+		},
+		{
+			name:    "Negative price",
+			product: domain.Product{Id: 4, Name: "tea", Price: price("-5")},
+			rule:    "no_free_products",
+		},
+		{
+			name:    "Empty name",
+			product: domain.Product{Id: 5, Name: "   ", Price: price("5")},
+			rule:    CollapseSpaces,
+		},
+		{
+			name:    "Long name",
+			product: domain.Product{Id: 6, Name: "черный чай с бергамотом", Price: price("5")},
+			rule:    MaxNameLength,
+		},
+	}
+
+	for _, table := range testTables {
+		t.Run(table.name, func(t *testing.T) {
+			got, rejection := pipeline.Apply(table.product)
+			if table.rule != "" {
+				if assert.NotNil(t, rejection) {
+					assert.Equal(t, table.rule, rejection.Rule)
+					assert.Equal(t, table.product.Id, rejection.Id)
+					assert.NotEmpty(t, rejection.Reason)
+				}
+				return
+			}
+			assert.Nil(t, rejection)
+			assert.Equal(t, table.want, got)
+		})
+	}
+}
+
+func TestMaxNameLengthTruncate(t *testing.T) {
+	pipeline, err := Build([]Config{{Rule: MaxNameLength, Max: 6, Truncate: true}})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	got, rejection := pipeline.Apply(domain.Product{Name: "черный чай"})
+	assert.Nil(t, rejection)
+	assert.Equal(t, "черный", got.Name)
+}
+
+func TestNameCase(t *testing.T) {
+	for mode, want := range map[string]string{
+		"lower":    "зелёный чай tea",
+		"upper":    "ЗЕЛЁНЫЙ ЧАЙ TEA",
+		"title":    "Зелёный Чай Tea",
+		"sentence": "Зелёный чай tea",
+	} {
+		pipeline, err := Build([]Config{{Rule: NameCase, Case: mode}})
+		if assert.NoError(t, err) {
+			got, _ := pipeline.Apply(domain.Product{Name: "зелёный ЧАЙ tea"})
+			assert.Equal(t, want, got.Name, mode)
+		}
+	}
+}
+
+func TestBuildErrors(t *testing.T) {
+	for _, config := range []Config{
+		{Rule: "uppercase"},
+		{Rule: NameCase, Case: "camel"},
+		{Rule: RoundPrice, Places: -1},
+		{Rule: MaxNameLength},
+	} {
+		_, err := Build([]Config{config})
+		assert.Error(t, err, config.Rule)
+	}
+}
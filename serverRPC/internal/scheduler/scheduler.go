@@ -0,0 +1,259 @@
+package scheduler
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"gRPC-server/internal/domain"
+	"gRPC-server/internal/metrics"
+	"gRPC-server/pkg/logger"
+	mathrand "math/rand/v2"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	"github.com/spf13/viper"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const leaseName = "scheduler"
+
+const (
+	MissedRunOnce = "run_once"
+	MissedSkip    = "skip"
+)
+
+// Store is the part of the storage the scheduler needs.
+type Store interface {
+	DueSchedules(ctx context.Context, now time.Time) ([]domain.Schedule, error)
+	ClaimScheduleRun(ctx context.Context, id primitive.ObjectID, scheduled, next time.Time) (bool, error)
+	FinishScheduleRun(ctx context.Context, id primitive.ObjectID, at time.Time, status, runErr string) error
+	AcquireLease(ctx context.Context, name, holder string, ttl time.Duration) (bool, error)
+	ReleaseLease(ctx context.Context, name, holder string) error
+}
+
+type Importer interface {
+	Import(ctx context.Context, source domain.Source) (domain.Status, error)
+}
+
+var parser = cron.NewParser(cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor)
+
+// ParseCron accepts standard 5 field expressions and descriptors like @hourly or @every 10m.
+func ParseCron(spec string) (cron.Schedule, error) {
+	return parser.Parse(spec)
+}
+
+// NextRun is the first time after `after` that matches spec, pushed back by a random
+// delay of up to jitter so replicas of a source aren't all hit on the same second.
+func NextRun(spec string, after time.Time, jitter time.Duration) (time.Time, error) {
+	schedule, err := ParseCron(spec)
+	if err != nil {
+		return time.Time{}, err
+	}
+	next := schedule.Next(after)
+	if jitter > 0 {
+		next = next.Add(mathrand.N(jitter))
+	}
+	// Mongo keeps milliseconds, a claim compares next_run with what was read back
+	return next.Truncate(time.Millisecond), nil
+}
+
+// Scheduler runs due schedules on the replica holding the scheduler lease.
+type Scheduler struct {
+	store    Store
+	importer Importer
+	logger   *logger.Logger
+	holder   string
+
+	interval    time.Duration
+	leaseTTL    time.Duration
+	jitter      time.Duration
+	missedRuns  string
+	missedGrace time.Duration
+
+	leader  bool
+	running sync.WaitGroup
+	cancel  context.CancelFunc
+	done    chan struct{}
+	// runs is the context of started imports, stopRuns cancels them when Shutdown runs out of time
+	runs     context.Context
+	stopRuns context.CancelFunc
+}
+
+// New returns nil when scheduler.enabled is off.
+func New(store Store, importer Importer, logger *logger.Logger) *Scheduler {
+	if !viper.GetBool("scheduler.enabled") {
+		return nil
+	}
+
+	s := &Scheduler{
+		store:       store,
+		importer:    importer,
+		logger:      logger,
+		holder:      holderID(),
+		interval:    viper.GetDuration("scheduler.interval"),
+		leaseTTL:    viper.GetDuration("scheduler.lease_ttl"),
+		jitter:      viper.GetDuration("scheduler.jitter"),
+		missedRuns:  viper.GetString("scheduler.missed_runs"),
+		missedGrace: viper.GetDuration("scheduler.missed_grace"),
+	}
+	if s.interval <= 0 {
+		s.interval = time.Second
+	}
+	if s.leaseTTL <= s.interval {
+		s.leaseTTL = 15 * s.interval
+	}
+	if s.missedRuns != MissedSkip {
+		s.missedRuns = MissedRunOnce
+	}
+	if s.missedGrace <= 0 {
+		s.missedGrace = time.Minute
+	}
+	s.runs, s.stopRuns = context.WithCancel(context.Background())
+	return s
+}
+
+// holderID tells replicas apart in the lease, the hostname is the pod name in Kubernetes.
+func holderID() string {
+	host, _ := os.Hostname()
+	b := make([]byte, 4)
+	rand.Read(b)
+	return fmt.Sprintf("%s-%s", host, hex.EncodeToString(b))
+}
+
+func (s *Scheduler) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+	s.done = make(chan struct{})
+
+	go func() {
+		defer close(s.done)
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+
+		for {
+			s.tick(ctx, time.Now())
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Shutdown stops starting new runs, waits for running ones and gives the lease up
+// so another replica takes over without waiting for it to expire. Runs still going when ctx
+// expires are cancelled; an import nobody else waits for is cancelled too, and Shutdown
+// returns after it has rolled back and the run is recorded as failed.
+func (s *Scheduler) Shutdown(ctx context.Context) error {
+	s.cancel()
+	<-s.done
+	defer s.stopRuns()
+
+	finished := make(chan struct{})
+	go func() {
+		s.running.Wait()
+		close(finished)
+	}()
+	select {
+	case <-finished:
+	case <-ctx.Done():
+		// cancelled imports return once rolled back, nothing is left writing after Shutdown
+		s.stopRuns()
+		<-finished
+		return ctx.Err()
+	}
+
+	if s.leader {
+		return s.store.ReleaseLease(ctx, leaseName, s.holder)
+	}
+	return nil
+}
+
+func (s *Scheduler) tick(ctx context.Context, now time.Time) {
+	leader, err := s.store.AcquireLease(ctx, leaseName, s.holder, s.leaseTTL)
+	if err != nil {
+		if ctx.Err() == nil {
+			s.logger.Errorf("Can't acquire scheduler lease: %s", err)
+		}
+		leader = false
+	}
+	if leader != s.leader {
+		s.logger.Infof("Scheduler leadership changed: leader=%t holder=%s", leader, s.holder)
+		s.leader = leader
+		metrics.SchedulerLeader.Set(boolToFloat(leader))
+	}
+	if !leader {
+		return
+	}
+
+	due, err := s.store.DueSchedules(ctx, now)
+	if err != nil {
+		s.logger.Errorf("Can't load due schedules: %s", err)
+		return
+	}
+
+	for _, schedule := range due {
+		s.dispatch(ctx, schedule, now)
+	}
+}
+
+func (s *Scheduler) dispatch(ctx context.Context, schedule domain.Schedule, now time.Time) {
+	log := s.logger.WithField("schedule", schedule.Id.Hex())
+
+	next, err := NextRun(schedule.Cron, now, s.jitter)
+	if err != nil {
+		log.Errorf("Invalid cron %q: %s", schedule.Cron, err)
+		return
+	}
+	claimed, err := s.store.ClaimScheduleRun(ctx, schedule.Id, schedule.NextRun, next)
+	if err != nil {
+		log.Errorf("Can't claim schedule run: %s", err)
+		return
+	}
+	if !claimed {
+		return
+	}
+
+	// however many runs were missed while no replica was leading, the source is fetched at most once
+	if now.Sub(schedule.NextRun) > s.missedGrace && s.missedRuns == MissedSkip {
+		metrics.ScheduledRuns.WithLabelValues("skipped").Inc()
+		s.finish(ctx, schedule, now, "Skipped", fmt.Sprintf("missed run at %s", schedule.NextRun.Format(time.RFC3339)))
+		return
+	}
+
+	s.running.Add(1)
+	go func() {
+		defer s.running.Done()
+
+		status, err := s.importer.Import(s.runs, domain.Source{
+			Name:   schedule.Source,
+			Url:    schedule.Url,
+			Format: schedule.Format,
+		})
+		if err != nil {
+			log.Errorf("Scheduled fetch of %s failed: %s", schedule.Url, err)
+			metrics.ScheduledRuns.WithLabelValues("failed").Inc()
+			s.finish(ctx, schedule, time.Now(), "Fail", err.Error())
+			return
+		}
+		metrics.ScheduledRuns.WithLabelValues("succeeded").Inc()
+		s.finish(ctx, schedule, time.Now(), status.Status, "")
+	}()
+}
+
+func (s *Scheduler) finish(ctx context.Context, schedule domain.Schedule, at time.Time, status, runErr string) {
+	if err := s.store.FinishScheduleRun(context.WithoutCancel(ctx), schedule.Id, at, status, runErr); err != nil {
+		s.logger.WithField("schedule", schedule.Id.Hex()).Errorf("Can't save schedule run: %s", err)
+	}
+}
+
+func boolToFloat(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
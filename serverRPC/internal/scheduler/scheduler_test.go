@@ -0,0 +1,190 @@
+package scheduler
+
+import (
+	"context"
+	"gRPC-server/internal/domain"
+	"gRPC-server/pkg/logger"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type run struct {
+	status string
+	err    string
+}
+
+type fakeStore struct {
+	mu        sync.Mutex
+	leader    bool
+	released  bool
+	schedules []domain.Schedule
+	claimed   map[primitive.ObjectID]time.Time
+	finished  map[primitive.ObjectID]run
+}
+
+func newFakeStore(leader bool, schedules ...domain.Schedule) *fakeStore {
+	return &fakeStore{
+		leader:    leader,
+		schedules: schedules,
+		claimed:   map[primitive.ObjectID]time.Time{},
+		finished:  map[primitive.ObjectID]run{},
+	}
+}
+
+func (f *fakeStore) DueSchedules(ctx context.Context, now time.Time) ([]domain.Schedule, error) {
+	return f.schedules, nil
+}
+
+func (f *fakeStore) ClaimScheduleRun(ctx context.Context, id primitive.ObjectID, scheduled, next time.Time) (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, ok := f.claimed[id]; ok {
+		return false, nil
+	}
+	f.claimed[id] = next
+	return true, nil
+}
+
+func (f *fakeStore) FinishScheduleRun(ctx context.Context, id primitive.ObjectID, at time.Time, status, runErr string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.finished[id] = run{status: status, err: runErr}
+	return nil
+}
+
+func (f *fakeStore) AcquireLease(ctx context.Context, name, holder string, ttl time.Duration) (bool, error) {
+	return f.leader, nil
+}
+
+func (f *fakeStore) ReleaseLease(ctx context.Context, name, holder string) error {
+	f.released = true
+	return nil
+}
+
+type fakeImporter struct {
+	mu   sync.Mutex
+	urls []string
+}
+
+func (f *fakeImporter) Import(ctx context.Context, source domain.Source) (domain.Status, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.urls = append(f.urls, source.Url)
+	return domain.Status{Status: "Success"}, nil
+}
+
+func newTestScheduler(store Store, importer Importer, missedRuns string) *Scheduler {
+	s := &Scheduler{
+		store:       store,
+		importer:    importer,
+		logger:      logger.GetLogger(),
+		holder:      "test",
+		interval:    time.Second,
+		leaseTTL:    time.Minute,
+		missedRuns:  missedRuns,
+		missedGrace: time.Minute,
+	}
+	s.runs, s.stopRuns = context.WithCancel(context.Background())
+	return s
+}
+
+func TestNextRun(t *testing.T) {
+	after := time.Date(2025, 1, 1, 10, 7, 0, 0, time.UTC)
+
+	got, err := NextRun("*/15 * * * *", after, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2025, 1, 1, 10, 15, 0, 0, time.UTC), got)
+
+	for i := 0; i < 100; i++ {
+		got, err := NextRun("@hourly", after, 30*time.Second)
+		assert.NoError(t, err)
+		assert.False(t, got.Before(time.Date(2025, 1, 1, 11, 0, 0, 0, time.UTC)))
+		assert.True(t, got.Before(time.Date(2025, 1, 1, 11, 0, 30, 0, time.UTC)))
+	}
+
+	_, err = NextRun("every minute", after, 0)
+	assert.Error(t, err)
+}
+
+func TestTick(t *testing.T) {
+	now := time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC)
+	onTime := domain.Schedule{Id: primitive.NewObjectID(), Url: "http://a", Cron: "@hourly", NextRun: now.Add(-time.Second)}
+	missed := domain.Schedule{Id: primitive.NewObjectID(), Url: "http://b", Cron: "@hourly", NextRun: now.Add(-3 * time.Hour)}
+
+	testTables := []struct {
+		name       string
+		leader     bool
+		missedRuns string
+		wantUrls   []string
+		wantMissed string
+	}{
+		{
+			name:       "Follower does nothing",
+			leader:     false,
+			missedRuns: MissedRunOnce,
+		},
+		{
+			name:       "Missed run once",
+			leader:     true,
+			missedRuns: MissedRunOnce,
+			wantUrls:   []string{"http://a", "http://b"},
+			wantMissed: "Success",
+		},
+		{
+			name:       "Missed skip",
+			leader:     true,
+			missedRuns: MissedSkip,
+			wantUrls:   []string{"http://a"},
+			wantMissed: "Skipped",
+		},
+	}
+
+	for _, table := range testTables {
+		t.Run(table.name, func(t *testing.T) {
+			store := newFakeStore(table.leader, onTime, missed)
+			importer := &fakeImporter{}
+			s := newTestScheduler(store, importer, table.missedRuns)
+
+			s.tick(context.Background(), now)
+			s.running.Wait()
+
+			assert.ElementsMatch(t, table.wantUrls, importer.urls)
+			if !table.leader {
+				assert.Empty(t, store.claimed)
+				return
+			}
+			assert.Equal(t, time.Date(2025, 1, 1, 11, 0, 0, 0, time.UTC), store.claimed[onTime.Id])
+			assert.Equal(t, "Success", store.finished[onTime.Id].status)
+			assert.Equal(t, table.wantMissed, store.finished[missed.Id].status)
+		})
+	}
+}
+
+func TestTickClaimedElsewhere(t *testing.T) {
+	now := time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC)
+	schedule := domain.Schedule{Id: primitive.NewObjectID(), Url: "http://a", Cron: "@hourly", NextRun: now}
+	store := newFakeStore(true, schedule)
+	store.claimed[schedule.Id] = now
+	importer := &fakeImporter{}
+
+	s := newTestScheduler(store, importer, MissedRunOnce)
+	s.tick(context.Background(), now)
+	s.running.Wait()
+
+	assert.Empty(t, importer.urls)
+	assert.Empty(t, store.finished)
+}
+
+func TestShutdownReleasesLease(t *testing.T) {
+	store := newFakeStore(true)
+	s := newTestScheduler(store, &fakeImporter{}, MissedRunOnce)
+
+	// the loop ticks once before looking at the context, so the lease is always taken
+	s.Start()
+	assert.NoError(t, s.Shutdown(context.Background()))
+	assert.True(t, store.released)
+}
@@ -0,0 +1,85 @@
+package server
+
+import (
+	"context"
+	"gRPC-server/internal/domain"
+	"gRPC-server/pkg/parseCSV/grpcPb"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func (s *SortServicegRPC) CreateAlertRule(ctx context.Context, req *grpcPb.CreateAlertRuleRequest) (*grpcPb.CreateAlertRuleResponce, error) {
+	rule := req.GetRule()
+	created, err := s.Sorting.CreateAlertRule(ctx, domain.AlertRule{
+		Name:             rule.GetName(),
+		Source:           rule.GetSource(),
+		ProductId:        int(rule.GetProductId()),
+		MinChangePercent: rule.GetMinChangePercent(),
+		Direction:        rule.GetDirection(),
+		WebhookUrl:       rule.GetWebhookUrl(),
+		Secret:           rule.GetSecret(),
+	})
+	if err != nil {
+		return &grpcPb.CreateAlertRuleResponce{}, toStatus(err)
+	}
+
+	// the only response that shows the secret, the receiver needs it to check signatures
+	ruleGrpc := toGrpcAlertRule(created)
+	ruleGrpc.Secret = created.Secret
+	return &grpcPb.CreateAlertRuleResponce{Rule: ruleGrpc}, nil
+}
+
+func (s *SortServicegRPC) ListAlertRules(ctx context.Context, req *grpcPb.ListAlertRulesRequest) (*grpcPb.ListAlertRulesResponce, error) {
+	rules, err := s.Sorting.ListAlertRules(ctx)
+	if err != nil {
+		return &grpcPb.ListAlertRulesResponce{}, toStatus(err)
+	}
+
+	rulesGrpc := make([]*grpcPb.AlertRule, len(rules))
+	for i, rule := range rules {
+		rulesGrpc[i] = toGrpcAlertRule(rule)
+	}
+	return &grpcPb.ListAlertRulesResponce{Rules: rulesGrpc}, nil
+}
+
+func (s *SortServicegRPC) DeleteAlertRule(ctx context.Context, req *grpcPb.DeleteAlertRuleRequest) (*grpcPb.DeleteAlertRuleResponce, error) {
+	if err := s.Sorting.DeleteAlertRule(ctx, req.GetId()); err != nil {
+		return &grpcPb.DeleteAlertRuleResponce{Status: "Fail"}, toStatus(err)
+	}
+	return &grpcPb.DeleteAlertRuleResponce{Status: "Success"}, nil
+}
+
+func (s *SortServicegRPC) ListDeadLetters(ctx context.Context, req *grpcPb.ListDeadLettersRequest) (*grpcPb.ListDeadLettersResponce, error) {
+	letters, err := s.Sorting.ListDeadLetters(ctx, int(req.GetLimit()))
+	if err != nil {
+		return &grpcPb.ListDeadLettersResponce{}, toStatus(err)
+	}
+
+	lettersGrpc := make([]*grpcPb.DeadLetter, len(letters))
+	for i, letter := range letters {
+		lettersGrpc[i] = &grpcPb.DeadLetter{
+			Id:         letter.Id.Hex(),
+			RuleId:     letter.RuleId.Hex(),
+			WebhookUrl: letter.WebhookUrl,
+			Payload:    letter.Payload,
+			Attempts:   int32(letter.Attempts),
+			LastError:  letter.LastError,
+			FailedAt:   timestamppb.New(letter.FailedAt),
+		}
+	}
+	return &grpcPb.ListDeadLettersResponce{DeadLetters: lettersGrpc}, nil
+}
+
+// toGrpcAlertRule leaves the secret out.
+func toGrpcAlertRule(rule domain.AlertRule) *grpcPb.AlertRule {
+	return &grpcPb.AlertRule{
+		Id:               rule.Id.Hex(),
+		Name:             rule.Name,
+		Source:           rule.Source,
+		ProductId:        int64(rule.ProductId),
+		MinChangePercent: rule.MinChangePercent,
+		Direction:        rule.Direction,
+		WebhookUrl:       rule.WebhookUrl,
+		CreatedAt:        timestamppb.New(rule.CreatedAt),
+	}
+}
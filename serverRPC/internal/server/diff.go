@@ -0,0 +1,32 @@
+package server
+
+import (
+	"context"
+	"gRPC-server/pkg/parseCSV/grpcPb"
+)
+
+func (s *SortServicegRPC) DiffFetch(ctx context.Context, req *grpcPb.DiffFetchRequest) (*grpcPb.DiffFetchResponce, error) {
+	diff, err := s.Sorting.DiffFetch(ctx, req)
+	if err != nil {
+		return &grpcPb.DiffFetchResponce{}, toStatus(err)
+	}
+
+	resp := &grpcPb.DiffFetchResponce{
+		Source:    diff.Source,
+		Unchanged: int32(diff.Unchanged),
+		Rejected:  toGrpcRejections(diff.Rejected),
+	}
+	for _, product := range diff.Created {
+		resp.Created = append(resp.Created, toGrpcProduct(product))
+	}
+	for _, update := range diff.Updated {
+		resp.Updated = append(resp.Updated, &grpcPb.ProductDiff{
+			Old: toGrpcProduct(update.Old),
+			New: toGrpcProduct(update.New),
+		})
+	}
+	for _, product := range diff.Deleted {
+		resp.Deleted = append(resp.Deleted, toGrpcProduct(product))
+	}
+	return resp, nil
+}
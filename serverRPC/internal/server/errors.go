@@ -0,0 +1,126 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"gRPC-server/internal/domain"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/x/mongo/driver/topology"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/protoadapt"
+)
+
+// errorDomain and the reasons below go into errdetails.ErrorInfo, clients switch on them
+// instead of parsing messages.
+const errorDomain = "sortservice.grpcPb"
+
+const (
+	ReasonInvalidArgument    = "INVALID_ARGUMENT"
+	ReasonInvalidCSV         = "INVALID_CSV"
+	ReasonProductNotFound    = "PRODUCT_NOT_FOUND"
+	ReasonSourceUnavailable  = "SOURCE_UNAVAILABLE"
+	ReasonStorageUnavailable = "STORAGE_UNAVAILABLE"
+	ReasonDeadlineExceeded   = "DEADLINE_EXCEEDED"
+	ReasonImportQueueFull    = "IMPORT_QUEUE_FULL"
+	ReasonRateLimited        = "RATE_LIMITED"
+	ReasonScheduleNotFound   = "SCHEDULE_NOT_FOUND"
+	ReasonAlertRuleNotFound  = "ALERT_RULE_NOT_FOUND"
+)
+
+// toStatus converts errors coming from the service layer into gRPC statuses.
+// Errors that already carry a status are returned as is.
+func toStatus(err error) error {
+	if err == nil {
+		return nil
+	}
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+
+	var fieldErr *domain.FieldError
+	var notFoundErr *domain.NotFoundError
+
+	switch {
+	case errors.As(err, &fieldErr):
+		reason := ReasonInvalidArgument
+		if errors.Is(err, domain.ErrInvalidCSV) {
+			reason = ReasonInvalidCSV
+		}
+		return withDetails(codes.InvalidArgument, err,
+			&errdetails.BadRequest{
+				FieldViolations: []*errdetails.BadRequest_FieldViolation{
+					{Field: fieldErr.Field, Description: fieldErr.Description},
+				},
+			},
+			errorInfo(reason, nil),
+		)
+	case errors.Is(err, domain.ErrInvalidCSV):
+		return withDetails(codes.InvalidArgument, err, errorInfo(ReasonInvalidCSV, nil))
+	case errors.Is(err, domain.ErrInvalidArgument):
+		return withDetails(codes.InvalidArgument, err, errorInfo(ReasonInvalidArgument, nil))
+	case errors.As(err, &notFoundErr):
+		return withDetails(codes.NotFound, err, errorInfo(ReasonProductNotFound, map[string]string{
+			"ids": notFoundErr.JoinIds(),
+		}))
+	case errors.Is(err, domain.ErrProductNotFound), errors.Is(err, mongo.ErrNoDocuments):
+		return withDetails(codes.NotFound, err, errorInfo(ReasonProductNotFound, nil))
+	case errors.Is(err, domain.ErrScheduleNotFound):
+		return withDetails(codes.NotFound, err, errorInfo(ReasonScheduleNotFound, nil))
+	case errors.Is(err, domain.ErrAlertRuleNotFound):
+		return withDetails(codes.NotFound, err, errorInfo(ReasonAlertRuleNotFound, nil))
+	case errors.Is(err, context.DeadlineExceeded), mongo.IsTimeout(err):
+		return withDetails(codes.DeadlineExceeded, err, errorInfo(ReasonDeadlineExceeded, nil))
+	case errors.Is(err, context.Canceled):
+		return status.Error(codes.Canceled, err.Error())
+	case errors.Is(err, domain.ErrImportQueueFull):
+		return withDetails(codes.ResourceExhausted, err, errorInfo(ReasonImportQueueFull, nil))
+	case errors.Is(err, domain.ErrSourceUnavailable):
+		return withDetails(codes.Unavailable, err, errorInfo(ReasonSourceUnavailable, nil))
+	case errors.Is(err, domain.ErrUnavailable), mongo.IsNetworkError(err), isServerSelectionError(err):
+		return withDetails(codes.Unavailable, err, errorInfo(ReasonStorageUnavailable, nil))
+	default:
+		return &internalError{err: err}
+	}
+}
+
+// internalError keeps the cause for the access log, which writes it with the request ID, while
+// the caller only gets a generic message: driver and CSV errors name hosts and collections.
+type internalError struct {
+	err error
+}
+
+func (e *internalError) Error() string {
+	return e.err.Error()
+}
+
+func (e *internalError) Unwrap() error {
+	return e.err
+}
+
+func (e *internalError) GRPCStatus() *status.Status {
+	return status.New(codes.Internal, "internal error")
+}
+
+func isServerSelectionError(err error) bool {
+	var selectionErr topology.ServerSelectionError
+	return errors.As(err, &selectionErr)
+}
+
+func errorInfo(reason string, metadata map[string]string) *errdetails.ErrorInfo {
+	return &errdetails.ErrorInfo{
+		Reason:   reason,
+		Domain:   errorDomain,
+		Metadata: metadata,
+	}
+}
+
+func withDetails(code codes.Code, err error, details ...protoadapt.MessageV1) error {
+	st, detailsErr := status.New(code, err.Error()).WithDetails(details...)
+	if detailsErr != nil {
+		return status.Error(code, err.Error())
+	}
+	return st.Err()
+}
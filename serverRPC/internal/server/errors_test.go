@@ -0,0 +1,141 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gRPC-server/internal/domain"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/mongo"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestToStatus(t *testing.T) {
+	testTables := []struct {
+		name   string
+		err    error
+		code   codes.Code
+		reason string
+		field  string
+	}{
+		{
+			name:   "Field error",
+			err:    domain.NewFieldError("sort_asc", "must be 1 or -1"),
+			code:   codes.InvalidArgument,
+			reason: ReasonInvalidArgument,
+			field:  "sort_asc",
+		},
+		{
+			name:   "Invalid csv",
+			err:    &domain.FieldError{Field: "url", Description: "record #1", Err: domain.ErrInvalidCSV},
+			code:   codes.InvalidArgument,
+			reason: ReasonInvalidCSV,
+			field:  "url",
+		},
+		{
+			name:   "Not found",
+			err:    &domain.NotFoundError{Ids: []int{1, 2}},
+			code:   codes.NotFound,
+			reason: ReasonProductNotFound,
+		},
+		{
+			name:   "No documents",
+			err:    mongo.ErrNoDocuments,
+			code:   codes.NotFound,
+			reason: ReasonProductNotFound,
+		},
+		{
+			name:   "Source unavailable",
+			err:    fmt.Errorf("%w: connection refused", domain.ErrSourceUnavailable),
+			code:   codes.Unavailable,
+			reason: ReasonSourceUnavailable,
+		},
+		{
+			name:   "Import queue full",
+			err:    domain.ErrImportQueueFull,
+			code:   codes.ResourceExhausted,
+			reason: ReasonImportQueueFull,
+		},
+		{
+			name:   "Schedule not found",
+			err:    domain.ErrScheduleNotFound,
+			code:   codes.NotFound,
+			reason: ReasonScheduleNotFound,
+		},
+		{
+			name:   "Alert rule not found",
+			err:    domain.ErrAlertRuleNotFound,
+			code:   codes.NotFound,
+			reason: ReasonAlertRuleNotFound,
+		},
+		{
+			name:   "Deadline",
+			err:    fmt.Errorf("find: %w", context.DeadlineExceeded),
+			code:   codes.DeadlineExceeded,
+			reason: ReasonDeadlineExceeded,
+		},
+		{
+			name: "Unknown",
+			err:  errors.New("some error"),
+			code: codes.Internal,
+		},
+		{
+			name: "Already status",
+			err:  status.Error(codes.PermissionDenied, "denied"),
+			code: codes.PermissionDenied,
+		},
+	}
+
+	for _, table := range testTables {
+		t.Run(table.name, func(t *testing.T) {
+			st := status.Convert(toStatus(table.err))
+
+			assert.Equal(t, table.code, st.Code())
+
+			var info *errdetails.ErrorInfo
+			var badRequest *errdetails.BadRequest
+			for _, detail := range st.Details() {
+				switch d := detail.(type) {
+				case *errdetails.ErrorInfo:
+					info = d
+				case *errdetails.BadRequest:
+					badRequest = d
+				}
+			}
+
+			if table.reason != "" {
+				if assert.NotNil(t, info) {
+					assert.Equal(t, table.reason, info.GetReason())
+					assert.Equal(t, errorDomain, info.GetDomain())
+				}
+			}
+			if table.field != "" {
+				if assert.NotNil(t, badRequest) {
+					assert.Equal(t, table.field, badRequest.GetFieldViolations()[0].GetField())
+				}
+			}
+		})
+	}
+
+	assert.NoError(t, toStatus(nil))
+}
+
+func TestToStatusInternalHidesCause(t *testing.T) {
+	err := toStatus(errors.New("server selection error: mongo-0.internal:27017"))
+
+	assert.Equal(t, "internal error", status.Convert(err).Message())
+	assert.Contains(t, err.Error(), "mongo-0.internal")
+}
+
+func TestToStatusNotFoundIds(t *testing.T) {
+	st := status.Convert(toStatus(&domain.NotFoundError{Ids: []int{4, 7}}))
+
+	info, ok := st.Details()[0].(*errdetails.ErrorInfo)
+	if assert.True(t, ok) {
+		assert.Equal(t, "4,7", info.GetMetadata()["ids"])
+	}
+}
@@ -0,0 +1,165 @@
+package server
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"gRPC-server/internal/domain"
+	"gRPC-server/pkg/parseCSV/grpcPb"
+	"io"
+	"strconv"
+	"time"
+)
+
+// exportChunkSize is roughly how much of the file one ExportChunk carries.
+const exportChunkSize = 64 << 10
+
+func (s *SortServicegRPC) Export(req *grpcPb.ExportRequest, stream grpcPb.SortService_ExportServer) error {
+	list := req.GetList()
+	if list == nil {
+		list = &grpcPb.ListRequest{}
+	}
+	if err := validateListRequest(list); err != nil {
+		return toStatus(err)
+	}
+
+	chunks := &chunkWriter{stream: stream}
+	encoder, err := newExportEncoder(req.GetFormat(), chunks)
+	if err != nil {
+		return toStatus(err)
+	}
+
+	if err := s.Sorting.Export(stream.Context(), list, encoder.encode); err != nil {
+		return toStatus(err)
+	}
+	if err := encoder.close(); err != nil {
+		return toStatus(err)
+	}
+	return toStatus(chunks.flush())
+}
+
+// chunkWriter collects the encoded file and sends it on in exportChunkSize pieces.
+type chunkWriter struct {
+	stream grpcPb.SortService_ExportServer
+	buf    bytes.Buffer
+}
+
+func (w *chunkWriter) Write(p []byte) (int, error) {
+	w.buf.Write(p)
+	if w.buf.Len() >= exportChunkSize {
+		if err := w.flush(); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+func (w *chunkWriter) flush() error {
+	if w.buf.Len() == 0 {
+		return nil
+	}
+	// the sent message may still be read by stats handlers, so it gets its own copy
+	chunk := &grpcPb.ExportChunk{Data: bytes.Clone(w.buf.Bytes())}
+	w.buf.Reset()
+	return w.stream.Send(chunk)
+}
+
+type exportEncoder interface {
+	encode(product domain.Product) error
+	close() error
+}
+
+func newExportEncoder(format grpcPb.ExportRequest_Format, w io.Writer) (exportEncoder, error) {
+	switch format {
+	case grpcPb.ExportRequest_csv:
+		writer := csv.NewWriter(w)
+		writer.Comma = ';'
+		return &csvEncoder{writer: writer}, nil
+	case grpcPb.ExportRequest_json:
+		return &jsonEncoder{w: w}, nil
+	case grpcPb.ExportRequest_ndjson:
+		return &ndjsonEncoder{encoder: json.NewEncoder(w)}, nil
+	default:
+		return nil, domain.NewFieldError("format", fmt.Sprintf("unknown format %d", format))
+	}
+}
+
+// csvEncoder writes id;name;price, the dialect web-app serves and Fetch reads by default.
+type csvEncoder struct {
+	writer *csv.Writer
+}
+
+func (e *csvEncoder) encode(product domain.Product) error {
+	return e.writer.Write([]string{strconv.Itoa(product.Id), product.Name, product.Price.String()})
+}
+
+func (e *csvEncoder) close() error {
+	e.writer.Flush()
+	return e.writer.Error()
+}
+
+// exportProduct is a product in JSON and NDJSON exports, the price stays a string to keep it exact.
+type exportProduct struct {
+	Source       string     `json:"source"`
+	Id           int        `json:"id"`
+	Name         string     `json:"name"`
+	Price        string     `json:"price"`
+	DateOfChange time.Time  `json:"date_of_change,omitzero"`
+	DeletedAt    *time.Time `json:"deleted_at,omitempty"`
+}
+
+func toExportProduct(product domain.Product) exportProduct {
+	return exportProduct{
+		Source:       product.Source,
+		Id:           product.Id,
+		Name:         product.Name,
+		Price:        product.Price.String(),
+		DateOfChange: product.DateOfChange,
+		DeletedAt:    product.DeletedAt,
+	}
+}
+
+// jsonEncoder writes one array, element by element, so the whole catalog is never in memory.
+type jsonEncoder struct {
+	w     io.Writer
+	count int
+}
+
+func (e *jsonEncoder) encode(product domain.Product) error {
+	item, err := json.Marshal(toExportProduct(product))
+	if err != nil {
+		return err
+	}
+	separator := ",\n"
+	if e.count == 0 {
+		separator = "[\n"
+	}
+	e.count++
+	if _, err := io.WriteString(e.w, separator); err != nil {
+		return err
+	}
+	_, err = e.w.Write(item)
+	return err
+}
+
+func (e *jsonEncoder) close() error {
+	end := "\n]\n"
+	if e.count == 0 {
+		end = "[]\n"
+	}
+	_, err := io.WriteString(e.w, end)
+	return err
+}
+
+type ndjsonEncoder struct {
+	encoder *json.Encoder
+}
+
+func (e *ndjsonEncoder) encode(product domain.Product) error {
+	return e.encoder.Encode(toExportProduct(product))
+}
+
+func (e *ndjsonEncoder) close() error {
+	return nil
+}
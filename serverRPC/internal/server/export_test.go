@@ -0,0 +1,186 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"gRPC-server/internal/domain"
+	mock_server "gRPC-server/internal/server/mocks"
+	"gRPC-server/pkg/logger"
+	"gRPC-server/pkg/parseCSV/grpcPb"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type exportStream struct {
+	grpc.ServerStream
+	chunks [][]byte
+}
+
+func (e *exportStream) Context() context.Context {
+	return context.Background()
+}
+
+func (e *exportStream) Send(chunk *grpcPb.ExportChunk) error {
+	e.chunks = append(e.chunks, chunk.GetData())
+	return nil
+}
+
+func (e *exportStream) file() string {
+	return string(bytes.Join(e.chunks, nil))
+}
+
+func exportProducts(n int) []domain.Product {
+	products := make([]domain.Product, n)
+	for i := range products {
+		price, _ := primitive.ParseDecimal128(fmt.Sprintf("%d.50", i+1))
+		products[i] = domain.Product{Source: "default", Id: i + 1, Name: fmt.Sprintf("product %d", i+1), Price: price}
+	}
+	return products
+}
+
+func TestExport(t *testing.T) {
+	logger := logger.GetLogger()
+	products := exportProducts(2)
+	products[1].Name = "Bread; white"
+
+	testTables := []struct {
+		name   string
+		format grpcPb.ExportRequest_Format
+		want   string
+	}{
+		{
+			name:   "CSV",
+			format: grpcPb.ExportRequest_csv,
+			want:   "1;product 1;1.50\n2;\"Bread; white\";2.50\n",
+		},
+		{
+			name:   "JSON",
+			format: grpcPb.ExportRequest_json,
+			want: "[\n" +
+				`{"source":"default","id":1,"name":"product 1","price":"1.50"},` + "\n" +
+				`{"source":"default","id":2,"name":"Bread; white","price":"2.50"}` + "\n]\n",
+		},
+		{
+			name:   "NDJSON",
+			format: grpcPb.ExportRequest_ndjson,
+			want: `{"source":"default","id":1,"name":"product 1","price":"1.50"}` + "\n" +
+				`{"source":"default","id":2,"name":"Bread; white","price":"2.50"}` + "\n",
+		},
+	}
+
+	for _, table := range testTables {
+		t.Run(table.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			list := &grpcPb.ListRequest{SortField: grpcPb.ListRequest_price, SortAsc: -1, Source: "default"}
+			mockSortingServiceServer := mock_server.NewMockSorting(c)
+			mockSortingServiceServer.EXPECT().Export(gomock.Any(), list, gomock.Any()).DoAndReturn(
+				func(ctx context.Context, req *grpcPb.ListRequest, send func(domain.Product) error) error {
+					for _, product := range products {
+						if err := send(product); err != nil {
+							return err
+						}
+					}
+					return nil
+				})
+
+			stream := &exportStream{}
+			serviceServer := NewSortServerService(mockSortingServiceServer, logger)
+			err := serviceServer.Export(&grpcPb.ExportRequest{List: list, Format: table.format}, stream)
+
+			assert.NoError(t, err)
+			assert.Equal(t, table.want, stream.file())
+		})
+	}
+}
+
+func TestExportEmptyJSON(t *testing.T) {
+	logger := logger.GetLogger()
+
+	c := gomock.NewController(t)
+	defer c.Finish()
+
+	mockSortingServiceServer := mock_server.NewMockSorting(c)
+	mockSortingServiceServer.EXPECT().Export(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil)
+
+	stream := &exportStream{}
+	serviceServer := NewSortServerService(mockSortingServiceServer, logger)
+	err := serviceServer.Export(&grpcPb.ExportRequest{List: &grpcPb.ListRequest{SortAsc: 1}, Format: grpcPb.ExportRequest_json}, stream)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "[]\n", stream.file())
+}
+
+func TestExportChunks(t *testing.T) {
+	logger := logger.GetLogger()
+	products := exportProducts(5000)
+
+	c := gomock.NewController(t)
+	defer c.Finish()
+
+	mockSortingServiceServer := mock_server.NewMockSorting(c)
+	mockSortingServiceServer.EXPECT().Export(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, req *grpcPb.ListRequest, send func(domain.Product) error) error {
+			for _, product := range products {
+				if err := send(product); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+
+	stream := &exportStream{}
+	serviceServer := NewSortServerService(mockSortingServiceServer, logger)
+	err := serviceServer.Export(&grpcPb.ExportRequest{List: &grpcPb.ListRequest{SortAsc: 1}, Format: grpcPb.ExportRequest_json}, stream)
+	assert.NoError(t, err)
+
+	assert.Greater(t, len(stream.chunks), 1)
+	for _, chunk := range stream.chunks {
+		assert.LessOrEqual(t, len(chunk), 2*exportChunkSize)
+	}
+	var decoded []exportProduct
+	assert.NoError(t, json.Unmarshal([]byte(stream.file()), &decoded))
+	assert.Len(t, decoded, 5000)
+	assert.Equal(t, "product 5000", decoded[4999].Name)
+}
+
+func TestExportValidation(t *testing.T) {
+	logger := logger.GetLogger()
+
+	testTables := []struct {
+		name string
+		req  *grpcPb.ExportRequest
+	}{
+		{
+			name: "No sort direction",
+			req:  &grpcPb.ExportRequest{},
+		},
+		{
+			name: "Unknown format",
+			req:  &grpcPb.ExportRequest{List: &grpcPb.ListRequest{SortAsc: 1}, Format: 7},
+		},
+	}
+
+	for _, table := range testTables {
+		t.Run(table.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			serviceServer := NewSortServerService(mock_server.NewMockSorting(c), logger)
+			stream := &exportStream{}
+			err := serviceServer.Export(table.req, stream)
+
+			assert.Equal(t, codes.InvalidArgument, status.Code(err))
+			assert.Empty(t, stream.chunks)
+		})
+	}
+}
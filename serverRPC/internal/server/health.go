@@ -0,0 +1,101 @@
+package server
+
+import (
+	"context"
+	"gRPC-server/pkg/logger"
+	"gRPC-server/pkg/parseCSV/grpcPb"
+	"time"
+
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+const (
+	defaultHealthInterval = 5 * time.Second
+	defaultHealthTimeout  = 2 * time.Second
+)
+
+// Pinger reports whether the storage behind SortService is reachable.
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// healthChecker keeps grpc.health.v1 in sync with the Mongo ping: both the server as a whole ("")
+// and SortService turn NOT_SERVING while the ping fails.
+type healthChecker struct {
+	server   *health.Server
+	pinger   Pinger
+	logger   *logger.Logger
+	interval time.Duration
+	timeout  time.Duration
+	done     chan struct{}
+}
+
+func newHealthChecker(pinger Pinger, logger *logger.Logger, interval, timeout time.Duration) *healthChecker {
+	if interval <= 0 {
+		interval = defaultHealthInterval
+	}
+	if timeout <= 0 {
+		timeout = defaultHealthTimeout
+	}
+
+	h := &healthChecker{
+		server:   health.NewServer(),
+		pinger:   pinger,
+		logger:   logger,
+		interval: interval,
+		timeout:  timeout,
+		done:     make(chan struct{}),
+	}
+	// nothing is known before the first ping
+	h.setStatus(healthpb.HealthCheckResponse_NOT_SERVING)
+	return h
+}
+
+func (h *healthChecker) setStatus(status healthpb.HealthCheckResponse_ServingStatus) {
+	h.server.SetServingStatus("", status)
+	h.server.SetServingStatus(grpcPb.SortService_ServiceDesc.ServiceName, status)
+}
+
+// check pings once and updates the status, it logs only when the status changes.
+func (h *healthChecker) check(serving bool) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), h.timeout)
+	defer cancel()
+
+	err := h.pinger.Ping(ctx)
+	switch {
+	case err != nil && serving:
+		h.logger.Errorf("Mongo ping failed, reporting NOT_SERVING: %s", err)
+	case err == nil && !serving:
+		h.logger.Info("Mongo ping ok, reporting SERVING")
+	}
+
+	if err != nil {
+		h.setStatus(healthpb.HealthCheckResponse_NOT_SERVING)
+		return false
+	}
+	h.setStatus(healthpb.HealthCheckResponse_SERVING)
+	return true
+}
+
+func (h *healthChecker) run() {
+	ticker := time.NewTicker(h.interval)
+	defer ticker.Stop()
+
+	serving := h.check(false)
+	for {
+		select {
+		case <-h.done:
+			return
+		case <-ticker.C:
+			serving = h.check(serving)
+		}
+	}
+}
+
+// shutdown switches everything to NOT_SERVING for good, so load balancers drain the server
+// while GracefulStop waits for running calls.
+func (h *healthChecker) shutdown() {
+	close(h.done)
+	h.server.Shutdown()
+}
@@ -0,0 +1,70 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"gRPC-server/pkg/parseCSV/grpcPb"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+type fakePinger struct {
+	mu  sync.Mutex
+	err error
+}
+
+func (p *fakePinger) Ping(ctx context.Context) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.err
+}
+
+func (p *fakePinger) set(err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.err = err
+}
+
+func servingStatus(t *testing.T, h *healthChecker, service string) healthpb.HealthCheckResponse_ServingStatus {
+	t.Helper()
+	resp, err := h.server.Check(context.Background(), &healthpb.HealthCheckRequest{Service: service})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp.GetStatus()
+}
+
+func TestHealthChecker(t *testing.T) {
+	log, hook := newTestLogger()
+	pinger := &fakePinger{}
+	h := newHealthChecker(pinger, log, 10*time.Millisecond, time.Second)
+	sortService := grpcPb.SortService_ServiceDesc.ServiceName
+
+	assert.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, servingStatus(t, h, ""))
+
+	go h.run()
+	assert.Eventually(t, func() bool {
+		return servingStatus(t, h, sortService) == healthpb.HealthCheckResponse_SERVING
+	}, time.Second, 5*time.Millisecond)
+
+	pinger.set(errors.New("no reachable servers"))
+	assert.Eventually(t, func() bool {
+		return servingStatus(t, h, "") == healthpb.HealthCheckResponse_NOT_SERVING
+	}, time.Second, 5*time.Millisecond)
+	assert.Contains(t, hook.LastEntry().Message, "no reachable servers")
+
+	pinger.set(nil)
+	assert.Eventually(t, func() bool {
+		return servingStatus(t, h, "") == healthpb.HealthCheckResponse_SERVING
+	}, time.Second, 5*time.Millisecond)
+
+	// after shutdown successful pings don't bring the server back
+	h.shutdown()
+	time.Sleep(30 * time.Millisecond)
+	assert.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, servingStatus(t, h, ""))
+	assert.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, servingStatus(t, h, sortService))
+}
@@ -0,0 +1,212 @@
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"gRPC-server/internal/auth"
+	"gRPC-server/internal/metrics"
+	"gRPC-server/pkg/logger"
+	"runtime/debug"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	requestIDHeader = "x-request-id"
+	// maxRequestIDLength bounds what a caller can put into every log line of its call
+	maxRequestIDLength = 64
+)
+
+// wrappedStream lets stream interceptors hand a modified context down to the handler.
+type wrappedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (w *wrappedStream) Context() context.Context {
+	return w.ctx
+}
+
+// requestID takes x-request-id from the incoming metadata or makes a new one, echoes it back
+// in the response header and stores it for logger.FromContext. An id that isn't valid is replaced.
+func requestID(ctx context.Context) context.Context {
+	var id string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(requestIDHeader); len(values) > 0 {
+			id = values[0]
+		}
+	}
+	if !validRequestID(id) {
+		id = newRequestID()
+	}
+
+	_ = grpc.SetHeader(ctx, metadata.Pairs(requestIDHeader, id))
+	ctx = logger.ContextWithField(ctx, "request_id", id)
+
+	if spanCtx := trace.SpanContextFromContext(ctx); spanCtx.HasTraceID() {
+		ctx = logger.ContextWithField(ctx, "trace_id", spanCtx.TraceID().String())
+	}
+	return ctx
+}
+
+// validRequestID allows ids up to maxRequestIDLength of letters, digits and "-_.:", enough for
+// uuids and the ids of common proxies.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, c := range id {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case c == '-', c == '_', c == '.', c == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(b)
+}
+
+func requestIDUnaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	return handler(requestID(ctx), req)
+}
+
+func requestIDStreamInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	return handler(srv, &wrappedStream{ServerStream: ss, ctx: requestID(ss.Context())})
+}
+
+func loggingUnaryInterceptor(log *logger.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		logCall(ctx, log, info.FullMethod, start, err)
+		return resp, err
+	}
+}
+
+func loggingStreamInterceptor(log *logger.Logger) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+		err := handler(srv, ss)
+		logCall(ss.Context(), log, info.FullMethod, start, err)
+		return err
+	}
+}
+
+func logCall(ctx context.Context, log *logger.Logger, method string, start time.Time, err error) {
+	code := status.Code(err)
+	entry := log.FromContext(ctx).WithFields(map[string]interface{}{
+		"method":   method,
+		"duration": time.Since(start).String(),
+		"code":     code.String(),
+	})
+
+	switch code {
+	case codes.OK, codes.Canceled, codes.NotFound, codes.InvalidArgument:
+		entry.Info("grpc call")
+	case codes.Internal, codes.Unknown, codes.DataLoss:
+		entry.Errorf("grpc call failed: %s", err)
+	default:
+		entry.Warnf("grpc call failed: %s", err)
+	}
+}
+
+func metricsUnaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	start := time.Now()
+	resp, err := handler(ctx, req)
+	observeCall(info.FullMethod, start, err)
+	return resp, err
+}
+
+func metricsStreamInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	start := time.Now()
+	err := handler(srv, ss)
+	observeCall(info.FullMethod, start, err)
+	return err
+}
+
+func observeCall(method string, start time.Time, err error) {
+	code := status.Code(err).String()
+	metrics.GrpcRequests.WithLabelValues(method, code).Inc()
+	metrics.GrpcLatency.WithLabelValues(method, code).Observe(time.Since(start).Seconds())
+}
+
+// recovery interceptors turn a panic in a handler into codes.Internal instead of killing the process.
+func recoveryUnaryInterceptor(log *logger.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				log.FromContext(ctx).Errorf("panic in %s: %v\n%s", info.FullMethod, r, debug.Stack())
+				err = status.Error(codes.Internal, "internal error")
+			}
+		}()
+		return handler(ctx, req)
+	}
+}
+
+func recoveryStreamInterceptor(log *logger.Logger) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				log.FromContext(ss.Context()).Errorf("panic in %s: %v\n%s", info.FullMethod, r, debug.Stack())
+				err = status.Error(codes.Internal, "internal error")
+			}
+		}()
+		return handler(srv, ss)
+	}
+}
+
+// authorize checks the caller of fullMethod and adds its identity to ctx and to the log fields.
+func authorize(ctx context.Context, authorizer *auth.Authorizer, log *logger.Logger, fullMethod string) (context.Context, error) {
+	if authorizer.Public(fullMethod) {
+		return ctx, nil
+	}
+
+	identity, err := authorizer.Authorize(ctx, fullMethod)
+	if identity != nil {
+		ctx = auth.WithIdentity(ctx, identity)
+		ctx = logger.ContextWithField(ctx, "caller", identity.Subject)
+	}
+	if err != nil {
+		log.FromContext(ctx).WithField("method", fullMethod).Warnf("grpc call rejected: %s", err)
+		if errors.Is(err, auth.ErrForbidden) {
+			return ctx, status.Error(codes.PermissionDenied, "permission denied")
+		}
+		return ctx, status.Error(codes.Unauthenticated, "missing or invalid credentials")
+	}
+	return ctx, nil
+}
+
+func authUnaryInterceptor(authorizer *auth.Authorizer, log *logger.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx, err := authorize(ctx, authorizer, log, info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+func authStreamInterceptor(authorizer *auth.Authorizer, log *logger.Logger) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := authorize(ss.Context(), authorizer, log, info.FullMethod)
+		if err != nil {
+			return err
+		}
+		return handler(srv, &wrappedStream{ServerStream: ss, ctx: ctx})
+	}
+}
@@ -0,0 +1,150 @@
+package server
+
+import (
+	"context"
+	"gRPC-server/internal/auth"
+	"gRPC-server/internal/metrics"
+	"gRPC-server/pkg/logger"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func newTestLogger() (*logger.Logger, *test.Hook) {
+	l, hook := test.NewNullLogger()
+	l.SetLevel(logrus.TraceLevel)
+	return &logger.Logger{Entry: logrus.NewEntry(l)}, hook
+}
+
+func TestRecoveryUnaryInterceptor(t *testing.T) {
+	log, hook := newTestLogger()
+	info := &grpc.UnaryServerInfo{FullMethod: "/grpcPb.SortService/List"}
+
+	resp, err := recoveryUnaryInterceptor(log)(context.Background(), nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		panic("boom")
+	})
+
+	assert.Nil(t, resp)
+	assert.Equal(t, codes.Internal, status.Code(err))
+	assert.Equal(t, logrus.ErrorLevel, hook.LastEntry().Level)
+	assert.Contains(t, hook.LastEntry().Message, "boom")
+}
+
+func TestRequestIDIsLogged(t *testing.T) {
+	log, hook := newTestLogger()
+	info := &grpc.UnaryServerInfo{FullMethod: "/grpcPb.SortService/List"}
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(requestIDHeader, "req-1"))
+
+	chain := func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		return requestIDUnaryInterceptor(ctx, req, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+			return loggingUnaryInterceptor(log)(ctx, req, info, handler)
+		})
+	}
+
+	_, err := chain(ctx, nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		log.FromContext(ctx).Info("inside handler")
+		return nil, status.Error(codes.NotFound, "nope")
+	})
+
+	assert.Equal(t, codes.NotFound, status.Code(err))
+	entries := hook.AllEntries()
+	if assert.Len(t, entries, 2) {
+		assert.Equal(t, "req-1", entries[0].Data["request_id"])
+		assert.Equal(t, "req-1", entries[1].Data["request_id"])
+		assert.Equal(t, "/grpcPb.SortService/List", entries[1].Data["method"])
+		assert.Equal(t, "NotFound", entries[1].Data["code"])
+	}
+}
+
+func TestRequestIDGenerated(t *testing.T) {
+	log, hook := newTestLogger()
+
+	ctx := requestID(context.Background())
+	log.FromContext(ctx).Info("message")
+
+	id, ok := hook.LastEntry().Data["request_id"].(string)
+	assert.True(t, ok)
+	assert.Len(t, id, 32)
+}
+
+func TestRequestIDReplacedWhenInvalid(t *testing.T) {
+	log, hook := newTestLogger()
+
+	for _, incoming := range []string{"bad id\nlevel=error", strings.Repeat("a", maxRequestIDLength+1)} {
+		ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(requestIDHeader, incoming))
+		log.FromContext(requestID(ctx)).Info("message")
+
+		id, _ := hook.LastEntry().Data["request_id"].(string)
+		assert.Len(t, id, 32)
+	}
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(requestIDHeader, "3f2a-b7:c.1_x"))
+	log.FromContext(requestID(ctx)).Info("message")
+	assert.Equal(t, "3f2a-b7:c.1_x", hook.LastEntry().Data["request_id"])
+}
+
+func TestMetricsUnaryInterceptor(t *testing.T) {
+	info := &grpc.UnaryServerInfo{FullMethod: "/grpcPb.SortService/GetProduct"}
+	before := testutil.ToFloat64(metrics.GrpcRequests.WithLabelValues(info.FullMethod, "NotFound"))
+
+	_, err := metricsUnaryInterceptor(context.Background(), nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, status.Error(codes.NotFound, "nope")
+	})
+
+	assert.Equal(t, codes.NotFound, status.Code(err))
+	assert.Equal(t, float64(1), testutil.ToFloat64(metrics.GrpcRequests.WithLabelValues(info.FullMethod, "NotFound"))-before)
+}
+
+func TestAuthInterceptor(t *testing.T) {
+	log, hook := newTestLogger()
+	authorizer := auth.NewAuthorizer(
+		[]auth.MethodRule{
+			{Method: "/grpcPb.SortService/Fetch", Roles: []string{"importer"}},
+			{Method: "/grpcPb.SortService/List", Open: true},
+		},
+		nil,
+		auth.NewAPIKeys([]auth.APIKey{{Key: "reader-key", Subject: "dashboard", Roles: []string{"reader"}}}),
+	)
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		log.FromContext(ctx).Info("inside handler")
+		return auth.FromContext(ctx).Subject, nil
+	}
+
+	tests := []struct {
+		name     string
+		method   string
+		md       metadata.MD
+		wantCode codes.Code
+	}{
+		{name: "Allowed", method: "/grpcPb.SortService/List", md: metadata.Pairs(auth.APIKeyHeader, "reader-key"), wantCode: codes.OK},
+		{name: "Forbidden", method: "/grpcPb.SortService/Fetch", md: metadata.Pairs(auth.APIKeyHeader, "reader-key"), wantCode: codes.PermissionDenied},
+		{name: "Anonymous", method: "/grpcPb.SortService/List", md: metadata.MD{}, wantCode: codes.Unauthenticated},
+		{name: "Not listed", method: "/grpcPb.SortService/GetStats", md: metadata.Pairs(auth.APIKeyHeader, "reader-key"), wantCode: codes.PermissionDenied},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			hook.Reset()
+			ctx := metadata.NewIncomingContext(context.Background(), test.md)
+			info := &grpc.UnaryServerInfo{FullMethod: test.method}
+
+			resp, err := authUnaryInterceptor(authorizer, log)(ctx, nil, info, handler)
+
+			assert.Equal(t, test.wantCode, status.Code(err))
+			if test.wantCode == codes.OK {
+				assert.Equal(t, "dashboard", resp)
+			}
+			if test.md.Len() > 0 {
+				assert.Equal(t, "dashboard", hook.LastEntry().Data["caller"])
+			}
+		})
+	}
+}
@@ -9,6 +9,7 @@ import (
 	domain "gRPC-server/internal/domain"
 	grpcPb "gRPC-server/pkg/parseCSV/grpcPb"
 	reflect "reflect"
+	time "time"
 
 	gomock "github.com/golang/mock/gomock"
 )
@@ -36,6 +37,122 @@ func (m *MockSorting) EXPECT() *MockSortingMockRecorder {
 	return m.recorder
 }
 
+// BatchGetProducts mocks base method.
+func (m *MockSorting) BatchGetProducts(ctx context.Context, source string, ids []int, includeDeleted bool) ([]domain.Product, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "BatchGetProducts", ctx, source, ids, includeDeleted)
+	ret0, _ := ret[0].([]domain.Product)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// BatchGetProducts indicates an expected call of BatchGetProducts.
+func (mr *MockSortingMockRecorder) BatchGetProducts(ctx, source, ids, includeDeleted interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BatchGetProducts", reflect.TypeOf((*MockSorting)(nil).BatchGetProducts), ctx, source, ids, includeDeleted)
+}
+
+// CreateAlertRule mocks base method.
+func (m *MockSorting) CreateAlertRule(ctx context.Context, rule domain.AlertRule) (domain.AlertRule, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateAlertRule", ctx, rule)
+	ret0, _ := ret[0].(domain.AlertRule)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateAlertRule indicates an expected call of CreateAlertRule.
+func (mr *MockSortingMockRecorder) CreateAlertRule(ctx, rule interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateAlertRule", reflect.TypeOf((*MockSorting)(nil).CreateAlertRule), ctx, rule)
+}
+
+// CreateSchedule mocks base method.
+func (m *MockSorting) CreateSchedule(ctx context.Context, schedule domain.Schedule) (domain.Schedule, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateSchedule", ctx, schedule)
+	ret0, _ := ret[0].(domain.Schedule)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateSchedule indicates an expected call of CreateSchedule.
+func (mr *MockSortingMockRecorder) CreateSchedule(ctx, schedule interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateSchedule", reflect.TypeOf((*MockSorting)(nil).CreateSchedule), ctx, schedule)
+}
+
+// DeleteAlertRule mocks base method.
+func (m *MockSorting) DeleteAlertRule(ctx context.Context, id string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteAlertRule", ctx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteAlertRule indicates an expected call of DeleteAlertRule.
+func (mr *MockSortingMockRecorder) DeleteAlertRule(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteAlertRule", reflect.TypeOf((*MockSorting)(nil).DeleteAlertRule), ctx, id)
+}
+
+// DeleteProduct mocks base method.
+func (m *MockSorting) DeleteProduct(ctx context.Context, product domain.Product) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteProduct", ctx, product)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteProduct indicates an expected call of DeleteProduct.
+func (mr *MockSortingMockRecorder) DeleteProduct(ctx, product interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteProduct", reflect.TypeOf((*MockSorting)(nil).DeleteProduct), ctx, product)
+}
+
+// DeleteSchedule mocks base method.
+func (m *MockSorting) DeleteSchedule(ctx context.Context, id string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteSchedule", ctx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteSchedule indicates an expected call of DeleteSchedule.
+func (mr *MockSortingMockRecorder) DeleteSchedule(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteSchedule", reflect.TypeOf((*MockSorting)(nil).DeleteSchedule), ctx, id)
+}
+
+// DiffFetch mocks base method.
+func (m *MockSorting) DiffFetch(ctx context.Context, req *grpcPb.DiffFetchRequest) (domain.ImportDiff, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DiffFetch", ctx, req)
+	ret0, _ := ret[0].(domain.ImportDiff)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DiffFetch indicates an expected call of DiffFetch.
+func (mr *MockSortingMockRecorder) DiffFetch(ctx, req interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DiffFetch", reflect.TypeOf((*MockSorting)(nil).DiffFetch), ctx, req)
+}
+
+// Export mocks base method.
+func (m *MockSorting) Export(ctx context.Context, req *grpcPb.ListRequest, send func(domain.Product) error) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Export", ctx, req, send)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Export indicates an expected call of Export.
+func (mr *MockSortingMockRecorder) Export(ctx, req, send interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Export", reflect.TypeOf((*MockSorting)(nil).Export), ctx, req, send)
+}
+
 // Fetch mocks base method.
 func (m *MockSorting) Fetch(ctx context.Context, req *grpcPb.FetchRequest) (domain.Status, error) {
 	m.ctrl.T.Helper()
@@ -51,6 +168,36 @@ func (mr *MockSortingMockRecorder) Fetch(ctx, req interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Fetch", reflect.TypeOf((*MockSorting)(nil).Fetch), ctx, req)
 }
 
+// GetProduct mocks base method.
+func (m *MockSorting) GetProduct(ctx context.Context, source string, id int, includeDeleted bool) (domain.Product, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetProduct", ctx, source, id, includeDeleted)
+	ret0, _ := ret[0].(domain.Product)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetProduct indicates an expected call of GetProduct.
+func (mr *MockSortingMockRecorder) GetProduct(ctx, source, id, includeDeleted interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetProduct", reflect.TypeOf((*MockSorting)(nil).GetProduct), ctx, source, id, includeDeleted)
+}
+
+// GetStats mocks base method.
+func (m *MockSorting) GetStats(ctx context.Context, filter domain.StatsFilter) (domain.Stats, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetStats", ctx, filter)
+	ret0, _ := ret[0].(domain.Stats)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetStats indicates an expected call of GetStats.
+func (mr *MockSortingMockRecorder) GetStats(ctx, filter interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetStats", reflect.TypeOf((*MockSorting)(nil).GetStats), ctx, filter)
+}
+
 // List mocks base method.
 func (m *MockSorting) List(ctx context.Context, req *grpcPb.ListRequest) ([]domain.Product, error) {
 	m.ctrl.T.Helper()
@@ -65,3 +212,120 @@ func (mr *MockSortingMockRecorder) List(ctx, req interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "List", reflect.TypeOf((*MockSorting)(nil).List), ctx, req)
 }
+
+// ListAlertRules mocks base method.
+func (m *MockSorting) ListAlertRules(ctx context.Context) ([]domain.AlertRule, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListAlertRules", ctx)
+	ret0, _ := ret[0].([]domain.AlertRule)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListAlertRules indicates an expected call of ListAlertRules.
+func (mr *MockSortingMockRecorder) ListAlertRules(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListAlertRules", reflect.TypeOf((*MockSorting)(nil).ListAlertRules), ctx)
+}
+
+// ListDeadLetters mocks base method.
+func (m *MockSorting) ListDeadLetters(ctx context.Context, limit int) ([]domain.DeadLetter, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListDeadLetters", ctx, limit)
+	ret0, _ := ret[0].([]domain.DeadLetter)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListDeadLetters indicates an expected call of ListDeadLetters.
+func (mr *MockSortingMockRecorder) ListDeadLetters(ctx, limit interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListDeadLetters", reflect.TypeOf((*MockSorting)(nil).ListDeadLetters), ctx, limit)
+}
+
+// ListSchedules mocks base method.
+func (m *MockSorting) ListSchedules(ctx context.Context) ([]domain.Schedule, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListSchedules", ctx)
+	ret0, _ := ret[0].([]domain.Schedule)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListSchedules indicates an expected call of ListSchedules.
+func (mr *MockSortingMockRecorder) ListSchedules(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListSchedules", reflect.TypeOf((*MockSorting)(nil).ListSchedules), ctx)
+}
+
+// ListSources mocks base method.
+func (m *MockSorting) ListSources(ctx context.Context) domain.SourceRegistry {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListSources", ctx)
+	ret0, _ := ret[0].(domain.SourceRegistry)
+	return ret0
+}
+
+// ListSources indicates an expected call of ListSources.
+func (mr *MockSortingMockRecorder) ListSources(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListSources", reflect.TypeOf((*MockSorting)(nil).ListSources), ctx)
+}
+
+// PauseSchedule mocks base method.
+func (m *MockSorting) PauseSchedule(ctx context.Context, id string, paused bool) (domain.Schedule, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PauseSchedule", ctx, id, paused)
+	ret0, _ := ret[0].(domain.Schedule)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PauseSchedule indicates an expected call of PauseSchedule.
+func (mr *MockSortingMockRecorder) PauseSchedule(ctx, id, paused interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PauseSchedule", reflect.TypeOf((*MockSorting)(nil).PauseSchedule), ctx, id, paused)
+}
+
+// PurgeDeleted mocks base method.
+func (m *MockSorting) PurgeDeleted(ctx context.Context, olderThan time.Time) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PurgeDeleted", ctx, olderThan)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PurgeDeleted indicates an expected call of PurgeDeleted.
+func (mr *MockSortingMockRecorder) PurgeDeleted(ctx, olderThan interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PurgeDeleted", reflect.TypeOf((*MockSorting)(nil).PurgeDeleted), ctx, olderThan)
+}
+
+// RestoreProduct mocks base method.
+func (m *MockSorting) RestoreProduct(ctx context.Context, product domain.Product) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RestoreProduct", ctx, product)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RestoreProduct indicates an expected call of RestoreProduct.
+func (mr *MockSortingMockRecorder) RestoreProduct(ctx, product interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RestoreProduct", reflect.TypeOf((*MockSorting)(nil).RestoreProduct), ctx, product)
+}
+
+// Watch mocks base method.
+func (m *MockSorting) Watch(ctx context.Context, resumeToken string, send func(domain.ProductEvent) error) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Watch", ctx, resumeToken, send)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Watch indicates an expected call of Watch.
+func (mr *MockSortingMockRecorder) Watch(ctx, resumeToken, send interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Watch", reflect.TypeOf((*MockSorting)(nil).Watch), ctx, resumeToken, send)
+}
@@ -0,0 +1,253 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"gRPC-server/internal/auth"
+	"gRPC-server/internal/metrics"
+	"net"
+	"net/netip"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/viper"
+	"golang.org/x/time/rate"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// callerIdleTTL is how long a caller's bucket is kept after its last call.
+const callerIdleTTL = 10 * time.Minute
+
+// rateLimiter applies a global token bucket and one bucket per caller to the configured methods.
+type rateLimiter struct {
+	methods map[string]bool
+	global  *rate.Limiter
+
+	callerRate  rate.Limit
+	callerBurst int
+	// trustedProxies are peers, like the REST gateway, whose x-forwarded-for names the caller
+	trustedProxies []netip.Prefix
+
+	mu        sync.Mutex
+	callers   map[string]*callerBucket
+	lastSweep time.Time
+}
+
+type callerBucket struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// newRateLimiterFromConfig returns nil when rate limiting is disabled.
+func newRateLimiterFromConfig() (*rateLimiter, error) {
+	if !viper.GetBool("ratelimit.enabled") {
+		return nil, nil
+	}
+	methods := viper.GetStringSlice("ratelimit.methods")
+	for _, method := range methods {
+		// like auth.methods, a short name like "Fetch" would never match
+		if !strings.Contains(method, ".") {
+			return nil, fmt.Errorf("ratelimit.methods: %q is neither a full method nor a service name", method)
+		}
+	}
+	l := newRateLimiter(
+		methods,
+		viper.GetFloat64("ratelimit.global.rps"), viper.GetInt("ratelimit.global.burst"),
+		viper.GetFloat64("ratelimit.per_caller.rps"), viper.GetInt("ratelimit.per_caller.burst"),
+	)
+
+	proxies := defaultTrustedProxies
+	if viper.IsSet("ratelimit.trusted_proxies") {
+		proxies = viper.GetStringSlice("ratelimit.trusted_proxies")
+	}
+	for _, proxy := range proxies {
+		prefix, err := parsePrefix(proxy)
+		if err != nil {
+			return nil, fmt.Errorf("ratelimit.trusted_proxies: %w", err)
+		}
+		l.trustedProxies = append(l.trustedProxies, prefix)
+	}
+	return l, nil
+}
+
+// defaultTrustedProxies is the gateway running next to the server.
+var defaultTrustedProxies = []string{"127.0.0.1/32", "::1/128"}
+
+// parsePrefix takes a CIDR or a single address.
+func parsePrefix(s string) (netip.Prefix, error) {
+	if strings.Contains(s, "/") {
+		return netip.ParsePrefix(s)
+	}
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+	return netip.PrefixFrom(addr, addr.BitLen()), nil
+}
+
+// newRateLimiter takes rates in calls per second, a zero rate turns that limit off. methods are
+// full method names like /grpcPb.SortService/Fetch or whole services like grpcPb.SortService.
+func newRateLimiter(methods []string, globalRPS float64, globalBurst int, callerRPS float64, callerBurst int) *rateLimiter {
+	l := &rateLimiter{
+		methods: map[string]bool{},
+		callers: map[string]*callerBucket{},
+	}
+	for _, method := range methods {
+		l.methods[strings.ToLower(method)] = true
+	}
+	if globalRPS > 0 {
+		l.global = rate.NewLimiter(rate.Limit(globalRPS), max(globalBurst, 1))
+	}
+	if callerRPS > 0 {
+		l.callerRate = rate.Limit(callerRPS)
+		l.callerBurst = max(callerBurst, 1)
+	}
+	return l
+}
+
+// allow takes a token from the caller's and the global bucket. When either is empty it returns
+// the limit that was hit and how long to wait before retrying; tokens already reserved for
+// the refused call are given back to both buckets.
+func (l *rateLimiter) allow(caller string, now time.Time) (string, time.Duration, bool) {
+	var reserved []*rate.Reservation
+	refuse := func(limit string, delay time.Duration) (string, time.Duration, bool) {
+		for _, r := range reserved {
+			r.CancelAt(now)
+		}
+		return limit, delay, false
+	}
+
+	if l.callerRate > 0 {
+		r := l.bucket(caller, now).ReserveN(now, 1)
+		reserved = append(reserved, r)
+		if delay := r.DelayFrom(now); delay > 0 {
+			return refuse("caller", delay)
+		}
+	}
+	if l.global != nil {
+		r := l.global.ReserveN(now, 1)
+		reserved = append(reserved, r)
+		if delay := r.DelayFrom(now); delay > 0 {
+			return refuse("global", delay)
+		}
+	}
+	return "", 0, true
+}
+
+func (l *rateLimiter) bucket(caller string, now time.Time) *rate.Limiter {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if now.Sub(l.lastSweep) > callerIdleTTL {
+		for key, bucket := range l.callers {
+			if now.Sub(bucket.lastSeen) > callerIdleTTL {
+				delete(l.callers, key)
+			}
+		}
+		l.lastSweep = now
+	}
+
+	bucket, ok := l.callers[caller]
+	if !ok {
+		bucket = &callerBucket{limiter: rate.NewLimiter(l.callerRate, l.callerBurst)}
+		l.callers[caller] = bucket
+	}
+	bucket.lastSeen = now
+	return bucket.limiter
+}
+
+// limits reports whether fullMethod or its whole service is listed.
+func (l *rateLimiter) limits(fullMethod string) bool {
+	fullMethod = strings.ToLower(fullMethod)
+	return l.methods[fullMethod] || l.methods[strings.Trim(path.Dir(fullMethod), "/")]
+}
+
+func (l *rateLimiter) check(ctx context.Context, fullMethod string) error {
+	if !l.limits(fullMethod) {
+		return nil
+	}
+
+	limit, delay, ok := l.allow(l.callerKey(ctx), time.Now())
+	if ok {
+		return nil
+	}
+
+	metrics.RateLimited.WithLabelValues(fullMethod, limit).Inc()
+	return withDetails(codes.ResourceExhausted, fmt.Errorf("rate limit exceeded (%s), retry in %s", limit, delay.Round(time.Millisecond)),
+		&errdetails.RetryInfo{RetryDelay: durationpb.New(delay)},
+		errorInfo(ReasonRateLimited, map[string]string{"limit": limit}),
+	)
+}
+
+// callerKey is the authenticated subject, or the peer ip when auth is off. Calls from a trusted
+// proxy are keyed by the client the proxy forwards, the REST gateway puts it last in x-forwarded-for;
+// without that all REST callers would share the gateway's bucket.
+func (l *rateLimiter) callerKey(ctx context.Context) string {
+	if identity := auth.FromContext(ctx); identity != nil {
+		return "sub:" + identity.Subject
+	}
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return "unknown"
+	}
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		host = p.Addr.String()
+	}
+	if l.trusted(host) {
+		if client := forwardedFor(ctx); client != "" {
+			return "ip:" + client
+		}
+	}
+	return "ip:" + host
+}
+
+func (l *rateLimiter) trusted(host string) bool {
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, prefix := range l.trustedProxies {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// forwardedFor is the last x-forwarded-for entry, the one the nearest proxy added; earlier ones
+// come from the client and can be anything.
+func forwardedFor(ctx context.Context) string {
+	values := metadata.ValueFromIncomingContext(ctx, "x-forwarded-for")
+	if len(values) == 0 {
+		return ""
+	}
+	entries := strings.Split(values[len(values)-1], ",")
+	return strings.TrimSpace(entries[len(entries)-1])
+}
+
+func rateLimitUnaryInterceptor(l *rateLimiter) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if err := l.check(ctx, info.FullMethod); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+func rateLimitStreamInterceptor(l *rateLimiter) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := l.check(ss.Context(), info.FullMethod); err != nil {
+			return err
+		}
+		return handler(srv, ss)
+	}
+}
@@ -0,0 +1,150 @@
+package server
+
+import (
+	"context"
+	"gRPC-server/internal/auth"
+	"net"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+func TestRateLimiterAllow(t *testing.T) {
+	now := time.Now()
+
+	t.Run("Per caller", func(t *testing.T) {
+		l := newRateLimiter([]string{"/grpcPb.SortService/Fetch"}, 0, 0, 1, 2)
+
+		for i := 0; i < 2; i++ {
+			_, _, ok := l.allow("alice", now)
+			assert.True(t, ok)
+		}
+		limit, delay, ok := l.allow("alice", now)
+		assert.False(t, ok)
+		assert.Equal(t, "caller", limit)
+		assert.Equal(t, time.Second, delay)
+
+		// other callers have their own bucket
+		_, _, ok = l.allow("bob", now)
+		assert.True(t, ok)
+
+		_, _, ok = l.allow("alice", now.Add(time.Second))
+		assert.True(t, ok)
+	})
+
+	t.Run("Global", func(t *testing.T) {
+		l := newRateLimiter([]string{"/grpcPb.SortService/Fetch"}, 2, 1, 0, 0)
+
+		_, _, ok := l.allow("alice", now)
+		assert.True(t, ok)
+		limit, delay, ok := l.allow("bob", now)
+		assert.False(t, ok)
+		assert.Equal(t, "global", limit)
+		assert.Equal(t, 500*time.Millisecond, delay)
+	})
+
+	t.Run("Caller rejection keeps global tokens", func(t *testing.T) {
+		l := newRateLimiter([]string{"/grpcPb.SortService/Fetch"}, 1, 2, 1, 1)
+
+		_, _, ok := l.allow("alice", now)
+		assert.True(t, ok)
+		_, _, ok = l.allow("alice", now)
+		assert.False(t, ok)
+		_, _, ok = l.allow("bob", now)
+		assert.True(t, ok)
+	})
+
+	t.Run("Global rejection keeps caller tokens", func(t *testing.T) {
+		l := newRateLimiter([]string{"/grpcPb.SortService/Fetch"}, 1, 1, 0.1, 1)
+
+		_, _, ok := l.allow("bob", now)
+		assert.True(t, ok)
+		limit, _, ok := l.allow("alice", now)
+		assert.False(t, ok)
+		assert.Equal(t, "global", limit)
+
+		// alice's refused call didn't spend her own token
+		_, _, ok = l.allow("alice", now.Add(time.Second))
+		assert.True(t, ok)
+	})
+
+	t.Run("Idle callers are dropped", func(t *testing.T) {
+		l := newRateLimiter([]string{"/grpcPb.SortService/Fetch"}, 0, 0, 1, 1)
+		l.allow("alice", now)
+		l.allow("bob", now.Add(2*callerIdleTTL))
+		assert.Len(t, l.callers, 1)
+	})
+}
+
+func TestRateLimiterLimits(t *testing.T) {
+	l := newRateLimiter([]string{"/grpcPb.SortService/Fetch", "grpcPb.AdminService"}, 1, 1, 0, 0)
+
+	assert.True(t, l.limits("/grpcPb.SortService/Fetch"))
+	assert.True(t, l.limits("/grpcpb.sortservice/fetch"))
+	assert.True(t, l.limits("/grpcPb.AdminService/Anything"))
+	assert.False(t, l.limits("/grpcPb.SortService/List"))
+	assert.False(t, l.limits("/other.Service/Fetch"))
+}
+
+func TestRateLimitInterceptor(t *testing.T) {
+	l := newRateLimiter([]string{"/grpcPb.SortService/Fetch"}, 0, 0, 1, 1)
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return "ok", nil
+	}
+	fetch := &grpc.UnaryServerInfo{FullMethod: "/grpcPb.SortService/Fetch"}
+	list := &grpc.UnaryServerInfo{FullMethod: "/grpcPb.SortService/List"}
+
+	alice := auth.WithIdentity(context.Background(), &auth.Identity{Subject: "alice"})
+	anonymous := peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP("10.0.0.1"), Port: 4000}})
+
+	_, err := rateLimitUnaryInterceptor(l)(alice, nil, fetch, handler)
+	assert.NoError(t, err)
+
+	_, err = rateLimitUnaryInterceptor(l)(alice, nil, fetch, handler)
+	st := status.Convert(err)
+	assert.Equal(t, codes.ResourceExhausted, st.Code())
+	var retry *errdetails.RetryInfo
+	for _, detail := range st.Details() {
+		if d, ok := detail.(*errdetails.RetryInfo); ok {
+			retry = d
+		}
+	}
+	if assert.NotNil(t, retry) {
+		assert.Greater(t, retry.GetRetryDelay().AsDuration(), time.Duration(0))
+	}
+
+	// methods that aren't listed are not limited, neither are same-named methods of other services
+	_, err = rateLimitUnaryInterceptor(l)(alice, nil, list, handler)
+	assert.NoError(t, err)
+	_, err = rateLimitUnaryInterceptor(l)(alice, nil, &grpc.UnaryServerInfo{FullMethod: "/other.Service/Fetch"}, handler)
+	assert.NoError(t, err)
+
+	// without auth the caller is its ip
+	_, err = rateLimitUnaryInterceptor(l)(anonymous, nil, fetch, handler)
+	assert.NoError(t, err)
+	assert.Equal(t, "ip:10.0.0.1", l.callerKey(anonymous))
+}
+
+func TestCallerKeyBehindGateway(t *testing.T) {
+	l := newRateLimiter([]string{"/grpcPb.SortService/Fetch"}, 0, 0, 1, 1)
+	l.trustedProxies = []netip.Prefix{netip.MustParsePrefix("127.0.0.1/32")}
+
+	forwarded := func(peerIP, header string) context.Context {
+		ctx := peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP(peerIP), Port: 4000}})
+		return metadata.NewIncomingContext(ctx, metadata.Pairs("x-forwarded-for", header))
+	}
+
+	// the gateway appends the address it got the request from, earlier entries are the client's word
+	assert.Equal(t, "ip:203.0.113.7", l.callerKey(forwarded("127.0.0.1", "203.0.113.7")))
+	assert.Equal(t, "ip:203.0.113.7", l.callerKey(forwarded("127.0.0.1", "10.9.9.9, 203.0.113.7")))
+	// anyone else can't pick their bucket with the header
+	assert.Equal(t, "ip:10.0.0.1", l.callerKey(forwarded("10.0.0.1", "203.0.113.7")))
+}
@@ -0,0 +1,101 @@
+package server
+
+import (
+	"context"
+	"gRPC-server/internal/domain"
+	"gRPC-server/pkg/parseCSV/grpcPb"
+	"unicode/utf8"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func (s *SortServicegRPC) CreateSchedule(ctx context.Context, req *grpcPb.CreateScheduleRequest) (*grpcPb.CreateScheduleResponce, error) {
+	// without a format the schedule gets the one the source has in the registry
+	var format domain.CSVFormat
+	if req.GetFormat() != nil {
+		delimiter, err := parseDelimiter(req.GetFormat().GetDelimiter())
+		if err != nil {
+			return &grpcPb.CreateScheduleResponce{}, toStatus(err)
+		}
+		format = domain.CSVFormat{
+			Delimiter:  delimiter,
+			SkipHeader: req.GetFormat().GetSkipHeader(),
+		}
+	}
+
+	schedule, err := s.Sorting.CreateSchedule(ctx, domain.Schedule{
+		Source: req.GetSource(),
+		Url:    req.GetUrl(),
+		Cron:   req.GetCron(),
+		Format: format,
+	})
+	if err != nil {
+		return &grpcPb.CreateScheduleResponce{}, toStatus(err)
+	}
+	return &grpcPb.CreateScheduleResponce{Schedule: toGrpcSchedule(schedule)}, nil
+}
+
+func (s *SortServicegRPC) ListSchedules(ctx context.Context, req *grpcPb.ListSchedulesRequest) (*grpcPb.ListSchedulesResponce, error) {
+	schedules, err := s.Sorting.ListSchedules(ctx)
+	if err != nil {
+		return &grpcPb.ListSchedulesResponce{}, toStatus(err)
+	}
+
+	schedulesGrpc := make([]*grpcPb.Schedule, len(schedules))
+	for i, schedule := range schedules {
+		schedulesGrpc[i] = toGrpcSchedule(schedule)
+	}
+	return &grpcPb.ListSchedulesResponce{Schedules: schedulesGrpc}, nil
+}
+
+func (s *SortServicegRPC) PauseSchedule(ctx context.Context, req *grpcPb.PauseScheduleRequest) (*grpcPb.PauseScheduleResponce, error) {
+	schedule, err := s.Sorting.PauseSchedule(ctx, req.GetId(), req.GetPaused())
+	if err != nil {
+		return &grpcPb.PauseScheduleResponce{}, toStatus(err)
+	}
+	return &grpcPb.PauseScheduleResponce{Schedule: toGrpcSchedule(schedule)}, nil
+}
+
+func (s *SortServicegRPC) DeleteSchedule(ctx context.Context, req *grpcPb.DeleteScheduleRequest) (*grpcPb.DeleteScheduleResponce, error) {
+	if err := s.Sorting.DeleteSchedule(ctx, req.GetId()); err != nil {
+		return &grpcPb.DeleteScheduleResponce{Status: "Fail"}, toStatus(err)
+	}
+	return &grpcPb.DeleteScheduleResponce{Status: "Success"}, nil
+}
+
+// parseDelimiter accepts a single character, empty means the web-app ';'.
+func parseDelimiter(delimiter string) (rune, error) {
+	if delimiter == "" {
+		return domain.DefaultCSVFormat.Delimiter, nil
+	}
+	r, size := utf8.DecodeRuneInString(delimiter)
+	if size != len(delimiter) || r == utf8.RuneError || r == '"' || r == '\r' || r == '\n' {
+		return 0, domain.NewFieldError("format.delimiter", "must be a single character other than quote or newline")
+	}
+	return r, nil
+}
+
+func toGrpcSchedule(schedule domain.Schedule) *grpcPb.Schedule {
+	out := &grpcPb.Schedule{
+		Id:         schedule.Id.Hex(),
+		Source:     schedule.Source,
+		Url:        schedule.Url,
+		Cron:       schedule.Cron,
+		Format:     toGrpcFormat(schedule.Format),
+		Paused:     schedule.Paused,
+		NextRun:    timestamppb.New(schedule.NextRun),
+		LastStatus: schedule.LastStatus,
+		LastError:  schedule.LastError,
+	}
+	if schedule.LastRun != nil {
+		out.LastRun = timestamppb.New(*schedule.LastRun)
+	}
+	return out
+}
+
+func toGrpcFormat(format domain.CSVFormat) *grpcPb.CSVFormat {
+	return &grpcPb.CSVFormat{
+		Delimiter:  string(format.Delimiter),
+		SkipHeader: format.SkipHeader,
+	}
+}
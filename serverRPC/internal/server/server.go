@@ -3,13 +3,22 @@ package server
 import (
 	"context"
 	"fmt"
+	"gRPC-server/internal/auth"
 	"gRPC-server/pkg/logger"
 	"gRPC-server/pkg/parseCSV/grpcPb"
+	"gRPC-server/pkg/tlsconfig"
 	"log"
 	"net"
+	"time"
 
 	"github.com/spf13/viper"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/admin"
+	"google.golang.org/grpc/credentials"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/keepalive"
+	"google.golang.org/grpc/reflection"
 )
 
 type grpcServer struct {
@@ -17,15 +26,91 @@ type grpcServer struct {
 	logger     *logger.Logger
 	csvService grpcPb.SortServiceServer
 	addr       string
+	tls        *tlsconfig.Reloader
+	health     *healthChecker
+	cleanup    []func()
 }
 
-func NewGrpcServer(csvService grpcPb.SortServiceServer, logger *logger.Logger) *grpcServer {
-	return &grpcServer{
-		grpcServer: grpc.NewServer(),
+// NewGrpcServer builds the server; pinger drives the grpc.health.v1 status when health is enabled.
+func NewGrpcServer(csvService grpcPb.SortServiceServer, pinger Pinger, logger *logger.Logger) (*grpcServer, error) {
+	authorizer, err := auth.NewFromConfig()
+	if err != nil {
+		return nil, fmt.Errorf("grpc auth: %w", err)
+	}
+
+	// recovery goes first so a panic in any interceptor below is recovered too, request id
+	// follows so access logs carry it, auth runs before logging so the access log has the caller
+	unary := []grpc.UnaryServerInterceptor{recoveryUnaryInterceptor(logger), requestIDUnaryInterceptor, metricsUnaryInterceptor}
+	stream := []grpc.StreamServerInterceptor{recoveryStreamInterceptor(logger), requestIDStreamInterceptor, metricsStreamInterceptor}
+	if authorizer != nil {
+		unary = append(unary, authUnaryInterceptor(authorizer, logger))
+		stream = append(stream, authStreamInterceptor(authorizer, logger))
+	}
+	unary = append(unary, loggingUnaryInterceptor(logger))
+	stream = append(stream, loggingStreamInterceptor(logger))
+	// after auth, callers are limited by identity; after logging, rejections show in the access log
+	limiter, err := newRateLimiterFromConfig()
+	if err != nil {
+		return nil, fmt.Errorf("grpc ratelimit: %w", err)
+	}
+	if limiter != nil {
+		unary = append(unary, rateLimitUnaryInterceptor(limiter))
+		stream = append(stream, rateLimitStreamInterceptor(limiter))
+	}
+
+	opts := []grpc.ServerOption{
+		grpc.StatsHandler(otelgrpc.NewServerHandler()),
+		// pkg/client pings every 30s while calls are running, the default policy would drop it
+		grpc.KeepaliveEnforcementPolicy(keepalive.EnforcementPolicy{MinTime: 15 * time.Second}),
+		grpc.ChainUnaryInterceptor(unary...),
+		grpc.ChainStreamInterceptor(stream...),
+	}
+
+	s := &grpcServer{
 		logger:     logger,
 		csvService: csvService,
 		addr:       viper.GetString("grpc.addr"),
 	}
+
+	if viper.GetBool("grpc.tls.enabled") {
+		tlsConfig, reloader, err := tlsconfig.ServerConfig(tlsconfig.ServerOptions{
+			CertFile:           viper.GetString("grpc.tls.cert_file"),
+			KeyFile:            viper.GetString("grpc.tls.key_file"),
+			ClientCAFile:       viper.GetString("grpc.tls.client_ca_file"),
+			OptionalClientCert: viper.GetString("grpc.tls.client_auth") == "verify_if_given",
+		})
+		if err != nil {
+			return nil, fmt.Errorf("grpc tls: %w", err)
+		}
+		go func() {
+			for err := range reloader.Errors() {
+				logger.Errorf("Can't reload tls certificates: %s", err)
+			}
+		}()
+		opts = append(opts, grpc.Creds(credentials.NewTLS(tlsConfig)))
+		s.tls = reloader
+	}
+
+	s.grpcServer = grpc.NewServer(opts...)
+	grpcPb.RegisterSortServiceServer(s.grpcServer, s.csvService)
+
+	if viper.GetBool("grpc.health.enabled") {
+		s.health = newHealthChecker(pinger, logger,
+			viper.GetDuration("grpc.health.interval"), viper.GetDuration("grpc.health.timeout"))
+		healthpb.RegisterHealthServer(s.grpcServer, s.health.server)
+	}
+	if viper.GetBool("grpc.reflection") {
+		reflection.Register(s.grpcServer)
+	}
+	if viper.GetBool("grpc.admin") {
+		cleanup, err := admin.Register(s.grpcServer)
+		if err != nil {
+			return nil, fmt.Errorf("grpc admin: %w", err)
+		}
+		s.cleanup = append(s.cleanup, cleanup)
+	}
+
+	return s, nil
 }
 
 func (s *grpcServer) ListenAndServer() {
@@ -35,7 +120,9 @@ func (s *grpcServer) ListenAndServer() {
 		log.Fatalf("Can't lisnet grpc address: %s", err)
 	}
 
-	grpcPb.RegisterSortServiceServer(s.grpcServer, s.csvService)
+	if s.health != nil {
+		go s.health.run()
+	}
 	fmt.Println("gRPC server has been started")
 
 	if err := s.grpcServer.Serve(listener); err != nil {
@@ -45,6 +132,16 @@ func (s *grpcServer) ListenAndServer() {
 }
 
 func (s *grpcServer) GracefulShutDown(ctx context.Context) error {
+	if s.tls != nil {
+		defer s.tls.Close()
+	}
+	for _, cleanup := range s.cleanup {
+		defer cleanup()
+	}
+	if s.health != nil {
+		s.health.shutdown()
+	}
+
 	done := make(chan struct{})
 	go func() {
 		s.grpcServer.GracefulStop()
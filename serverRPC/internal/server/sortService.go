@@ -2,57 +2,285 @@ package server
 
 import (
 	"context"
+	"fmt"
 	"gRPC-server/internal/domain"
 	"gRPC-server/pkg/logger"
 	"gRPC-server/pkg/parseCSV/grpcPb"
+	"time"
+
+	"github.com/spf13/viper"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
 //go:generate mockgen -source=sortService.go -destination=mocks/mock.go
 type Sorting interface {
 	Fetch(ctx context.Context, req *grpcPb.FetchRequest) (domain.Status, error)
 	List(ctx context.Context, req *grpcPb.ListRequest) ([]domain.Product, error)
+	Export(ctx context.Context, req *grpcPb.ListRequest, send func(domain.Product) error) error
+	GetProduct(ctx context.Context, source string, id int, includeDeleted bool) (domain.Product, error)
+	BatchGetProducts(ctx context.Context, source string, ids []int, includeDeleted bool) ([]domain.Product, error)
+	GetStats(ctx context.Context, filter domain.StatsFilter) (domain.Stats, error)
+	Watch(ctx context.Context, resumeToken string, send func(domain.ProductEvent) error) error
+	DeleteProduct(ctx context.Context, product domain.Product) error
+	RestoreProduct(ctx context.Context, product domain.Product) error
+	PurgeDeleted(ctx context.Context, olderThan time.Time) (int64, error)
+	CreateSchedule(ctx context.Context, schedule domain.Schedule) (domain.Schedule, error)
+	ListSchedules(ctx context.Context) ([]domain.Schedule, error)
+	PauseSchedule(ctx context.Context, id string, paused bool) (domain.Schedule, error)
+	DeleteSchedule(ctx context.Context, id string) error
+	ListSources(ctx context.Context) domain.SourceRegistry
+	DiffFetch(ctx context.Context, req *grpcPb.DiffFetchRequest) (domain.ImportDiff, error)
+	CreateAlertRule(ctx context.Context, rule domain.AlertRule) (domain.AlertRule, error)
+	ListAlertRules(ctx context.Context) ([]domain.AlertRule, error)
+	DeleteAlertRule(ctx context.Context, id string) error
+	ListDeadLetters(ctx context.Context, limit int) ([]domain.DeadLetter, error)
 }
 
+const defaultMaxBatchIds = 1000
+
 type SortServicegRPC struct {
 	grpcPb.UnimplementedSortServiceServer
-	logger *logger.Logger
+	logger      *logger.Logger
+	maxBatchIds int
 	Sorting
 }
 
 func NewSortServerService(sortingService Sorting, logger *logger.Logger) *SortServicegRPC {
+	maxBatchIds := viper.GetInt("grpc.max_batch_ids")
+	if maxBatchIds <= 0 {
+		maxBatchIds = defaultMaxBatchIds
+	}
 	return &SortServicegRPC{
-		logger:  logger,
-		Sorting: sortingService,
+		logger:      logger,
+		maxBatchIds: maxBatchIds,
+		Sorting:     sortingService,
 	}
 }
 
 func (s *SortServicegRPC) Fetch(ctx context.Context, req *grpcPb.FetchRequest) (*grpcPb.FethResponce, error) {
+	// a registered source has its own url
+	if req.GetUrl() == "" && req.GetSource() == "" {
+		return &grpcPb.FethResponce{Status: "Fail"}, toStatus(domain.NewFieldError("url", "must not be empty"))
+	}
+
 	status, err := s.Sorting.Fetch(ctx, req)
 	if err != nil {
 		return &grpcPb.FethResponce{
 			Status: status.Status,
-		}, err
+		}, toStatus(err)
 	}
 	return &grpcPb.FethResponce{
-		Status: status.Status,
+		Status:   status.Status,
+		Rejected: toGrpcRejections(status.Rejected),
 	}, nil
 }
 
 func (s *SortServicegRPC) List(ctx context.Context, req *grpcPb.ListRequest) (*grpcPb.ListResponce, error) {
+	if err := validateListRequest(req); err != nil {
+		return &grpcPb.ListResponce{}, toStatus(err)
+	}
+
 	products, err := s.Sorting.List(ctx, req)
 	if err != nil {
-		return &grpcPb.ListResponce{}, err
+		return &grpcPb.ListResponce{}, toStatus(err)
 	}
 	productsGrpc := make([]*grpcPb.Product, len(products))
 
 	for i, product := range products {
-		productsGrpc[i] = &grpcPb.Product{
-			Id:    int64(product.Id),
-			Name:  product.Name,
-			Price: product.Price.String(),
-		}
+		productsGrpc[i] = toGrpcProduct(product)
 	}
 	return &grpcPb.ListResponce{
 		Product: productsGrpc,
 	}, nil
 }
+
+func (s *SortServicegRPC) GetProduct(ctx context.Context, req *grpcPb.GetProductRequest) (*grpcPb.GetProductResponce, error) {
+	product, err := s.Sorting.GetProduct(ctx, req.GetSource(), int(req.GetId()), req.GetIncludeDeleted())
+	if err != nil {
+		return &grpcPb.GetProductResponce{}, toStatus(err)
+	}
+	return &grpcPb.GetProductResponce{
+		Product: toGrpcProduct(product),
+	}, nil
+}
+
+func (s *SortServicegRPC) BatchGetProducts(ctx context.Context, req *grpcPb.BatchGetProductsRequest) (*grpcPb.BatchGetProductsResponce, error) {
+	if len(req.GetIds()) == 0 {
+		return &grpcPb.BatchGetProductsResponce{}, toStatus(domain.NewFieldError("ids", "must not be empty"))
+	}
+	if len(req.GetIds()) > s.maxBatchIds {
+		return &grpcPb.BatchGetProductsResponce{}, toStatus(domain.NewFieldError("ids", fmt.Sprintf("must not have more than %d ids", s.maxBatchIds)))
+	}
+
+	ids := make([]int, len(req.GetIds()))
+	for i, id := range req.GetIds() {
+		ids[i] = int(id)
+	}
+
+	products, err := s.Sorting.BatchGetProducts(ctx, req.GetSource(), ids, req.GetIncludeDeleted())
+	if err != nil {
+		return &grpcPb.BatchGetProductsResponce{}, toStatus(err)
+	}
+
+	productsGrpc := make([]*grpcPb.Product, len(products))
+	for i, product := range products {
+		productsGrpc[i] = toGrpcProduct(product)
+	}
+	return &grpcPb.BatchGetProductsResponce{
+		Product: productsGrpc,
+	}, nil
+}
+
+func (s *SortServicegRPC) GetStats(ctx context.Context, req *grpcPb.GetStatsRequest) (*grpcPb.GetStatsResponce, error) {
+	filter := domain.StatsFilter{
+		Name:             req.GetName(),
+		HistogramBuckets: req.GetHistogramBuckets(),
+		ChangedDays:      req.GetChangedDays(),
+		TopChanged:       req.GetTopChanged(),
+	}
+
+	var err error
+	if filter.MinPrice, err = parseOptionalDecimal(req.GetMinPrice()); err != nil {
+		return &grpcPb.GetStatsResponce{}, toStatus(domain.NewFieldError("min_price", err.Error()))
+	}
+	if filter.MaxPrice, err = parseOptionalDecimal(req.GetMaxPrice()); err != nil {
+		return &grpcPb.GetStatsResponce{}, toStatus(domain.NewFieldError("max_price", err.Error()))
+	}
+	for _, bound := range req.GetHistogramBounds() {
+		value, err := primitive.ParseDecimal128(bound)
+		if err != nil {
+			return &grpcPb.GetStatsResponce{}, toStatus(domain.NewFieldError("histogram_bounds", err.Error()))
+		}
+		filter.HistogramBounds = append(filter.HistogramBounds, value)
+	}
+
+	stats, err := s.Sorting.GetStats(ctx, filter)
+	if err != nil {
+		return &grpcPb.GetStatsResponce{}, toStatus(err)
+	}
+
+	resp := &grpcPb.GetStatsResponce{
+		Total:           stats.Total,
+		ChangedRecently: stats.ChangedRecently,
+	}
+	if stats.Total > 0 {
+		resp.MinPrice = stats.MinPrice.String()
+		resp.MaxPrice = stats.MaxPrice.String()
+		resp.AvgPrice = stats.AvgPrice.String()
+		resp.MedianPrice = stats.MedianPrice.String()
+	}
+	for _, bucket := range stats.Histogram {
+		resp.Histogram = append(resp.Histogram, &grpcPb.HistogramBucket{
+			LowerBound: bucket.LowerBound.String(),
+			UpperBound: bucket.UpperBound.String(),
+			Count:      bucket.Count,
+		})
+	}
+	for _, changed := range stats.MostChanged {
+		resp.MostChanged = append(resp.MostChanged, &grpcPb.ChangedProduct{
+			Product:      toGrpcProduct(changed.Product),
+			ChangesCount: int32(changed.ChangesCount),
+		})
+	}
+	return resp, nil
+}
+
+// Watch streams product changes; on reconnect clients pass the resume_token of the last event they got.
+func (s *SortServicegRPC) Watch(req *grpcPb.WatchRequest, stream grpcPb.SortService_WatchServer) error {
+	err := s.Sorting.Watch(stream.Context(), req.GetResumeToken(), func(event domain.ProductEvent) error {
+		return stream.Send(&grpcPb.WatchEvent{
+			Type:        watchEventTypes[event.Type],
+			Product:     toGrpcProduct(event.Product),
+			ResumeToken: event.ResumeToken,
+			Time:        timestamppb.New(event.Time),
+		})
+	})
+	return toStatus(err)
+}
+
+var watchEventTypes = map[string]grpcPb.WatchEvent_EventType{
+	domain.EventInsert: grpcPb.WatchEvent_insert,
+	domain.EventUpdate: grpcPb.WatchEvent_update,
+	domain.EventDelete: grpcPb.WatchEvent_delete,
+}
+
+func (s *SortServicegRPC) DeleteProduct(ctx context.Context, req *grpcPb.DeleteProductRequest) (*grpcPb.DeleteProductResponce, error) {
+	if err := s.Sorting.DeleteProduct(ctx, domain.Product{Source: req.GetSource(), Id: int(req.GetId())}); err != nil {
+		return &grpcPb.DeleteProductResponce{Status: "Fail"}, toStatus(err)
+	}
+	return &grpcPb.DeleteProductResponce{Status: "Success"}, nil
+}
+
+func (s *SortServicegRPC) RestoreProduct(ctx context.Context, req *grpcPb.RestoreProductRequest) (*grpcPb.RestoreProductResponce, error) {
+	if err := s.Sorting.RestoreProduct(ctx, domain.Product{Source: req.GetSource(), Id: int(req.GetId())}); err != nil {
+		return &grpcPb.RestoreProductResponce{Status: "Fail"}, toStatus(err)
+	}
+	return &grpcPb.RestoreProductResponce{Status: "Success"}, nil
+}
+
+// PurgeDeleted requires older_than, an empty request must not remove everything soft-deleted.
+func (s *SortServicegRPC) PurgeDeleted(ctx context.Context, req *grpcPb.PurgeDeletedRequest) (*grpcPb.PurgeDeletedResponce, error) {
+	if req.GetOlderThan() == nil {
+		return &grpcPb.PurgeDeletedResponce{}, toStatus(domain.NewFieldError("older_than", "must be set"))
+	}
+
+	purged, err := s.Sorting.PurgeDeleted(ctx, req.GetOlderThan().AsTime())
+	if err != nil {
+		return &grpcPb.PurgeDeletedResponce{}, toStatus(err)
+	}
+	return &grpcPb.PurgeDeletedResponce{Purged: purged}, nil
+}
+
+func toGrpcRejections(rejected []domain.Rejection) []*grpcPb.RowRejection {
+	if len(rejected) == 0 {
+		return nil
+	}
+	out := make([]*grpcPb.RowRejection, len(rejected))
+	for i, rejection := range rejected {
+		out[i] = &grpcPb.RowRejection{
+			Id:     int64(rejection.Id),
+			Name:   rejection.Name,
+			Rule:   rejection.Rule,
+			Reason: rejection.Reason,
+		}
+	}
+	return out
+}
+
+func toGrpcProduct(product domain.Product) *grpcPb.Product {
+	productGrpc := &grpcPb.Product{
+		Id:     int64(product.Id),
+		Name:   product.Name,
+		Price:  product.Price.String(),
+		Source: product.Source,
+	}
+	if product.DeletedAt != nil {
+		productGrpc.DeletedAt = timestamppb.New(*product.DeletedAt)
+	}
+	return productGrpc
+}
+
+func parseOptionalDecimal(value string) (*primitive.Decimal128, error) {
+	if value == "" {
+		return nil, nil
+	}
+	parsed, err := primitive.ParseDecimal128(value)
+	if err != nil {
+		return nil, err
+	}
+	return &parsed, nil
+}
+
+func validateListRequest(req *grpcPb.ListRequest) error {
+	if req.GetSortAsc() != 1 && req.GetSortAsc() != -1 {
+		return domain.NewFieldError("sort_asc", "must be 1 (ascending) or -1 (descending)")
+	}
+	if req.GetPagingOffset() < 0 {
+		return domain.NewFieldError("paging_offset", "must not be negative")
+	}
+	if req.GetPagingLimit() < 0 {
+		return domain.NewFieldError("paging_limit", "must not be negative")
+	}
+	return nil
+}
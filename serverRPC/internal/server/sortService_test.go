@@ -8,10 +8,15 @@ import (
 	"gRPC-server/pkg/logger"
 	"gRPC-server/pkg/parseCSV/grpcPb"
 	"testing"
+	"time"
 
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
 func TestFetch(t *testing.T) {
@@ -59,6 +64,22 @@ func TestFetch(t *testing.T) {
 			},
 			isErr: true,
 		},
+		{
+			name: "Source without url",
+			req: &grpcPb.FetchRequest{
+				Source: "web-app",
+			},
+			ctx: context.Background(),
+			want: &grpcPb.FethResponce{
+				Status: "Success",
+			},
+			mockBehavior: func(m *mock_server.MockSorting, ctx context.Context, req *grpcPb.FetchRequest) {
+				m.EXPECT().Fetch(ctx, req).Return(domain.Status{
+					Status: "Success",
+				}, nil)
+			},
+			isErr: false,
+		},
 	}
 
 	for i := range testTables {
@@ -195,3 +216,518 @@ func TestList(t *testing.T) {
 		})
 	}
 }
+
+func TestDeleteProduct(t *testing.T) {
+	logger := logger.GetLogger()
+
+	type mockBehavior func(m *mock_server.MockSorting, ctx context.Context, product domain.Product)
+
+	testTables := []struct {
+		name         string
+		ctx          context.Context
+		req          *grpcPb.DeleteProductRequest
+		mockBehavior mockBehavior
+		want         *grpcPb.DeleteProductResponce
+		code         codes.Code
+	}{
+		{
+			name: "Valid",
+			ctx:  context.Background(),
+			req:  &grpcPb.DeleteProductRequest{Id: 1},
+			mockBehavior: func(m *mock_server.MockSorting, ctx context.Context, product domain.Product) {
+				m.EXPECT().DeleteProduct(ctx, product).Return(nil)
+			},
+			want: &grpcPb.DeleteProductResponce{Status: "Success"},
+			code: codes.OK,
+		},
+		{
+			name: "Not found",
+			ctx:  context.Background(),
+			req:  &grpcPb.DeleteProductRequest{Id: 2},
+			mockBehavior: func(m *mock_server.MockSorting, ctx context.Context, product domain.Product) {
+				m.EXPECT().DeleteProduct(ctx, product).Return(domain.ErrProductNotFound)
+			},
+			want: &grpcPb.DeleteProductResponce{Status: "Fail"},
+			code: codes.NotFound,
+		},
+	}
+
+	for i := range testTables {
+		table := &testTables[i]
+		t.Run(table.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			mockSortingServiceServer := mock_server.NewMockSorting(c)
+
+			serviceServer := NewSortServerService(mockSortingServiceServer, logger)
+			table.mockBehavior(mockSortingServiceServer, table.ctx, domain.Product{Id: int(table.req.GetId())})
+			got, err := serviceServer.DeleteProduct(table.ctx, table.req)
+
+			assert.Equal(t, table.code, status.Code(err))
+			assert.Equal(t, table.want, got)
+		})
+	}
+}
+
+func TestPurgeDeleted(t *testing.T) {
+	logger := logger.GetLogger()
+	olderThan := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	c := gomock.NewController(t)
+	defer c.Finish()
+
+	mockSortingServiceServer := mock_server.NewMockSorting(c)
+	mockSortingServiceServer.EXPECT().PurgeDeleted(gomock.Any(), olderThan).Return(int64(3), nil)
+
+	serviceServer := NewSortServerService(mockSortingServiceServer, logger)
+	got, err := serviceServer.PurgeDeleted(context.Background(), &grpcPb.PurgeDeletedRequest{
+		OlderThan: timestamppb.New(olderThan),
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, &grpcPb.PurgeDeletedResponce{Purged: 3}, got)
+
+	_, err = serviceServer.PurgeDeleted(context.Background(), &grpcPb.PurgeDeletedRequest{})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestBatchGetProducts(t *testing.T) {
+	logger := logger.GetLogger()
+
+	type mockBehavior func(m *mock_server.MockSorting, ctx context.Context)
+
+	testTables := []struct {
+		name         string
+		ctx          context.Context
+		req          *grpcPb.BatchGetProductsRequest
+		mockBehavior mockBehavior
+		want         *grpcPb.BatchGetProductsResponce
+		code         codes.Code
+	}{
+		{
+			name: "Valid",
+			ctx:  context.Background(),
+			req:  &grpcPb.BatchGetProductsRequest{Ids: []int64{2, 1}},
+			mockBehavior: func(m *mock_server.MockSorting, ctx context.Context) {
+				m.EXPECT().BatchGetProducts(ctx, "", []int{2, 1}, false).Return([]domain.Product{
+					{
+						Id:   2,
+						Name: "Name2",
+						Price: func() primitive.Decimal128 {
+							got, _ := primitive.ParseDecimal128("60.00")
+							return got
+						}(),
+					},
+					{
+						Id:   1,
+						Name: "name",
+						Price: func() primitive.Decimal128 {
+							got, _ := primitive.ParseDecimal128("50.00")
+							return got
+						}(),
+					},
+				}, nil)
+			},
+			want: &grpcPb.BatchGetProductsResponce{
+				Product: []*grpcPb.Product{
+					{Id: 2, Name: "Name2", Price: "60.00"},
+					{Id: 1, Name: "name", Price: "50.00"},
+				},
+			},
+			code: codes.OK,
+		},
+		{
+			name: "Not found",
+			ctx:  context.Background(),
+			req:  &grpcPb.BatchGetProductsRequest{Ids: []int64{7}},
+			mockBehavior: func(m *mock_server.MockSorting, ctx context.Context) {
+				m.EXPECT().BatchGetProducts(ctx, "", []int{7}, false).Return(nil, domain.ErrProductNotFound)
+			},
+			want: &grpcPb.BatchGetProductsResponce{},
+			code: codes.NotFound,
+		},
+		{
+			name:         "Empty ids",
+			ctx:          context.Background(),
+			req:          &grpcPb.BatchGetProductsRequest{},
+			mockBehavior: func(m *mock_server.MockSorting, ctx context.Context) {},
+			want:         &grpcPb.BatchGetProductsResponce{},
+			code:         codes.InvalidArgument,
+		},
+		{
+			name:         "Too many ids",
+			ctx:          context.Background(),
+			req:          &grpcPb.BatchGetProductsRequest{Ids: make([]int64, defaultMaxBatchIds+1)},
+			mockBehavior: func(m *mock_server.MockSorting, ctx context.Context) {},
+			want:         &grpcPb.BatchGetProductsResponce{},
+			code:         codes.InvalidArgument,
+		},
+	}
+
+	for i := range testTables {
+		table := &testTables[i]
+		t.Run(table.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			mockSortingServiceServer := mock_server.NewMockSorting(c)
+
+			serviceServer := NewSortServerService(mockSortingServiceServer, logger)
+			table.mockBehavior(mockSortingServiceServer, table.ctx)
+			got, err := serviceServer.BatchGetProducts(table.ctx, table.req)
+
+			assert.Equal(t, table.code, status.Code(err))
+			assert.Equal(t, table.want, got)
+		})
+	}
+}
+
+func TestGetStats(t *testing.T) {
+	logger := logger.GetLogger()
+	price := func(v string) primitive.Decimal128 {
+		got, _ := primitive.ParseDecimal128(v)
+		return got
+	}
+
+	t.Run("Valid", func(t *testing.T) {
+		c := gomock.NewController(t)
+		defer c.Finish()
+
+		min := price("1")
+		mockSortingServiceServer := mock_server.NewMockSorting(c)
+		mockSortingServiceServer.EXPECT().GetStats(gomock.Any(), domain.StatsFilter{
+			Name:            "name",
+			MinPrice:        &min,
+			HistogramBounds: []primitive.Decimal128{price("0"), price("100")},
+			ChangedDays:     3,
+		}).Return(domain.Stats{
+			Total:           2,
+			MinPrice:        price("50.00"),
+			MaxPrice:        price("70.00"),
+			AvgPrice:        price("60.00"),
+			MedianPrice:     price("60.00"),
+			Histogram:       []domain.HistogramBucket{{LowerBound: price("0"), UpperBound: price("100"), Count: 2}},
+			ChangedRecently: 1,
+			MostChanged: []domain.ProductChanges{
+				{Product: domain.Product{Id: 1, Name: "name", Price: price("50.00")}, ChangesCount: 4},
+			},
+		}, nil)
+
+		serviceServer := NewSortServerService(mockSortingServiceServer, logger)
+		got, err := serviceServer.GetStats(context.Background(), &grpcPb.GetStatsRequest{
+			Name:            "name",
+			MinPrice:        "1",
+			HistogramBounds: []string{"0", "100"},
+			ChangedDays:     3,
+		})
+
+		assert.NoError(t, err)
+		assert.Equal(t, &grpcPb.GetStatsResponce{
+			Total:           2,
+			MinPrice:        "50.00",
+			MaxPrice:        "70.00",
+			AvgPrice:        "60.00",
+			MedianPrice:     "60.00",
+			Histogram:       []*grpcPb.HistogramBucket{{LowerBound: "0", UpperBound: "100", Count: 2}},
+			ChangedRecently: 1,
+			MostChanged: []*grpcPb.ChangedProduct{
+				{Product: &grpcPb.Product{Id: 1, Name: "name", Price: "50.00"}, ChangesCount: 4},
+			},
+		}, got)
+	})
+
+	t.Run("Invalid price", func(t *testing.T) {
+		c := gomock.NewController(t)
+		defer c.Finish()
+
+		serviceServer := NewSortServerService(mock_server.NewMockSorting(c), logger)
+		_, err := serviceServer.GetStats(context.Background(), &grpcPb.GetStatsRequest{MaxPrice: "abc"})
+
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+}
+
+type watchStream struct {
+	grpc.ServerStream
+	ctx    context.Context
+	events []*grpcPb.WatchEvent
+}
+
+func (w *watchStream) Context() context.Context {
+	return w.ctx
+}
+
+func (w *watchStream) Send(event *grpcPb.WatchEvent) error {
+	w.events = append(w.events, event)
+	return nil
+}
+
+func TestWatch(t *testing.T) {
+	logger := logger.GetLogger()
+	eventTime := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	t.Run("Streams events", func(t *testing.T) {
+		c := gomock.NewController(t)
+		defer c.Finish()
+
+		mockSortingServiceServer := mock_server.NewMockSorting(c)
+		mockSortingServiceServer.EXPECT().Watch(gomock.Any(), "cs:1", gomock.Any()).DoAndReturn(
+			func(ctx context.Context, resumeToken string, send func(domain.ProductEvent) error) error {
+				if err := send(domain.ProductEvent{Type: domain.EventInsert, Product: domain.Product{Id: 1, Name: "name"}, ResumeToken: "cs:2", Time: eventTime}); err != nil {
+					return err
+				}
+				return send(domain.ProductEvent{Type: domain.EventDelete, Product: domain.Product{Id: 1, Name: "name"}, ResumeToken: "cs:3", Time: eventTime})
+			})
+
+		stream := &watchStream{ctx: context.Background()}
+		serviceServer := NewSortServerService(mockSortingServiceServer, logger)
+		err := serviceServer.Watch(&grpcPb.WatchRequest{ResumeToken: "cs:1"}, stream)
+
+		assert.NoError(t, err)
+		assert.Len(t, stream.events, 2)
+		assert.Equal(t, grpcPb.WatchEvent_insert, stream.events[0].GetType())
+		assert.Equal(t, "cs:2", stream.events[0].GetResumeToken())
+		assert.Equal(t, grpcPb.WatchEvent_delete, stream.events[1].GetType())
+		assert.Equal(t, int64(1), stream.events[1].GetProduct().GetId())
+	})
+
+	t.Run("Bad resume token", func(t *testing.T) {
+		c := gomock.NewController(t)
+		defer c.Finish()
+
+		mockSortingServiceServer := mock_server.NewMockSorting(c)
+		mockSortingServiceServer.EXPECT().Watch(gomock.Any(), "bad", gomock.Any()).Return(domain.ErrInvalidArgument)
+
+		serviceServer := NewSortServerService(mockSortingServiceServer, logger)
+		err := serviceServer.Watch(&grpcPb.WatchRequest{ResumeToken: "bad"}, &watchStream{ctx: context.Background()})
+
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+}
+
+func TestListValidation(t *testing.T) {
+	logger := logger.GetLogger()
+
+	for _, req := range []*grpcPb.ListRequest{
+		{SortAsc: 0, PagingLimit: 10},
+		{SortAsc: 1, PagingOffset: -1},
+		{SortAsc: -1, PagingLimit: -5},
+	} {
+		c := gomock.NewController(t)
+
+		serviceServer := NewSortServerService(mock_server.NewMockSorting(c), logger)
+		_, err := serviceServer.List(context.Background(), req)
+
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+		c.Finish()
+	}
+}
+
+func TestCreateSchedule(t *testing.T) {
+	logger := logger.GetLogger()
+	id := primitive.NewObjectID()
+	next := time.Date(2025, 1, 1, 11, 0, 0, 0, time.UTC)
+
+	testTables := []struct {
+		name         string
+		req          *grpcPb.CreateScheduleRequest
+		mockBehavior func(m *mock_server.MockSorting)
+		want         *grpcPb.CreateScheduleResponce
+		code         codes.Code
+	}{
+		{
+			name: "Valid",
+			req: &grpcPb.CreateScheduleRequest{
+				Url:    "http://example.com/products.csv",
+				Cron:   "@hourly",
+				Format: &grpcPb.CSVFormat{Delimiter: ",", SkipHeader: true},
+			},
+			mockBehavior: func(m *mock_server.MockSorting) {
+				m.EXPECT().CreateSchedule(gomock.Any(), domain.Schedule{
+					Url:    "http://example.com/products.csv",
+					Cron:   "@hourly",
+					Format: domain.CSVFormat{Delimiter: ',', SkipHeader: true},
+				}).Return(domain.Schedule{
+					Id:      id,
+					Url:     "http://example.com/products.csv",
+					Cron:    "@hourly",
+					Format:  domain.CSVFormat{Delimiter: ',', SkipHeader: true},
+					NextRun: next,
+				}, nil)
+			},
+			want: &grpcPb.CreateScheduleResponce{Schedule: &grpcPb.Schedule{
+				Id:      id.Hex(),
+				Url:     "http://example.com/products.csv",
+				Cron:    "@hourly",
+				Format:  &grpcPb.CSVFormat{Delimiter: ",", SkipHeader: true},
+				NextRun: timestamppb.New(next),
+			}},
+			code: codes.OK,
+		},
+		{
+			name: "Invalid delimiter",
+			req: &grpcPb.CreateScheduleRequest{
+				Url:    "http://example.com/products.csv",
+				Cron:   "@hourly",
+				Format: &grpcPb.CSVFormat{Delimiter: ";;"},
+			},
+			mockBehavior: func(m *mock_server.MockSorting) {},
+			want:         &grpcPb.CreateScheduleResponce{},
+			code:         codes.InvalidArgument,
+		},
+	}
+
+	for _, table := range testTables {
+		t.Run(table.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			mockSortingServiceServer := mock_server.NewMockSorting(c)
+			table.mockBehavior(mockSortingServiceServer)
+
+			serviceServer := NewSortServerService(mockSortingServiceServer, logger)
+			got, err := serviceServer.CreateSchedule(context.Background(), table.req)
+
+			assert.Equal(t, table.code, status.Code(err))
+			assert.Equal(t, table.want, got)
+		})
+	}
+}
+
+func TestDeleteSchedule(t *testing.T) {
+	logger := logger.GetLogger()
+
+	c := gomock.NewController(t)
+	defer c.Finish()
+
+	mockSortingServiceServer := mock_server.NewMockSorting(c)
+	mockSortingServiceServer.EXPECT().DeleteSchedule(gomock.Any(), "missing").Return(domain.ErrScheduleNotFound)
+
+	serviceServer := NewSortServerService(mockSortingServiceServer, logger)
+	got, err := serviceServer.DeleteSchedule(context.Background(), &grpcPb.DeleteScheduleRequest{Id: "missing"})
+
+	assert.Equal(t, codes.NotFound, status.Code(err))
+	assert.Equal(t, &grpcPb.DeleteScheduleResponce{Status: "Fail"}, got)
+}
+
+func TestAlertRules(t *testing.T) {
+	logger := logger.GetLogger()
+	id := primitive.NewObjectID()
+	created := time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC)
+	rule := domain.AlertRule{
+		Id:               id,
+		Name:             "milk",
+		MinChangePercent: 5,
+		Direction:        domain.DirectionDown,
+		WebhookUrl:       "https://hooks.example.com/prices",
+		Secret:           "key",
+		CreatedAt:        created,
+	}
+
+	c := gomock.NewController(t)
+	defer c.Finish()
+
+	mockSortingServiceServer := mock_server.NewMockSorting(c)
+	mockSortingServiceServer.EXPECT().CreateAlertRule(gomock.Any(), domain.AlertRule{
+		Name:             "milk",
+		MinChangePercent: 5,
+		Direction:        domain.DirectionDown,
+		WebhookUrl:       "https://hooks.example.com/prices",
+	}).Return(rule, nil)
+	mockSortingServiceServer.EXPECT().ListAlertRules(gomock.Any()).Return([]domain.AlertRule{rule}, nil)
+
+	serviceServer := NewSortServerService(mockSortingServiceServer, logger)
+	want := &grpcPb.AlertRule{
+		Id:               id.Hex(),
+		Name:             "milk",
+		MinChangePercent: 5,
+		Direction:        domain.DirectionDown,
+		WebhookUrl:       "https://hooks.example.com/prices",
+		CreatedAt:        timestamppb.New(created),
+	}
+
+	got, err := serviceServer.ListAlertRules(context.Background(), &grpcPb.ListAlertRulesRequest{})
+	assert.NoError(t, err)
+	assert.Equal(t, &grpcPb.ListAlertRulesResponce{Rules: []*grpcPb.AlertRule{want}}, got)
+
+	// only the create response shows the secret
+	createdGrpc, err := serviceServer.CreateAlertRule(context.Background(), &grpcPb.CreateAlertRuleRequest{Rule: &grpcPb.AlertRule{
+		Name:             "milk",
+		MinChangePercent: 5,
+		Direction:        domain.DirectionDown,
+		WebhookUrl:       "https://hooks.example.com/prices",
+	}})
+	assert.NoError(t, err)
+	assert.Equal(t, "key", createdGrpc.GetRule().GetSecret())
+}
+
+func TestListSources(t *testing.T) {
+	logger := logger.GetLogger()
+
+	c := gomock.NewController(t)
+	defer c.Finish()
+
+	mockSortingServiceServer := mock_server.NewMockSorting(c)
+	mockSortingServiceServer.EXPECT().ListSources(gomock.Any()).Return(domain.SourceRegistry{
+		Sources: []domain.Source{
+			{Name: "web-app", Url: "http://web-app:8085/products/", Format: domain.DefaultCSVFormat, Priority: 10},
+		},
+		Default: "web-app",
+		Merge:   domain.MergePriority,
+	})
+
+	serviceServer := NewSortServerService(mockSortingServiceServer, logger)
+	got, err := serviceServer.ListSources(context.Background(), &grpcPb.ListSourcesRequest{})
+
+	assert.NoError(t, err)
+	assert.Equal(t, &grpcPb.ListSourcesResponce{
+		Sources: []*grpcPb.Source{{
+			Name:     "web-app",
+			Url:      "http://web-app:8085/products/",
+			Format:   &grpcPb.CSVFormat{Delimiter: ";"},
+			Priority: 10,
+		}},
+		DefaultSource: "web-app",
+		MergeStrategy: domain.MergePriority,
+	}, got)
+}
+
+func TestDiffFetch(t *testing.T) {
+	logger := logger.GetLogger()
+	price := func(v string) primitive.Decimal128 {
+		got, _ := primitive.ParseDecimal128(v)
+		return got
+	}
+	req := &grpcPb.DiffFetchRequest{Source: "web-app", Sync: true}
+
+	c := gomock.NewController(t)
+	defer c.Finish()
+
+	mockSortingServiceServer := mock_server.NewMockSorting(c)
+	mockSortingServiceServer.EXPECT().DiffFetch(gomock.Any(), req).Return(domain.ImportDiff{
+		Source:  "web-app",
+		Created: []domain.Product{{Source: "web-app", Id: 3, Name: "new", Price: price("30.00")}},
+		Updated: []domain.ProductUpdate{{
+			Old: domain.Product{Source: "web-app", Id: 1, Name: "name", Price: price("10.00")},
+			New: domain.Product{Source: "web-app", Id: 1, Name: "name", Price: price("15.00")},
+		}},
+		Deleted:   []domain.Product{{Source: "web-app", Id: 2, Name: "gone", Price: price("20.00")}},
+		Unchanged: 4,
+	}, nil)
+
+	serviceServer := NewSortServerService(mockSortingServiceServer, logger)
+	got, err := serviceServer.DiffFetch(context.Background(), req)
+
+	assert.NoError(t, err)
+	assert.Equal(t, &grpcPb.DiffFetchResponce{
+		Source:  "web-app",
+		Created: []*grpcPb.Product{{Source: "web-app", Id: 3, Name: "new", Price: "30.00"}},
+		Updated: []*grpcPb.ProductDiff{{
+			Old: &grpcPb.Product{Source: "web-app", Id: 1, Name: "name", Price: "10.00"},
+			New: &grpcPb.Product{Source: "web-app", Id: 1, Name: "name", Price: "15.00"},
+		}},
+		Deleted:   []*grpcPb.Product{{Source: "web-app", Id: 2, Name: "gone", Price: "20.00"}},
+		Unchanged: 4,
+	}, got)
+}
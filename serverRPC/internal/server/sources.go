@@ -0,0 +1,25 @@
+package server
+
+import (
+	"context"
+	"gRPC-server/pkg/parseCSV/grpcPb"
+)
+
+func (s *SortServicegRPC) ListSources(ctx context.Context, req *grpcPb.ListSourcesRequest) (*grpcPb.ListSourcesResponce, error) {
+	registry := s.Sorting.ListSources(ctx)
+
+	sources := make([]*grpcPb.Source, len(registry.Sources))
+	for i, source := range registry.Sources {
+		sources[i] = &grpcPb.Source{
+			Name:     source.Name,
+			Url:      source.Url,
+			Format:   toGrpcFormat(source.Format),
+			Priority: int32(source.Priority),
+		}
+	}
+	return &grpcPb.ListSourcesResponce{
+		Sources:       sources,
+		DefaultSource: registry.Default,
+		MergeStrategy: registry.Merge,
+	}, nil
+}
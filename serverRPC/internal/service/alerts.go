@@ -0,0 +1,85 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"gRPC-server/internal/domain"
+	"net/url"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	defaultDeadLetters = 100
+	maxDeadLetters     = 1000
+)
+
+// CreateAlertRule validates the rule and stores it; a rule without a secret gets a generated one,
+// the caller sees it only in this response.
+func (s *Service) CreateAlertRule(ctx context.Context, rule domain.AlertRule) (domain.AlertRule, error) {
+	if rule.Name == "" {
+		return domain.AlertRule{}, domain.NewFieldError("name", "is required")
+	}
+	if u, err := url.Parse(rule.WebhookUrl); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return domain.AlertRule{}, domain.NewFieldError("webhook_url", "must be an http or https url")
+	}
+	switch rule.Direction {
+	case "", domain.DirectionUp, domain.DirectionDown:
+	default:
+		return domain.AlertRule{}, domain.NewFieldError("direction", "must be up, down or empty")
+	}
+	if rule.MinChangePercent < 0 {
+		return domain.AlertRule{}, domain.NewFieldError("min_change_percent", "must not be negative")
+	}
+	if rule.ProductId < 0 {
+		return domain.AlertRule{}, domain.NewFieldError("product_id", "must not be negative")
+	}
+	if rule.Source != "" {
+		if _, err := s.sources.Name(rule.Source); err != nil {
+			return domain.AlertRule{}, err
+		}
+	}
+
+	if rule.Secret == "" {
+		secret := make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			return domain.AlertRule{}, err
+		}
+		rule.Secret = hex.EncodeToString(secret)
+	}
+	rule.Id = primitive.NilObjectID
+	rule.CreatedAt = time.Now()
+
+	return s.Sorting.CreateAlertRule(ctx, rule)
+}
+
+func (s *Service) DeleteAlertRule(ctx context.Context, id string) error {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return domain.NewFieldError("id", "must be an alert rule id")
+	}
+	return s.Sorting.DeleteAlertRule(ctx, oid)
+}
+
+// ListDeadLetters returns the newest failed deliveries, 100 unless limit says otherwise.
+func (s *Service) ListDeadLetters(ctx context.Context, limit int) ([]domain.DeadLetter, error) {
+	switch {
+	case limit < 0:
+		return nil, domain.NewFieldError("limit", "must not be negative")
+	case limit == 0:
+		limit = defaultDeadLetters
+	case limit > maxDeadLetters:
+		limit = maxDeadLetters
+	}
+	return s.Sorting.ListDeadLetters(ctx, limit)
+}
+
+// Shutdown waits for queued webhook deliveries.
+func (s *Service) Shutdown(ctx context.Context) error {
+	if s.alerts == nil {
+		return nil
+	}
+	return s.alerts.Shutdown(ctx)
+}
@@ -0,0 +1,101 @@
+package service
+
+import (
+	"context"
+	"gRPC-server/internal/domain"
+	mock_service "gRPC-server/internal/service/mocks"
+	"gRPC-server/pkg/logger"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateAlertRule(t *testing.T) {
+	logger := logger.GetLogger()
+
+	testTables := []struct {
+		name  string
+		rule  domain.AlertRule
+		field string
+	}{
+		{
+			name: "Valid",
+			rule: domain.AlertRule{Name: "milk", Source: "default", WebhookUrl: "https://hooks.example.com/prices", Direction: domain.DirectionDown},
+		},
+		{
+			name:  "Empty name",
+			rule:  domain.AlertRule{WebhookUrl: "https://hooks.example.com/prices"},
+			field: "name",
+		},
+		{
+			name:  "Not http",
+			rule:  domain.AlertRule{Name: "milk", WebhookUrl: "ftp://hooks.example.com/prices"},
+			field: "webhook_url",
+		},
+		{
+			name:  "Unknown direction",
+			rule:  domain.AlertRule{Name: "milk", WebhookUrl: "https://hooks.example.com/prices", Direction: "sideways"},
+			field: "direction",
+		},
+		{
+			name:  "Negative threshold",
+			rule:  domain.AlertRule{Name: "milk", WebhookUrl: "https://hooks.example.com/prices", MinChangePercent: -1},
+			field: "min_change_percent",
+		},
+		{
+			name:  "Unknown source",
+			rule:  domain.AlertRule{Name: "milk", WebhookUrl: "https://hooks.example.com/prices", Source: "shop"},
+			field: "source",
+		},
+	}
+
+	for _, table := range testTables {
+		t.Run(table.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			mockService := mock_service.NewMockSorting(c)
+			service := NewService(mockService, testSources(), logger)
+			if table.field == "" {
+				mockService.EXPECT().CreateAlertRule(gomock.Any(), gomock.Any()).DoAndReturn(
+					func(ctx context.Context, rule domain.AlertRule) (domain.AlertRule, error) {
+						return rule, nil
+					})
+			}
+
+			got, err := service.CreateAlertRule(context.Background(), table.rule)
+			if table.field != "" {
+				var fieldErr *domain.FieldError
+				if assert.ErrorAs(t, err, &fieldErr) {
+					assert.Equal(t, table.field, fieldErr.Field)
+				}
+				return
+			}
+			assert.NoError(t, err)
+			assert.Len(t, got.Secret, 64)
+			assert.False(t, got.CreatedAt.IsZero())
+		})
+	}
+}
+
+func TestListDeadLetters(t *testing.T) {
+	logger := logger.GetLogger()
+
+	c := gomock.NewController(t)
+	defer c.Finish()
+
+	mockService := mock_service.NewMockSorting(c)
+	mockService.EXPECT().ListDeadLetters(gomock.Any(), 100).Return(nil, nil)
+	mockService.EXPECT().ListDeadLetters(gomock.Any(), 1000).Return(nil, nil)
+	service := NewService(mockService, testSources(), logger)
+
+	_, err := service.ListDeadLetters(context.Background(), 0)
+	assert.NoError(t, err)
+	_, err = service.ListDeadLetters(context.Background(), 5000)
+	assert.NoError(t, err)
+
+	var fieldErr *domain.FieldError
+	_, err = service.ListDeadLetters(context.Background(), -1)
+	assert.ErrorAs(t, err, &fieldErr)
+}
@@ -0,0 +1,100 @@
+package service
+
+import (
+	"context"
+	"gRPC-server/internal/domain"
+	"gRPC-server/pkg/parseCSV/grpcPb"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// DiffFetch downloads the CSV like Fetch does and compares it with what the source has stored,
+// nothing is written. With req.Sync products of the source missing from the CSV are reported
+// as deleted.
+func (s *Service) DiffFetch(ctx context.Context, req *grpcPb.DiffFetchRequest) (domain.ImportDiff, error) {
+	source, err := s.sources.Resolve(req.GetSource(), req.GetUrl())
+	if err != nil {
+		return domain.ImportDiff{}, err
+	}
+
+	ctx, span := tracer.Start(ctx, "import.diff", trace.WithAttributes(
+		attribute.String("import.url", source.Url),
+		attribute.String("import.source", source.Name),
+	))
+	defer span.End()
+
+	incoming, rejected, err := s.download(ctx, source.Url, source.Format)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return domain.ImportDiff{}, err
+	}
+	incoming, rejected = s.prepare(ctx, source, incoming, rejected)
+
+	// the whole source in one query, deleted products included: Fetch updates those too
+	stored, err := s.Sorting.List(ctx, &grpcPb.ListRequest{
+		SortField:      grpcPb.ListRequest_id,
+		SortAsc:        1,
+		IncludeDeleted: true,
+		Source:         source.Name,
+	}, s.sources.MergeRule())
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return domain.ImportDiff{}, err
+	}
+
+	result := diff(stored, incoming, req.GetSync())
+	result.Source = source.Name
+	result.Rejected = rejected
+	span.SetAttributes(
+		attribute.Int("import.created", len(result.Created)),
+		attribute.Int("import.updated", len(result.Updated)),
+		attribute.Int("import.deleted", len(result.Deleted)),
+	)
+	return result, nil
+}
+
+// diff mirrors apply: rows with a stored id are updates when changed or soft-deleted, the rest are created.
+func diff(stored, incoming []domain.Product, sync bool) domain.ImportDiff {
+	var result domain.ImportDiff
+
+	byId := make(map[int]domain.Product, len(stored))
+	for _, product := range stored {
+		byId[product.Id] = product
+	}
+
+	seen := make(map[int]bool, len(incoming))
+	for _, product := range incoming {
+		seen[product.Id] = true
+		exists, ok := byId[product.Id]
+		switch {
+		case !ok:
+			result.Created = append(result.Created, product)
+		case changed(exists, product) || exists.DeletedAt != nil:
+			updated := exists
+			updated.Name = product.Name
+			updated.Price = product.Price
+			updated.DeletedAt = nil
+			result.Updated = append(result.Updated, domain.ProductUpdate{Old: exists, New: updated})
+		default:
+			result.Unchanged++
+		}
+	}
+
+	if sync {
+		for _, product := range stored {
+			if product.DeletedAt == nil && !seen[product.Id] {
+				result.Deleted = append(result.Deleted, product)
+			}
+		}
+	}
+	return result
+}
+
+// changed tells whether an import writes product over exists.
+func changed(exists, product domain.Product) bool {
+	return exists.Price != product.Price || exists.Name != product.Name
+}
@@ -0,0 +1,148 @@
+package service
+
+import (
+	"context"
+	"gRPC-server/internal/domain"
+	"gRPC-server/internal/rules"
+	mock_service "gRPC-server/internal/service/mocks"
+	"gRPC-server/pkg/logger"
+	"gRPC-server/pkg/parseCSV/grpcPb"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestDiff(t *testing.T) {
+	price := func(v string) primitive.Decimal128 {
+		got, _ := primitive.ParseDecimal128(v)
+		return got
+	}
+	deletedAt := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	stored := []domain.Product{
+		{Id: 1, Name: "same", Price: price("10.00")},
+		{Id: 2, Name: "price", Price: price("20.00")},
+		{Id: 3, Name: "old name", Price: price("30.00")},
+		{Id: 4, Name: "gone", Price: price("40.00")},
+		{Id: 5, Name: "already deleted", Price: price("50.00"), DeletedAt: &deletedAt},
+		{Id: 7, Name: "restored", Price: price("70.00"), DeletedAt: &deletedAt},
+	}
+	incoming := []domain.Product{
+		{Id: 1, Name: "same", Price: price("10.00")},
+		{Id: 2, Name: "price", Price: price("25.00")},
+		{Id: 3, Name: "new name", Price: price("30.00")},
+		{Id: 6, Name: "new", Price: price("60.00")},
+		{Id: 7, Name: "restored", Price: price("70.00")},
+	}
+
+	want := domain.ImportDiff{
+		Created: []domain.Product{{Id: 6, Name: "new", Price: price("60.00")}},
+		Updated: []domain.ProductUpdate{
+			{Old: stored[1], New: domain.Product{Id: 2, Name: "price", Price: price("25.00")}},
+			{Old: stored[2], New: domain.Product{Id: 3, Name: "new name", Price: price("30.00")}},
+			{Old: stored[5], New: domain.Product{Id: 7, Name: "restored", Price: price("70.00")}},
+		},
+		Unchanged: 1,
+	}
+	assert.Equal(t, want, diff(stored, incoming, false))
+
+	want.Deleted = []domain.Product{stored[3]}
+	assert.Equal(t, want, diff(stored, incoming, true))
+}
+
+func TestDiffFetchWritesNothing(t *testing.T) {
+	logger := logger.GetLogger()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("1;name;50.00\n2;Name2;60.00"))
+	}))
+	defer srv.Close()
+
+	c := gomock.NewController(t)
+	defer c.Finish()
+
+	stored := domain.Product{Source: "default", Id: 1, Name: "name"}
+	stored.Price, _ = primitive.ParseDecimal128("40.00")
+
+	// only the read is expected, a write would fail the test
+	mockService := mock_service.NewMockSorting(c)
+	mockService.EXPECT().List(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, req *grpcPb.ListRequest, merge domain.MergeRule) ([]domain.Product, error) {
+			assert.Equal(t, "default", req.GetSource())
+			assert.True(t, req.GetIncludeDeleted())
+			assert.Zero(t, req.GetPagingLimit())
+			return []domain.Product{stored}, nil
+		})
+
+	got, err := NewService(mockService, testSources(), logger).DiffFetch(context.Background(), &grpcPb.DiffFetchRequest{Url: srv.URL})
+	assert.NoError(t, err)
+	assert.Equal(t, "default", got.Source)
+	if assert.Len(t, got.Created, 1) {
+		assert.Equal(t, 2, got.Created[0].Id)
+	}
+	if assert.Len(t, got.Updated, 1) {
+		assert.Equal(t, "50.00", got.Updated[0].New.Price.String())
+		assert.Equal(t, "40.00", got.Updated[0].Old.Price.String())
+	}
+}
+
+func TestFetchRules(t *testing.T) {
+	logger := logger.GetLogger()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("1;  green   tea ;10.005\n2;free;0\n3\n"))
+	}))
+	defer srv.Close()
+
+	registry := testSources()
+	pipeline, _ := rules.Build([]rules.Config{{Rule: rules.CollapseSpaces}, {Rule: rules.RoundPrice, Places: 2}, {Rule: rules.PositivePrice}})
+	registry.SetRules(registry.Default(), pipeline)
+
+	c := gomock.NewController(t)
+	defer c.Finish()
+
+	product := domain.Product{Source: "default", Id: 1, Name: "green tea"}
+	product.Price, _ = primitive.ParseDecimal128("10.01")
+
+	mockService := mock_service.NewMockSorting(c)
+	expectTransaction(mockService)
+	mockService.EXPECT().GetByName(gomock.Any(), product).Return(domain.Product{}, domain.ErrProductNotFound)
+	mockService.EXPECT().Fetch(gomock.Any(), []domain.Product{product}).Return(domain.Status{}, nil)
+
+	got, err := NewService(mockService, registry, logger).Fetch(context.Background(), &grpcPb.FetchRequest{Url: srv.URL})
+	assert.NoError(t, err)
+	assert.Equal(t, "Success", got.Status)
+	if assert.Len(t, got.Rejected, 2) {
+		assert.Equal(t, RuleColumns, got.Rejected[0].Rule)
+		assert.Equal(t, domain.Rejection{Id: 2, Name: "free", Rule: rules.PositivePrice, Reason: "price 0.00 must be greater than zero"}, got.Rejected[1])
+	}
+}
+
+func TestFetchDuplicateIds(t *testing.T) {
+	logger := logger.GetLogger()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("1;tea;10.00\n1;coffee;20.00\n"))
+	}))
+	defer srv.Close()
+
+	c := gomock.NewController(t)
+	defer c.Finish()
+
+	product := domain.Product{Source: "default", Id: 1, Name: "tea"}
+	product.Price, _ = primitive.ParseDecimal128("10.00")
+
+	mockService := mock_service.NewMockSorting(c)
+	expectTransaction(mockService)
+	mockService.EXPECT().GetByName(gomock.Any(), product).Return(domain.Product{}, domain.ErrProductNotFound)
+	mockService.EXPECT().Fetch(gomock.Any(), []domain.Product{product}).Return(domain.Status{}, nil)
+
+	got, err := NewService(mockService, testSources(), logger).Fetch(context.Background(), &grpcPb.FetchRequest{Url: srv.URL})
+	assert.NoError(t, err)
+	assert.Equal(t, []domain.Rejection{{Id: 1, Name: "coffee", Rule: RuleDuplicateId, Reason: "id 1 is already used by an earlier row"}}, got.Rejected)
+}
@@ -0,0 +1,153 @@
+package service
+
+import (
+	"context"
+	"gRPC-server/internal/domain"
+	"gRPC-server/internal/metrics"
+	"sync"
+	"time"
+
+	"github.com/spf13/viper"
+	"golang.org/x/sync/singleflight"
+)
+
+const (
+	defaultMaxConcurrentImports = 2
+	defaultImportQueueSize      = 8
+	defaultImportTimeout        = 5 * time.Minute
+)
+
+// fetchQueue bounds how many imports run at once and how many may wait for a slot.
+// Concurrent fetches of the same URL share one import instead of queueing twice.
+type fetchQueue struct {
+	group singleflight.Group
+	// admitted holds running and waiting imports, when it's full new URLs are rejected
+	admitted chan struct{}
+	running  chan struct{}
+	timeout  time.Duration
+	// apply looks ids up with GetByName and then inserts the missing ones,
+	// two imports applying at once would both miss an id and insert it twice
+	applyMu sync.Mutex
+
+	jobsMu sync.Mutex
+	jobs   map[string]*fetchJob
+}
+
+// fetchJob is the cancellation of a shared import, it is cancelled once nobody waits for it.
+type fetchJob struct {
+	ctx     context.Context
+	cancel  context.CancelFunc
+	waiters int
+}
+
+func newFetchQueue() *fetchQueue {
+	maxConcurrent := viper.GetInt("fetch.max_concurrent")
+	if maxConcurrent <= 0 {
+		maxConcurrent = defaultMaxConcurrentImports
+	}
+	// zero is a valid queue size: nothing waits, imports beyond max_concurrent are rejected
+	queueSize := defaultImportQueueSize
+	if viper.IsSet("fetch.queue_size") && viper.GetInt("fetch.queue_size") >= 0 {
+		queueSize = viper.GetInt("fetch.queue_size")
+	}
+	timeout := viper.GetDuration("fetch.import_timeout")
+	if timeout <= 0 {
+		timeout = defaultImportTimeout
+	}
+
+	return &fetchQueue{
+		admitted: make(chan struct{}, maxConcurrent+queueSize),
+		running:  make(chan struct{}, maxConcurrent),
+		timeout:  timeout,
+		jobs:     map[string]*fetchJob{},
+	}
+}
+
+// join registers the caller as a waiter of the import of url, the first one creates it.
+// The import is detached from the caller's cancellation but keeps its values.
+func (q *fetchQueue) join(ctx context.Context, url string) *fetchJob {
+	q.jobsMu.Lock()
+	defer q.jobsMu.Unlock()
+	job, ok := q.jobs[url]
+	if !ok {
+		job = &fetchJob{}
+		job.ctx, job.cancel = context.WithCancel(context.WithoutCancel(ctx))
+		q.jobs[url] = job
+	}
+	job.waiters++
+	return job
+}
+
+// leave unregisters a waiter and reports whether it was the last one. The import of the
+// last one is cancelled and forgotten, so a later call of url starts a new import.
+func (q *fetchQueue) leave(url string, job *fetchJob) bool {
+	q.jobsMu.Lock()
+	defer q.jobsMu.Unlock()
+	job.waiters--
+	if job.waiters > 0 {
+		return false
+	}
+	job.cancel()
+	if q.jobs[url] == job {
+		delete(q.jobs, url)
+		q.group.Forget(url)
+	}
+	return true
+}
+
+// exclusive runs fn while no other import is applying.
+func (q *fetchQueue) exclusive(fn func() error) error {
+	q.applyMu.Lock()
+	defer q.applyMu.Unlock()
+	return fn()
+}
+
+// do runs fn for url unless an import of the same url is already queued or running,
+// in which case the caller waits for that one. One caller hanging up doesn't fail the others
+// sharing the import; when the last one does, the import is cancelled and do returns only
+// after fn has finished, so the caller never reports a failure of an import that commits later.
+// The import is also bounded by the import timeout.
+func (q *fetchQueue) do(ctx context.Context, url string, fn func(ctx context.Context) (domain.Status, error)) (domain.Status, error) {
+	job := q.join(ctx, url)
+	ch := q.group.DoChan(url, func() (interface{}, error) {
+		select {
+		case q.admitted <- struct{}{}:
+		default:
+			metrics.ImportRejected.Inc()
+			return domain.Status{Status: "Fail"}, domain.ErrImportQueueFull
+		}
+		defer func() { <-q.admitted }()
+
+		importCtx, cancel := context.WithTimeout(job.ctx, q.timeout)
+		defer cancel()
+
+		metrics.ImportQueued.Inc()
+		select {
+		case q.running <- struct{}{}:
+			metrics.ImportQueued.Dec()
+		case <-importCtx.Done():
+			metrics.ImportQueued.Dec()
+			return domain.Status{Status: "Fail"}, importCtx.Err()
+		}
+		defer func() { <-q.running }()
+
+		metrics.ImportRunning.Inc()
+		defer metrics.ImportRunning.Dec()
+		return fn(importCtx)
+	})
+
+	select {
+	case res := <-ch:
+		q.leave(url, job)
+		if res.Shared {
+			metrics.ImportShared.Inc()
+		}
+		return res.Val.(domain.Status), res.Err
+	case <-ctx.Done():
+		if q.leave(url, job) {
+			res := <-ch
+			return res.Val.(domain.Status), res.Err
+		}
+		return domain.Status{Status: "Fail"}, ctx.Err()
+	}
+}
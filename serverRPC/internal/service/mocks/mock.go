@@ -9,8 +9,10 @@ import (
 	domain "gRPC-server/internal/domain"
 	grpcPb "gRPC-server/pkg/parseCSV/grpcPb"
 	reflect "reflect"
+	time "time"
 
 	gomock "github.com/golang/mock/gomock"
+	primitive "go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 // MockSorting is a mock of Sorting interface.
@@ -36,6 +38,137 @@ func (m *MockSorting) EXPECT() *MockSortingMockRecorder {
 	return m.recorder
 }
 
+// AcquireLease mocks base method.
+func (m *MockSorting) AcquireLease(ctx context.Context, name, holder string, ttl time.Duration) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AcquireLease", ctx, name, holder, ttl)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AcquireLease indicates an expected call of AcquireLease.
+func (mr *MockSortingMockRecorder) AcquireLease(ctx, name, holder, ttl interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AcquireLease", reflect.TypeOf((*MockSorting)(nil).AcquireLease), ctx, name, holder, ttl)
+}
+
+// ClaimScheduleRun mocks base method.
+func (m *MockSorting) ClaimScheduleRun(ctx context.Context, id primitive.ObjectID, scheduled, next time.Time) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ClaimScheduleRun", ctx, id, scheduled, next)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ClaimScheduleRun indicates an expected call of ClaimScheduleRun.
+func (mr *MockSortingMockRecorder) ClaimScheduleRun(ctx, id, scheduled, next interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ClaimScheduleRun", reflect.TypeOf((*MockSorting)(nil).ClaimScheduleRun), ctx, id, scheduled, next)
+}
+
+// CreateAlertRule mocks base method.
+func (m *MockSorting) CreateAlertRule(ctx context.Context, rule domain.AlertRule) (domain.AlertRule, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateAlertRule", ctx, rule)
+	ret0, _ := ret[0].(domain.AlertRule)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateAlertRule indicates an expected call of CreateAlertRule.
+func (mr *MockSortingMockRecorder) CreateAlertRule(ctx, rule interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateAlertRule", reflect.TypeOf((*MockSorting)(nil).CreateAlertRule), ctx, rule)
+}
+
+// CreateSchedule mocks base method.
+func (m *MockSorting) CreateSchedule(ctx context.Context, schedule domain.Schedule) (domain.Schedule, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateSchedule", ctx, schedule)
+	ret0, _ := ret[0].(domain.Schedule)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateSchedule indicates an expected call of CreateSchedule.
+func (mr *MockSortingMockRecorder) CreateSchedule(ctx, schedule interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateSchedule", reflect.TypeOf((*MockSorting)(nil).CreateSchedule), ctx, schedule)
+}
+
+// DeleteAlertRule mocks base method.
+func (m *MockSorting) DeleteAlertRule(ctx context.Context, id primitive.ObjectID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteAlertRule", ctx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteAlertRule indicates an expected call of DeleteAlertRule.
+func (mr *MockSortingMockRecorder) DeleteAlertRule(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteAlertRule", reflect.TypeOf((*MockSorting)(nil).DeleteAlertRule), ctx, id)
+}
+
+// DeleteProduct mocks base method.
+func (m *MockSorting) DeleteProduct(ctx context.Context, product domain.Product) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteProduct", ctx, product)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteProduct indicates an expected call of DeleteProduct.
+func (mr *MockSortingMockRecorder) DeleteProduct(ctx, product interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteProduct", reflect.TypeOf((*MockSorting)(nil).DeleteProduct), ctx, product)
+}
+
+// DeleteSchedule mocks base method.
+func (m *MockSorting) DeleteSchedule(ctx context.Context, id primitive.ObjectID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteSchedule", ctx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteSchedule indicates an expected call of DeleteSchedule.
+func (mr *MockSortingMockRecorder) DeleteSchedule(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteSchedule", reflect.TypeOf((*MockSorting)(nil).DeleteSchedule), ctx, id)
+}
+
+// DueSchedules mocks base method.
+func (m *MockSorting) DueSchedules(ctx context.Context, now time.Time) ([]domain.Schedule, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DueSchedules", ctx, now)
+	ret0, _ := ret[0].([]domain.Schedule)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DueSchedules indicates an expected call of DueSchedules.
+func (mr *MockSortingMockRecorder) DueSchedules(ctx, now interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DueSchedules", reflect.TypeOf((*MockSorting)(nil).DueSchedules), ctx, now)
+}
+
+// Export mocks base method.
+func (m *MockSorting) Export(ctx context.Context, product *grpcPb.ListRequest, merge domain.MergeRule, send func(domain.Product) error) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Export", ctx, product, merge, send)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Export indicates an expected call of Export.
+func (mr *MockSortingMockRecorder) Export(ctx, product, merge, send interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Export", reflect.TypeOf((*MockSorting)(nil).Export), ctx, product, merge, send)
+}
+
 // Fetch mocks base method.
 func (m *MockSorting) Fetch(ctx context.Context, product []domain.Product) (domain.Status, error) {
 	m.ctrl.T.Helper()
@@ -51,6 +184,35 @@ func (mr *MockSortingMockRecorder) Fetch(ctx, product interface{}) *gomock.Call
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Fetch", reflect.TypeOf((*MockSorting)(nil).Fetch), ctx, product)
 }
 
+// FinishScheduleRun mocks base method.
+func (m *MockSorting) FinishScheduleRun(ctx context.Context, id primitive.ObjectID, at time.Time, status, runErr string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FinishScheduleRun", ctx, id, at, status, runErr)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// FinishScheduleRun indicates an expected call of FinishScheduleRun.
+func (mr *MockSortingMockRecorder) FinishScheduleRun(ctx, id, at, status, runErr interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FinishScheduleRun", reflect.TypeOf((*MockSorting)(nil).FinishScheduleRun), ctx, id, at, status, runErr)
+}
+
+// GetByIds mocks base method.
+func (m *MockSorting) GetByIds(ctx context.Context, source string, ids []int) ([]domain.Product, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetByIds", ctx, source, ids)
+	ret0, _ := ret[0].([]domain.Product)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetByIds indicates an expected call of GetByIds.
+func (mr *MockSortingMockRecorder) GetByIds(ctx, source, ids interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByIds", reflect.TypeOf((*MockSorting)(nil).GetByIds), ctx, source, ids)
+}
+
 // GetByName mocks base method.
 func (m *MockSorting) GetByName(ctx context.Context, product domain.Product) (domain.Product, error) {
 	m.ctrl.T.Helper()
@@ -66,19 +228,180 @@ func (mr *MockSortingMockRecorder) GetByName(ctx, product interface{}) *gomock.C
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByName", reflect.TypeOf((*MockSorting)(nil).GetByName), ctx, product)
 }
 
+// GetSchedule mocks base method.
+func (m *MockSorting) GetSchedule(ctx context.Context, id primitive.ObjectID) (domain.Schedule, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSchedule", ctx, id)
+	ret0, _ := ret[0].(domain.Schedule)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetSchedule indicates an expected call of GetSchedule.
+func (mr *MockSortingMockRecorder) GetSchedule(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSchedule", reflect.TypeOf((*MockSorting)(nil).GetSchedule), ctx, id)
+}
+
+// GetStats mocks base method.
+func (m *MockSorting) GetStats(ctx context.Context, filter domain.StatsFilter) (domain.Stats, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetStats", ctx, filter)
+	ret0, _ := ret[0].(domain.Stats)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetStats indicates an expected call of GetStats.
+func (mr *MockSortingMockRecorder) GetStats(ctx, filter interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetStats", reflect.TypeOf((*MockSorting)(nil).GetStats), ctx, filter)
+}
+
+// InsertDeadLetter mocks base method.
+func (m *MockSorting) InsertDeadLetter(ctx context.Context, letter domain.DeadLetter) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "InsertDeadLetter", ctx, letter)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// InsertDeadLetter indicates an expected call of InsertDeadLetter.
+func (mr *MockSortingMockRecorder) InsertDeadLetter(ctx, letter interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InsertDeadLetter", reflect.TypeOf((*MockSorting)(nil).InsertDeadLetter), ctx, letter)
+}
+
 // List mocks base method.
-func (m *MockSorting) List(ctx context.Context, product *grpcPb.ListRequest) ([]domain.Product, error) {
+func (m *MockSorting) List(ctx context.Context, product *grpcPb.ListRequest, merge domain.MergeRule) ([]domain.Product, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "List", ctx, product)
+	ret := m.ctrl.Call(m, "List", ctx, product, merge)
 	ret0, _ := ret[0].([]domain.Product)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // List indicates an expected call of List.
-func (mr *MockSortingMockRecorder) List(ctx, product interface{}) *gomock.Call {
+func (mr *MockSortingMockRecorder) List(ctx, product, merge interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "List", reflect.TypeOf((*MockSorting)(nil).List), ctx, product, merge)
+}
+
+// ListAlertRules mocks base method.
+func (m *MockSorting) ListAlertRules(ctx context.Context) ([]domain.AlertRule, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListAlertRules", ctx)
+	ret0, _ := ret[0].([]domain.AlertRule)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListAlertRules indicates an expected call of ListAlertRules.
+func (mr *MockSortingMockRecorder) ListAlertRules(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListAlertRules", reflect.TypeOf((*MockSorting)(nil).ListAlertRules), ctx)
+}
+
+// ListDeadLetters mocks base method.
+func (m *MockSorting) ListDeadLetters(ctx context.Context, limit int) ([]domain.DeadLetter, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListDeadLetters", ctx, limit)
+	ret0, _ := ret[0].([]domain.DeadLetter)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListDeadLetters indicates an expected call of ListDeadLetters.
+func (mr *MockSortingMockRecorder) ListDeadLetters(ctx, limit interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListDeadLetters", reflect.TypeOf((*MockSorting)(nil).ListDeadLetters), ctx, limit)
+}
+
+// ListSchedules mocks base method.
+func (m *MockSorting) ListSchedules(ctx context.Context) ([]domain.Schedule, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListSchedules", ctx)
+	ret0, _ := ret[0].([]domain.Schedule)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListSchedules indicates an expected call of ListSchedules.
+func (mr *MockSortingMockRecorder) ListSchedules(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListSchedules", reflect.TypeOf((*MockSorting)(nil).ListSchedules), ctx)
+}
+
+// Ping mocks base method.
+func (m *MockSorting) Ping(ctx context.Context) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Ping", ctx)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Ping indicates an expected call of Ping.
+func (mr *MockSortingMockRecorder) Ping(ctx interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "List", reflect.TypeOf((*MockSorting)(nil).List), ctx, product)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Ping", reflect.TypeOf((*MockSorting)(nil).Ping), ctx)
+}
+
+// PurgeDeleted mocks base method.
+func (m *MockSorting) PurgeDeleted(ctx context.Context, olderThan time.Time) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PurgeDeleted", ctx, olderThan)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PurgeDeleted indicates an expected call of PurgeDeleted.
+func (mr *MockSortingMockRecorder) PurgeDeleted(ctx, olderThan interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PurgeDeleted", reflect.TypeOf((*MockSorting)(nil).PurgeDeleted), ctx, olderThan)
+}
+
+// ReleaseLease mocks base method.
+func (m *MockSorting) ReleaseLease(ctx context.Context, name, holder string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReleaseLease", ctx, name, holder)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ReleaseLease indicates an expected call of ReleaseLease.
+func (mr *MockSortingMockRecorder) ReleaseLease(ctx, name, holder interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReleaseLease", reflect.TypeOf((*MockSorting)(nil).ReleaseLease), ctx, name, holder)
+}
+
+// RestoreProduct mocks base method.
+func (m *MockSorting) RestoreProduct(ctx context.Context, product domain.Product) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RestoreProduct", ctx, product)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RestoreProduct indicates an expected call of RestoreProduct.
+func (mr *MockSortingMockRecorder) RestoreProduct(ctx, product interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RestoreProduct", reflect.TypeOf((*MockSorting)(nil).RestoreProduct), ctx, product)
+}
+
+// SetSchedulePaused mocks base method.
+func (m *MockSorting) SetSchedulePaused(ctx context.Context, id primitive.ObjectID, paused bool, nextRun time.Time) (domain.Schedule, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetSchedulePaused", ctx, id, paused, nextRun)
+	ret0, _ := ret[0].(domain.Schedule)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SetSchedulePaused indicates an expected call of SetSchedulePaused.
+func (mr *MockSortingMockRecorder) SetSchedulePaused(ctx, id, paused, nextRun interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetSchedulePaused", reflect.TypeOf((*MockSorting)(nil).SetSchedulePaused), ctx, id, paused, nextRun)
 }
 
 // UpdateProduct mocks base method.
@@ -94,3 +417,31 @@ func (mr *MockSortingMockRecorder) UpdateProduct(ctx, product interface{}) *gomo
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateProduct", reflect.TypeOf((*MockSorting)(nil).UpdateProduct), ctx, product)
 }
+
+// Watch mocks base method.
+func (m *MockSorting) Watch(ctx context.Context, resumeToken string, send func(domain.ProductEvent) error) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Watch", ctx, resumeToken, send)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Watch indicates an expected call of Watch.
+func (mr *MockSortingMockRecorder) Watch(ctx, resumeToken, send interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Watch", reflect.TypeOf((*MockSorting)(nil).Watch), ctx, resumeToken, send)
+}
+
+// WithTransaction mocks base method.
+func (m *MockSorting) WithTransaction(ctx context.Context, fn func(context.Context) error) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WithTransaction", ctx, fn)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// WithTransaction indicates an expected call of WithTransaction.
+func (mr *MockSortingMockRecorder) WithTransaction(ctx, fn interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WithTransaction", reflect.TypeOf((*MockSorting)(nil).WithTransaction), ctx, fn)
+}
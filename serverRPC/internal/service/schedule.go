@@ -0,0 +1,78 @@
+package service
+
+import (
+	"context"
+	"gRPC-server/internal/domain"
+	"gRPC-server/internal/scheduler"
+	"net/url"
+	"time"
+
+	"github.com/spf13/viper"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// CreateSchedule resolves the source like Fetch does and stores the schedule with its first run already planned.
+func (s *Service) CreateSchedule(ctx context.Context, schedule domain.Schedule) (domain.Schedule, error) {
+	source, err := s.sources.Resolve(schedule.Source, schedule.Url)
+	if err != nil {
+		return domain.Schedule{}, err
+	}
+	if u, err := url.Parse(source.Url); err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return domain.Schedule{}, domain.NewFieldError("url", "must be an http or https url")
+	}
+	schedule.Source = source.Name
+	schedule.Url = source.Url
+	// a format given with the schedule wins over the registry's one
+	if schedule.Format.Delimiter == 0 {
+		schedule.Format = source.Format
+	}
+
+	now := time.Now()
+	next, err := scheduler.NextRun(schedule.Cron, now, viper.GetDuration("scheduler.jitter"))
+	if err != nil {
+		return domain.Schedule{}, domain.NewFieldError("cron", err.Error())
+	}
+	schedule.NextRun = next
+	schedule.CreatedAt = now
+	schedule.Paused = false
+
+	return s.Sorting.CreateSchedule(ctx, schedule)
+}
+
+// PauseSchedule pauses the schedule or, with paused false, resumes it from the next cron
+// time; runs missed while it was paused are not made up.
+func (s *Service) PauseSchedule(ctx context.Context, id string, paused bool) (domain.Schedule, error) {
+	oid, err := scheduleID(id)
+	if err != nil {
+		return domain.Schedule{}, err
+	}
+
+	var next time.Time
+	if !paused {
+		schedule, err := s.Sorting.GetSchedule(ctx, oid)
+		if err != nil {
+			return domain.Schedule{}, err
+		}
+		if next, err = scheduler.NextRun(schedule.Cron, time.Now(), viper.GetDuration("scheduler.jitter")); err != nil {
+			return domain.Schedule{}, err
+		}
+	}
+
+	return s.Sorting.SetSchedulePaused(ctx, oid, paused, next)
+}
+
+func (s *Service) DeleteSchedule(ctx context.Context, id string) error {
+	oid, err := scheduleID(id)
+	if err != nil {
+		return err
+	}
+	return s.Sorting.DeleteSchedule(ctx, oid)
+}
+
+func scheduleID(id string) (primitive.ObjectID, error) {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return primitive.NilObjectID, domain.NewFieldError("id", "must be a schedule id")
+	}
+	return oid, nil
+}
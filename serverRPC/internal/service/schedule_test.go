@@ -0,0 +1,267 @@
+package service
+
+import (
+	"context"
+	"gRPC-server/internal/domain"
+	"gRPC-server/internal/scheduler"
+	mock_service "gRPC-server/internal/service/mocks"
+	"gRPC-server/internal/sources"
+	"gRPC-server/pkg/logger"
+	"gRPC-server/pkg/parseCSV/grpcPb"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestCreateSchedule(t *testing.T) {
+	logger := logger.GetLogger()
+
+	testTables := []struct {
+		name     string
+		schedule domain.Schedule
+		field    string
+	}{
+		{
+			name:     "Valid",
+			schedule: domain.Schedule{Url: "http://example.com/products.csv", Cron: "*/5 * * * *"},
+		},
+		{
+			name:     "Empty url",
+			schedule: domain.Schedule{Cron: "@hourly"},
+			field:    "url",
+		},
+		{
+			name:     "Not http",
+			schedule: domain.Schedule{Url: "ftp://example.com/products.csv", Cron: "@hourly"},
+			field:    "url",
+		},
+		{
+			name:     "Invalid cron",
+			schedule: domain.Schedule{Url: "http://example.com/products.csv", Cron: "sometimes"},
+			field:    "cron",
+		},
+	}
+
+	for _, table := range testTables {
+		t.Run(table.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			mockService := mock_service.NewMockSorting(c)
+			service := NewService(mockService, testSources(), logger)
+			if table.field == "" {
+				mockService.EXPECT().CreateSchedule(gomock.Any(), gomock.Any()).DoAndReturn(
+					func(ctx context.Context, schedule domain.Schedule) (domain.Schedule, error) {
+						return schedule, nil
+					})
+			}
+
+			got, err := service.CreateSchedule(context.Background(), table.schedule)
+			if table.field != "" {
+				var fieldErr *domain.FieldError
+				if assert.ErrorAs(t, err, &fieldErr) {
+					assert.Equal(t, table.field, fieldErr.Field)
+				}
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, ';', got.Format.Delimiter)
+			assert.True(t, got.NextRun.After(time.Now()))
+			assert.Equal(t, 0, got.NextRun.Minute()%5)
+		})
+	}
+}
+
+func TestPauseSchedule(t *testing.T) {
+	logger := logger.GetLogger()
+	id := primitive.NewObjectID()
+
+	t.Run("Pause", func(t *testing.T) {
+		c := gomock.NewController(t)
+		defer c.Finish()
+
+		mockService := mock_service.NewMockSorting(c)
+		mockService.EXPECT().SetSchedulePaused(gomock.Any(), id, true, time.Time{}).Return(domain.Schedule{Id: id, Paused: true}, nil)
+
+		got, err := NewService(mockService, testSources(), logger).PauseSchedule(context.Background(), id.Hex(), true)
+		assert.NoError(t, err)
+		assert.True(t, got.Paused)
+	})
+
+	t.Run("Resume plans next run", func(t *testing.T) {
+		c := gomock.NewController(t)
+		defer c.Finish()
+
+		mockService := mock_service.NewMockSorting(c)
+		mockService.EXPECT().GetSchedule(gomock.Any(), id).Return(domain.Schedule{Id: id, Cron: "@hourly", Paused: true}, nil)
+		mockService.EXPECT().SetSchedulePaused(gomock.Any(), id, false, gomock.Any()).DoAndReturn(
+			func(ctx context.Context, id primitive.ObjectID, paused bool, next time.Time) (domain.Schedule, error) {
+				assert.True(t, next.After(time.Now()))
+				assert.Zero(t, next.Minute())
+				return domain.Schedule{Id: id, NextRun: next}, nil
+			})
+
+		_, err := NewService(mockService, testSources(), logger).PauseSchedule(context.Background(), id.Hex(), false)
+		assert.NoError(t, err)
+	})
+
+	t.Run("Invalid id", func(t *testing.T) {
+		c := gomock.NewController(t)
+		defer c.Finish()
+
+		_, err := NewService(mock_service.NewMockSorting(c), testSources(), logger).PauseSchedule(context.Background(), "42", true)
+		var fieldErr *domain.FieldError
+		if assert.ErrorAs(t, err, &fieldErr) {
+			assert.Equal(t, "id", fieldErr.Field)
+		}
+	})
+}
+
+func TestImportFormat(t *testing.T) {
+	logger := logger.GetLogger()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("id,name,price\n1,name,50.00\n"))
+	}))
+	defer srv.Close()
+
+	c := gomock.NewController(t)
+	defer c.Finish()
+
+	product := domain.Product{Source: "supplier", Id: 1, Name: "name"}
+	product.Price, _ = primitive.ParseDecimal128("50.00")
+
+	mockService := mock_service.NewMockSorting(c)
+	expectTransaction(mockService)
+	mockService.EXPECT().GetByName(gomock.Any(), product).Return(domain.Product{}, domain.ErrProductNotFound)
+	mockService.EXPECT().Fetch(gomock.Any(), []domain.Product{product}).Return(domain.Status{}, nil)
+
+	got, err := NewService(mockService, testSources(), logger).Import(context.Background(), domain.Source{
+		Name:   "supplier",
+		Url:    srv.URL,
+		Format: domain.CSVFormat{Delimiter: ',', SkipHeader: true},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, domain.Status{Status: "Success"}, got)
+}
+
+// TestSchedulerShutdownCancelsImport runs a scheduled import through the real Service: when
+// Shutdown runs out of time the detached import is cancelled too, and the run is recorded as
+// failed only after the import has stopped writing.
+func TestSchedulerShutdownCancelsImport(t *testing.T) {
+	logger := logger.GetLogger()
+	viper.Set("scheduler.enabled", true)
+	viper.Set("scheduler.interval", time.Hour)
+	defer func() {
+		viper.Set("scheduler.enabled", nil)
+		viper.Set("scheduler.interval", nil)
+	}()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("1;name;50.00"))
+	}))
+	defer srv.Close()
+
+	c := gomock.NewController(t)
+	defer c.Finish()
+
+	schedule := domain.Schedule{
+		Id:      primitive.NewObjectID(),
+		Source:  "supplier",
+		Url:     srv.URL,
+		Cron:    "@hourly",
+		Format:  domain.CSVFormat{Delimiter: ';'},
+		NextRun: time.Now(),
+	}
+	started := make(chan struct{})
+	var rolledBack atomic.Bool
+	finished := make(chan struct{})
+
+	mockService := mock_service.NewMockSorting(c)
+	mockService.EXPECT().AcquireLease(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(true, nil)
+	mockService.EXPECT().DueSchedules(gomock.Any(), gomock.Any()).Return([]domain.Schedule{schedule}, nil)
+	mockService.EXPECT().ClaimScheduleRun(gomock.Any(), schedule.Id, gomock.Any(), gomock.Any()).Return(true, nil)
+	mockService.EXPECT().WithTransaction(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, fn func(ctx context.Context) error) error {
+			err := fn(ctx)
+			rolledBack.Store(true)
+			return err
+		})
+	mockService.EXPECT().GetByName(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, product domain.Product) (domain.Product, error) {
+			close(started)
+			<-ctx.Done()
+			return domain.Product{}, ctx.Err()
+		})
+	// no Fetch: the cancelled import must not write
+	mockService.EXPECT().FinishScheduleRun(gomock.Any(), schedule.Id, gomock.Any(), "Fail", gomock.Any()).DoAndReturn(
+		func(ctx context.Context, id primitive.ObjectID, at time.Time, status, runErr string) error {
+			assert.True(t, rolledBack.Load(), "the run is recorded before the import stopped")
+			close(finished)
+			return nil
+		})
+
+	service := NewService(mockService, testSources(), logger)
+	jobs := scheduler.New(service, service, logger)
+	jobs.Start()
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, jobs.Shutdown(ctx), context.DeadlineExceeded)
+	select {
+	case <-finished:
+	default:
+		t.Fatal("Shutdown returned before the run was recorded")
+	}
+}
+
+func TestFetchSource(t *testing.T) {
+	logger := logger.GetLogger()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("id,name,price\n1,name,50.00\n"))
+	}))
+	defer srv.Close()
+
+	registry, _ := sources.New([]domain.Source{
+		{Name: "supplier", Url: srv.URL, Format: domain.CSVFormat{Delimiter: ',', SkipHeader: true}},
+	}, "", "")
+
+	t.Run("Registered source", func(t *testing.T) {
+		c := gomock.NewController(t)
+		defer c.Finish()
+
+		product := domain.Product{Source: "supplier", Id: 1, Name: "name"}
+		product.Price, _ = primitive.ParseDecimal128("50.00")
+
+		mockService := mock_service.NewMockSorting(c)
+		expectTransaction(mockService)
+		mockService.EXPECT().GetByName(gomock.Any(), product).Return(domain.Product{}, domain.ErrProductNotFound)
+		mockService.EXPECT().Fetch(gomock.Any(), []domain.Product{product}).Return(domain.Status{}, nil)
+
+		got, err := NewService(mockService, registry, logger).Fetch(context.Background(), &grpcPb.FetchRequest{Source: "supplier"})
+		assert.NoError(t, err)
+		assert.Equal(t, domain.Status{Status: "Success"}, got)
+	})
+
+	t.Run("Unknown source", func(t *testing.T) {
+		c := gomock.NewController(t)
+		defer c.Finish()
+
+		service := NewService(mock_service.NewMockSorting(c), registry, logger)
+		got, err := service.Fetch(context.Background(), &grpcPb.FetchRequest{Source: "nobody", Url: srv.URL})
+		assert.ErrorIs(t, err, domain.ErrInvalidArgument)
+		assert.Equal(t, domain.Status{Status: "Fail"}, got)
+
+		_, err = service.List(context.Background(), &grpcPb.ListRequest{Source: "nobody"})
+		assert.ErrorIs(t, err, domain.ErrInvalidArgument)
+	})
+}
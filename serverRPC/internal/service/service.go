@@ -3,114 +3,525 @@ package service
 import (
 	"context"
 	"encoding/csv"
+	"errors"
+	"fmt"
+	"gRPC-server/internal/alerts"
 	"gRPC-server/internal/domain"
+	"gRPC-server/internal/metrics"
+	"gRPC-server/internal/sources"
 	"gRPC-server/pkg/logger"
 	"gRPC-server/pkg/parseCSV/grpcPb"
+	"io"
+	"math/big"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
 //go:generate mockgen -source=service.go -destination=mocks/mock.go
 type Sorting interface {
+	WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error
 	Fetch(ctx context.Context, product []domain.Product) (domain.Status, error)
-	List(ctx context.Context, product *grpcPb.ListRequest) ([]domain.Product, error)
+	List(ctx context.Context, product *grpcPb.ListRequest, merge domain.MergeRule) ([]domain.Product, error)
+	Export(ctx context.Context, product *grpcPb.ListRequest, merge domain.MergeRule, send func(domain.Product) error) error
+	// GetByName reports a missing product with domain.ErrProductNotFound
 	GetByName(ctx context.Context, product domain.Product) (domain.Product, error)
+	GetByIds(ctx context.Context, source string, ids []int) ([]domain.Product, error)
 	UpdateProduct(ctx context.Context, product domain.Product) error
+	GetStats(ctx context.Context, filter domain.StatsFilter) (domain.Stats, error)
+	Watch(ctx context.Context, resumeToken string, send func(domain.ProductEvent) error) error
+	DeleteProduct(ctx context.Context, product domain.Product) error
+	RestoreProduct(ctx context.Context, product domain.Product) error
+	PurgeDeleted(ctx context.Context, olderThan time.Time) (int64, error)
+	Ping(ctx context.Context) error
+	CreateSchedule(ctx context.Context, schedule domain.Schedule) (domain.Schedule, error)
+	ListSchedules(ctx context.Context) ([]domain.Schedule, error)
+	GetSchedule(ctx context.Context, id primitive.ObjectID) (domain.Schedule, error)
+	SetSchedulePaused(ctx context.Context, id primitive.ObjectID, paused bool, nextRun time.Time) (domain.Schedule, error)
+	DeleteSchedule(ctx context.Context, id primitive.ObjectID) error
+	DueSchedules(ctx context.Context, now time.Time) ([]domain.Schedule, error)
+	ClaimScheduleRun(ctx context.Context, id primitive.ObjectID, scheduled, next time.Time) (bool, error)
+	FinishScheduleRun(ctx context.Context, id primitive.ObjectID, at time.Time, status, runErr string) error
+	AcquireLease(ctx context.Context, name, holder string, ttl time.Duration) (bool, error)
+	ReleaseLease(ctx context.Context, name, holder string) error
+	CreateAlertRule(ctx context.Context, rule domain.AlertRule) (domain.AlertRule, error)
+	ListAlertRules(ctx context.Context) ([]domain.AlertRule, error)
+	DeleteAlertRule(ctx context.Context, id primitive.ObjectID) error
+	InsertDeadLetter(ctx context.Context, letter domain.DeadLetter) error
+	ListDeadLetters(ctx context.Context, limit int) ([]domain.DeadLetter, error)
 }
 
 type Service struct {
-	logger *logger.Logger
+	logger     *logger.Logger
+	httpClient *http.Client
+	imports    *fetchQueue
+	sources    *sources.Registry
+	alerts     *alerts.Dispatcher
 	Sorting
 }
 
-func NewService(sortService Sorting, logger *logger.Logger) *Service {
+func NewService(sortService Sorting, registry *sources.Registry, logger *logger.Logger) *Service {
 	return &Service{
 		logger:  logger,
-		Sorting: sortService,
+		sources: registry,
+		// the transport opens a client span for the download and injects the trace context into it
+		httpClient: &http.Client{Transport: otelhttp.NewTransport(http.DefaultTransport)},
+		imports:    newFetchQueue(),
+		alerts:     alerts.New(sortService, logger),
+		Sorting:    sortService,
 	}
 }
 
+var tracer = otel.Tracer("gRPC-server/internal/service")
+
+// Fetch imports req.Url into req.Source, in the format the registry has for that source.
 func (s *Service) Fetch(ctx context.Context, req *grpcPb.FetchRequest) (domain.Status, error) {
-	var products []domain.Product
+	source, err := s.sources.Resolve(req.GetSource(), req.GetUrl())
+	if err != nil {
+		return domain.Status{
+			Status: "Fail",
+		}, err
+	}
+	return s.Import(ctx, source)
+}
+
+// Import imports the CSV at source.Url into source.Name, a source without a name is looked up
+// by its url. Concurrent calls for the same source share one import, ErrImportQueueFull is
+// returned when too many imports are already waiting.
+func (s *Service) Import(ctx context.Context, source domain.Source) (domain.Status, error) {
+	if source.Name == "" {
+		resolved, err := s.sources.Resolve("", source.Url)
+		if err != nil {
+			return domain.Status{
+				Status: "Fail",
+			}, err
+		}
+		source.Name = resolved.Name
+	}
+
+	key := fmt.Sprintf("%s|%s|%q|%t", source.Name, source.Url, source.Format.Delimiter, source.Format.SkipHeader)
+	return s.imports.do(ctx, key, func(ctx context.Context) (domain.Status, error) {
+		return s.runImport(ctx, source)
+	})
+}
+
+// runImport downloads the CSV first and then applies it in a single unit of work,
+// so a failure halfway through leaves the collection untouched.
+func (s *Service) runImport(ctx context.Context, source domain.Source) (domain.Status, error) {
+	ctx, span := tracer.Start(ctx, "import.fetch", trace.WithAttributes(
+		attribute.String("import.url", source.Url),
+		attribute.String("import.source", source.Name),
+	))
+	defer span.End()
 
-	resp, err := http.Get(req.GetUrl())
+	products, rejected, err := s.download(ctx, source.Url, source.Format)
 	if err != nil {
-		s.logger.Errorf("Get URL request error: %s", err)
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
 		return domain.Status{
 			Status: "Fail",
 		}, err
 	}
+	products, rejected = s.prepare(ctx, source, products, rejected)
+	span.SetAttributes(
+		attribute.Int("import.rows", len(products)),
+		attribute.Int("import.rejected", len(rejected)),
+	)
 
-	reader := csv.NewReader(resp.Body)
-	reader.Comma = ';'
-	records, err := reader.ReadAll()
+	var result importResult
+	err = s.imports.exclusive(func() error {
+		return s.Sorting.WithTransaction(ctx, func(ctx context.Context) error {
+			ctx, applySpan := tracer.Start(ctx, "import.apply")
+			defer applySpan.End()
+
+			result, err = s.apply(ctx, products)
+			if err != nil {
+				applySpan.RecordError(err)
+				applySpan.SetStatus(codes.Error, err.Error())
+			}
+			return err
+		})
+	})
 	if err != nil {
-		s.logger.Errorf("Read csv error: %s", err)
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		s.logger.FromContext(ctx).Errorf("Fetch request error: %s", err)
 		return domain.Status{
 			Status: "Fail",
 		}, err
 	}
 
+	// counted only after commit, a retried transaction would count twice otherwise
+	span.SetAttributes(
+		attribute.Int("import.inserted", result.inserted),
+		attribute.Int("import.updated", result.updated),
+	)
+	metrics.ImportProductsInserted.Add(float64(result.inserted))
+	metrics.ImportProductsUpdated.Add(float64(result.updated))
+	if s.alerts != nil {
+		// the import is already committed, alerts must not be cut short with the request
+		s.alerts.Notify(context.WithoutCancel(ctx), result.updates)
+	}
+
+	return domain.Status{
+		Status:   "Success",
+		Rejected: rejected,
+	}, nil
+}
+
+const (
+	// RuleColumns is the rule name of rows that don't have id, name and price.
+	RuleColumns = "columns"
+	// RuleId and RulePrice are the rule names of rows whose id or price can't be parsed.
+	RuleId    = "id"
+	RulePrice = "price"
+	// RuleDuplicateId is the rule name of rows repeating an id of an earlier row of the file.
+	RuleDuplicateId = "duplicate_id"
+)
+
+// download parses the CSV, rows without enough columns or with an id or price that doesn't parse
+// are skipped and reported.
+func (s *Service) download(ctx context.Context, url string, format domain.CSVFormat) ([]domain.Product, []domain.Rejection, error) {
+	var products []domain.Product
+	var rejected []domain.Rejection
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		s.logger.FromContext(ctx).Errorf("Build URL request error: %s", err)
+		return nil, nil, domain.NewFieldError("url", err.Error())
+	}
+
+	resp, err := s.httpClient.Do(httpReq)
+	if err != nil {
+		s.logger.FromContext(ctx).Errorf("Get URL request error: %s", err)
+		if ctx.Err() != nil {
+			return nil, nil, ctx.Err()
+		}
+		return nil, nil, fmt.Errorf("%w: %s", domain.ErrSourceUnavailable, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		s.logger.FromContext(ctx).Errorf("Get URL request status: %s", resp.Status)
+		return nil, nil, fmt.Errorf("%w: %s responded %s", domain.ErrSourceUnavailable, url, resp.Status)
+	}
+
+	body := &countingReader{reader: resp.Body}
+	defer func() {
+		metrics.ImportBytesDownloaded.Add(float64(body.n))
+	}()
+
+	reader := csv.NewReader(body)
+	reader.Comma = format.Delimiter
+	reader.FieldsPerRecord = -1
+	records, err := reader.ReadAll()
+	if err != nil {
+		s.logger.FromContext(ctx).Errorf("Read csv error: %s", err)
+		return nil, nil, &domain.FieldError{Field: "url", Description: err.Error(), Err: domain.ErrInvalidCSV}
+	}
+	if format.SkipHeader && len(records) > 0 {
+		records = records[1:]
+	}
+
 	for i, v := range records {
 		if len(v) < 3 {
-			s.logger.Warnf("skipping invalid record #%d: %v", i, v)
+			s.logger.FromContext(ctx).Warnf("skipping invalid record #%d: %v", i, v)
+			metrics.ImportRowsRejected.WithLabelValues(RuleColumns).Inc()
+			rejected = append(rejected, domain.Rejection{
+				Rule:   RuleColumns,
+				Reason: fmt.Sprintf("record #%d has %d columns, expected id, name and price", i, len(v)),
+			})
+			continue
+		}
+		// the id is the identity of the product, a row without one must not become product 0
+		Id, err := strconv.Atoi(strings.TrimSpace(v[0]))
+		if err != nil {
+			s.logger.FromContext(ctx).Warnf("skipping record #%d with invalid id %q", i, v[0])
+			metrics.ImportRowsRejected.WithLabelValues(RuleId).Inc()
+			rejected = append(rejected, domain.Rejection{
+				Name:   v[1],
+				Rule:   RuleId,
+				Reason: fmt.Sprintf("record #%d: invalid id %q", i, v[0]),
+			})
 			continue
 		}
-		Id, _ := strconv.Atoi(v[0])
 		price, err := primitive.ParseDecimal128(v[2])
 		if err != nil {
-			s.logger.Errorf("Decimal parse error: %s", err)
-			return domain.Status{
-				Status: "Fail",
-			}, err
+			s.logger.FromContext(ctx).Warnf("skipping record #%d with invalid price %q", i, v[2])
+			metrics.ImportRowsRejected.WithLabelValues(RulePrice).Inc()
+			rejected = append(rejected, domain.Rejection{
+				Id:     Id,
+				Name:   v[1],
+				Rule:   RulePrice,
+				Reason: fmt.Sprintf("record #%d: invalid price %q", i, v[2]),
+			})
+			continue
 		}
 
-		product := domain.Product{
+		products = append(products, domain.Product{
 			Id:    Id,
 			Name:  v[1],
 			Price: price,
+		})
+		metrics.ImportRowsParsed.Inc()
+	}
+
+	return products, rejected, nil
+}
+
+// prepare puts products into the source and runs them through its rules. Only the first row of
+// an id is kept, (source, id) is unique and a repeated id would fail the whole import.
+func (s *Service) prepare(ctx context.Context, source domain.Source, products []domain.Product, rejected []domain.Rejection) ([]domain.Product, []domain.Rejection) {
+	pipeline := s.sources.Rules(source.Name)
+	prepared := make([]domain.Product, 0, len(products))
+	seen := make(map[int]bool, len(products))
+	for _, product := range products {
+		if seen[product.Id] {
+			s.logger.FromContext(ctx).Warnf("skipping product %d, its id is repeated", product.Id)
+			metrics.ImportRowsRejected.WithLabelValues(RuleDuplicateId).Inc()
+			rejected = append(rejected, domain.Rejection{
+				Id:     product.Id,
+				Name:   product.Name,
+				Rule:   RuleDuplicateId,
+				Reason: fmt.Sprintf("id %d is already used by an earlier row", product.Id),
+			})
+			continue
 		}
+		seen[product.Id] = true
+
+		product.Source = source.Name
+		product, rejection := pipeline.Apply(product)
+		if rejection != nil {
+			s.logger.FromContext(ctx).Warnf("rule %s rejected product %d: %s", rejection.Rule, rejection.Id, rejection.Reason)
+			metrics.ImportRowsRejected.WithLabelValues(rejection.Rule).Inc()
+			rejected = append(rejected, *rejection)
+			continue
+		}
+		prepared = append(prepared, product)
+	}
+	return prepared, rejected
+}
 
+type countingReader struct {
+	reader io.Reader
+	n      int
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.reader.Read(p)
+	c.n += n
+	return n, err
+}
+
+type importResult struct {
+	inserted int
+	updated  int
+	updates  []domain.ProductUpdate
+}
+
+// apply may be retried by the transaction on transient errors, so it keeps no state between runs.
+func (s *Service) apply(ctx context.Context, products []domain.Product) (importResult, error) {
+	var newProducts []domain.Product
+	var result importResult
+
+	for _, product := range products {
 		exists, err := s.Sorting.GetByName(ctx, product)
 		if err != nil {
-			if err == mongo.ErrNoDocuments {
-				products = append(products, product)
-			} else {
-				return domain.Status{
-					Status: "Fail",
-				}, err
+			if errors.Is(err, domain.ErrProductNotFound) {
+				newProducts = append(newProducts, product)
+				continue
+			}
+			return importResult{}, err
+		}
+		// a soft-deleted product the source lists again comes back
+		restored := exists.DeletedAt != nil
+		if restored {
+			if err := s.Sorting.RestoreProduct(ctx, product); err != nil {
+				return importResult{}, err
 			}
 		}
-		if exists.Price != product.Price {
+		updated := changed(exists, product)
+		if updated {
 			if err := s.Sorting.UpdateProduct(ctx, product); err != nil {
-				return domain.Status{
-					Status: "Fail",
-				}, err
+				return importResult{}, err
 			}
+			result.updates = append(result.updates, domain.ProductUpdate{Old: exists, New: product})
 		}
-	}
-	status, err := s.Sorting.Fetch(ctx, products)
-	if err != nil {
-		if err == domain.ErrNoProducts {
-			status.Status = "Success"
-			return status, nil
+		if restored || updated {
+			result.updated++
 		}
-		s.logger.Errorf("Fetch request error: %s", err)
-		return domain.Status{
-			Status: "Fail",
-		}, err
 	}
-	status.Status = "Success"
-	return status, nil
+
+	if _, err := s.Sorting.Fetch(ctx, newProducts); err != nil && !errors.Is(err, domain.ErrNoProducts) {
+		return importResult{}, err
+	}
+	result.inserted = len(newProducts)
+
+	return result, nil
 }
 
+// List reads req.Source or, when it's empty, the catalog merged by the registry's merge rule.
 func (s *Service) List(ctx context.Context, req *grpcPb.ListRequest) ([]domain.Product, error) {
-	products, err := s.Sorting.List(ctx, req)
+	if req.GetSource() != "" {
+		if _, err := s.sources.Name(req.GetSource()); err != nil {
+			return []domain.Product{}, err
+		}
+	}
+	products, err := s.Sorting.List(ctx, req, s.sources.MergeRule())
 	if err != nil {
 		return []domain.Product{}, err
 	}
 	return products, nil
 }
+
+// Export streams what List would return for req without holding the catalog in memory.
+func (s *Service) Export(ctx context.Context, req *grpcPb.ListRequest, send func(domain.Product) error) error {
+	if req.GetSource() != "" {
+		if _, err := s.sources.Name(req.GetSource()); err != nil {
+			return err
+		}
+	}
+	return s.Sorting.Export(ctx, req, s.sources.MergeRule(), send)
+}
+
+// GetProduct treats soft-deleted products as missing unless includeDeleted is set.
+func (s *Service) GetProduct(ctx context.Context, source string, id int, includeDeleted bool) (domain.Product, error) {
+	source, err := s.sources.Name(source)
+	if err != nil {
+		return domain.Product{}, err
+	}
+	product, err := s.Sorting.GetByName(ctx, domain.Product{Source: source, Id: id})
+	if err != nil {
+		if errors.Is(err, domain.ErrProductNotFound) {
+			return domain.Product{}, &domain.NotFoundError{Ids: []int{id}}
+		}
+		return domain.Product{}, err
+	}
+	if product.DeletedAt != nil && !includeDeleted {
+		return domain.Product{}, &domain.NotFoundError{Ids: []int{id}}
+	}
+	return product, nil
+}
+
+// BatchGetProducts returns products in the order of ids and fails as a whole if any of them is missing.
+func (s *Service) BatchGetProducts(ctx context.Context, source string, ids []int, includeDeleted bool) ([]domain.Product, error) {
+	source, err := s.sources.Name(source)
+	if err != nil {
+		return nil, err
+	}
+	found, err := s.Sorting.GetByIds(ctx, source, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	byId := make(map[int]domain.Product, len(found))
+	for _, product := range found {
+		if product.DeletedAt != nil && !includeDeleted {
+			continue
+		}
+		byId[product.Id] = product
+	}
+
+	products := make([]domain.Product, 0, len(ids))
+	var missing []int
+	for _, id := range ids {
+		product, ok := byId[id]
+		if !ok {
+			missing = append(missing, id)
+			continue
+		}
+		products = append(products, product)
+	}
+	if len(missing) > 0 {
+		return nil, &domain.NotFoundError{Ids: missing}
+	}
+	return products, nil
+}
+
+// DeleteProduct soft-deletes a product of product.Source, the default source if it's empty.
+func (s *Service) DeleteProduct(ctx context.Context, product domain.Product) error {
+	source, err := s.sources.Name(product.Source)
+	if err != nil {
+		return err
+	}
+	product.Source = source
+	return s.Sorting.DeleteProduct(ctx, product)
+}
+
+func (s *Service) RestoreProduct(ctx context.Context, product domain.Product) error {
+	source, err := s.sources.Name(product.Source)
+	if err != nil {
+		return err
+	}
+	product.Source = source
+	return s.Sorting.RestoreProduct(ctx, product)
+}
+
+func (s *Service) ListSources(ctx context.Context) domain.SourceRegistry {
+	return domain.SourceRegistry{
+		Sources: s.sources.List(),
+		Default: s.sources.Default(),
+		Merge:   s.sources.MergeRule().Strategy,
+	}
+}
+
+const (
+	defaultHistogramBuckets = 10
+	defaultChangedDays      = 7
+	defaultTopChanged       = 5
+)
+
+// GetStats fills in defaults and rejects filters the aggregation can't run with.
+func (s *Service) GetStats(ctx context.Context, filter domain.StatsFilter) (domain.Stats, error) {
+	if filter.MinPrice != nil && filter.MaxPrice != nil && compareDecimal(*filter.MinPrice, *filter.MaxPrice) > 0 {
+		return domain.Stats{}, domain.NewFieldError("min_price", "min price is greater than max price")
+	}
+	if len(filter.HistogramBounds) == 1 {
+		return domain.Stats{}, domain.NewFieldError("histogram_bounds", "histogram needs at least two bounds")
+	}
+	for i := 1; i < len(filter.HistogramBounds); i++ {
+		if compareDecimal(filter.HistogramBounds[i-1], filter.HistogramBounds[i]) >= 0 {
+			return domain.Stats{}, domain.NewFieldError("histogram_bounds", "histogram bounds must be strictly increasing")
+		}
+	}
+	if filter.HistogramBuckets < 0 {
+		return domain.Stats{}, domain.NewFieldError("histogram_buckets", "must not be negative")
+	}
+	if filter.ChangedDays < 0 {
+		return domain.Stats{}, domain.NewFieldError("changed_days", "must not be negative")
+	}
+	if filter.TopChanged < 0 {
+		return domain.Stats{}, domain.NewFieldError("top_changed", "must not be negative")
+	}
+
+	if filter.HistogramBuckets == 0 {
+		filter.HistogramBuckets = defaultHistogramBuckets
+	}
+	if filter.TopChanged == 0 {
+		filter.TopChanged = defaultTopChanged
+	}
+	if filter.ChangedDays == 0 {
+		filter.ChangedDays = defaultChangedDays
+	}
+	filter.ChangedSince = time.Now().AddDate(0, 0, -int(filter.ChangedDays))
+
+	return s.Sorting.GetStats(ctx, filter)
+}
+
+// compareDecimal returns -1, 0 or 1; values big.Float can't parse (NaN, Inf) compare as equal.
+func compareDecimal(a, b primitive.Decimal128) int {
+	x, okX := new(big.Float).SetString(a.String())
+	y, okY := new(big.Float).SetString(b.String())
+	if !okX || !okY {
+		return 0
+	}
+	return x.Cmp(y)
+}
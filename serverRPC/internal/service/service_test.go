@@ -4,160 +4,171 @@ import (
 	"context"
 	"errors"
 	"gRPC-server/internal/domain"
+	"gRPC-server/internal/metrics"
 	mock_service "gRPC-server/internal/service/mocks"
+	"gRPC-server/internal/sources"
 	"gRPC-server/pkg/logger"
 	"gRPC-server/pkg/parseCSV/grpcPb"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/golang/mock/gomock"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/spf13/viper"
 	"github.com/stretchr/testify/assert"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
-/* //переделать
+// expectTransaction lets the service run its transaction body as is.
+func expectTransaction(m *mock_service.MockSorting) *gomock.Call {
+	return m.EXPECT().WithTransaction(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, fn func(ctx context.Context) error) error {
+			return fn(ctx)
+		})
+}
+
 func TestFetch(t *testing.T) {
 	logger := logger.GetLogger()
-	type mockBehavior func(m *mock_service.MockSorting, ctx context.Context, req *grpcPb.FetchRequest, products []domain.Product)
-	//type Handler func(products []domain.Product, c *gin.Context)
+	type mockBehavior func(m *mock_service.MockSorting, products []domain.Product)
+
+	price := func(v string) primitive.Decimal128 {
+		got, _ := primitive.ParseDecimal128(v)
+		return got
+	}
+
 	testTables := []struct {
 		name         string
-		ctx          context.Context
-		req          *grpcPb.FetchRequest
+		requestBody  string
 		mockBehavior mockBehavior
-		Url          string
-		want         *grpcPb.FethResponce
 		products     []domain.Product
-		product      domain.Product
-		requestBody  string
-		Handler      func(c *gin.Context)
+		want         domain.Status
 		isErr        bool
 	}{
 		{
-			name: "Valid",
-			Url:  "http://localhost:8888/products",
-			req: &grpcPb.FetchRequest{
-				Url: "http://localhost:8888/products",
+			name:        "Valid",
+			requestBody: "1;name;50.00\n2;Name2;60.00\n3;Name3;70.00",
+			products: []domain.Product{
+				{Source: "default", Id: 1, Name: "name", Price: price("50.00")},
+				{Source: "default", Id: 2, Name: "Name2", Price: price("60.00")},
+				{Source: "default", Id: 3, Name: "Name3", Price: price("70.00")},
+			},
+			mockBehavior: func(m *mock_service.MockSorting, products []domain.Product) {
+				expectTransaction(m)
+				m.EXPECT().GetByName(gomock.Any(), products[0]).Return(domain.Product{}, domain.ErrProductNotFound)
+				m.EXPECT().GetByName(gomock.Any(), products[1]).Return(products[1], nil)
+				m.EXPECT().GetByName(gomock.Any(), products[2]).Return(domain.Product{Id: 3, Name: "Name3", Price: price("65.00")}, nil)
+				m.EXPECT().UpdateProduct(gomock.Any(), products[2]).Return(nil)
+				m.EXPECT().Fetch(gomock.Any(), []domain.Product{products[0]}).Return(domain.Status{}, nil)
 			},
-			ctx: context.Background(),
-			want: &grpcPb.FethResponce{
+			want: domain.Status{
 				Status: "Success",
 			},
-			Handler: func(c *gin.Context) {
-				products := []domain.Product{
-					{
-						Id:   1,
-						Name: "name",
-						Price: func() primitive.Decimal128 {
-							got, _ := primitive.ParseDecimal128("50.00")
-							return got
-						}(),
-					},
-					{
-						Id:   2,
-						Name: "Name2",
-						Price: func() primitive.Decimal128 {
-							got, _ := primitive.ParseDecimal128("60.00")
-							return got
-						}(),
-					},
-					{
-						Id:   3,
-						Name: "Name3",
-						Price: func() primitive.Decimal128 {
-							got, _ := primitive.ParseDecimal128("70.00")
-							return got
-						}(),
-					},
-				}
-				writer := csv.NewWriter(c.Writer)
-				// Устанавливаем разделитель ';'
-				writer.Comma = ';'
-				defer writer.Flush()
-
-				// Записываем строки данных
-				for _, product := range products {
-					record := []string{
-						strconv.Itoa(product.Id),
-						product.Name,
-						product.Price.String(),
-					}
-					if err := writer.Write(record); err != nil {
-						c.JSON(500, gin.H{"error": "Internal Server Error"})
-						return
-					}
-				}
+			isErr: false,
+		},
+		{
+			name:        "Nothing new",
+			requestBody: "1;name;50.00",
+			products: []domain.Product{
+				{Source: "default", Id: 1, Name: "name", Price: price("50.00")},
+			},
+			mockBehavior: func(m *mock_service.MockSorting, products []domain.Product) {
+				expectTransaction(m)
+				m.EXPECT().GetByName(gomock.Any(), products[0]).Return(products[0], nil)
+				m.EXPECT().Fetch(gomock.Any(), nil).Return(domain.Status{}, domain.ErrNoProducts)
+			},
+			want: domain.Status{
+				Status: "Success",
 			},
+			isErr: false,
+		},
+		{
+			name:        "Soft-deleted product is restored",
+			requestBody: "1;name;50.00\n2;Name2;60.00",
 			products: []domain.Product{
-				{
-					Id:   1,
-					Name: "name",
-					Price: func() primitive.Decimal128 {
-						got, _ := primitive.ParseDecimal128("50.00")
-						return got
-					}(),
-				},
-				{
-					Id:   2,
-					Name: "Name2",
-					Price: func() primitive.Decimal128 {
-						got, _ := primitive.ParseDecimal128("60.00")
-						return got
-					}(),
-				},
-				{
-					Id:   3,
-					Name: "Name3",
-					Price: func() primitive.Decimal128 {
-						got, _ := primitive.ParseDecimal128("70.00")
-						return got
-					}(),
-				},
+				{Source: "default", Id: 1, Name: "name", Price: price("50.00")},
+				{Source: "default", Id: 2, Name: "Name2", Price: price("60.00")},
 			},
-			requestBody: "1;name;50.00\n2;Name2;60.00\n3;Name3;70.00",
-			mockBehavior: func(m *mock_service.MockSorting, ctx context.Context, req *grpcPb.FetchRequest, products []domain.Product) {
-				for _, product := range products {
-					m.EXPECT().GetByName(ctx, product).Return(product, mongo.ErrNoDocuments)
-				}
-
-				m.EXPECT().Fetch(ctx, products).Return(domain.Status{
-					Status: "Success",
-				}, nil)
+			mockBehavior: func(m *mock_service.MockSorting, products []domain.Product) {
+				deletedAt := time.Now()
+				expectTransaction(m)
+				m.EXPECT().GetByName(gomock.Any(), products[0]).Return(domain.Product{Id: 1, Name: "name", Price: price("50.00"), DeletedAt: &deletedAt}, nil)
+				m.EXPECT().RestoreProduct(gomock.Any(), products[0]).Return(nil)
+				m.EXPECT().GetByName(gomock.Any(), products[1]).Return(domain.Product{Id: 2, Name: "Name2", Price: price("55.00"), DeletedAt: &deletedAt}, nil)
+				m.EXPECT().RestoreProduct(gomock.Any(), products[1]).Return(nil)
+				m.EXPECT().UpdateProduct(gomock.Any(), products[1]).Return(nil)
+				m.EXPECT().Fetch(gomock.Any(), nil).Return(domain.Status{}, domain.ErrNoProducts)
+			},
+			want: domain.Status{
+				Status: "Success",
+			},
+			isErr: false,
+		},
+		{
+			name:        "Update error rolls back",
+			requestBody: "1;name;50.00",
+			products: []domain.Product{
+				{Source: "default", Id: 1, Name: "name", Price: price("50.00")},
+			},
+			mockBehavior: func(m *mock_service.MockSorting, products []domain.Product) {
+				expectTransaction(m)
+				m.EXPECT().GetByName(gomock.Any(), products[0]).Return(domain.Product{Id: 1, Name: "name", Price: price("40.00")}, nil)
+				m.EXPECT().UpdateProduct(gomock.Any(), products[0]).Return(errors.New("some error"))
+			},
+			want: domain.Status{
+				Status: "Fail",
+			},
+			isErr: true,
+		},
+		{
+			name:        "Invalid price and id are rejected",
+			requestBody: "1;name;fifty\nabc;Name2;60.00\n3;Name3;70.00",
+			products: []domain.Product{
+				{Source: "default", Id: 3, Name: "Name3", Price: price("70.00")},
+			},
+			mockBehavior: func(m *mock_service.MockSorting, products []domain.Product) {
+				expectTransaction(m)
+				m.EXPECT().GetByName(gomock.Any(), products[0]).Return(domain.Product{}, domain.ErrProductNotFound)
+				m.EXPECT().Fetch(gomock.Any(), products).Return(domain.Status{}, nil)
+			},
+			want: domain.Status{
+				Status: "Success",
+				Rejected: []domain.Rejection{
+					{Id: 1, Name: "name", Rule: RulePrice, Reason: `record #0: invalid price "fifty"`},
+					{Name: "Name2", Rule: RuleId, Reason: `record #1: invalid id "abc"`},
+				},
 			},
 			isErr: false,
 		},
 	}
 
-	r := gin.New()
-
-	go r.Run("localhost:8888")
-
-	for i := range testTables {
-		table := &testTables[i]
+	for _, table := range testTables {
 		t.Run(table.name, func(t *testing.T) {
 			c := gomock.NewController(t)
 			defer c.Finish()
 
-			new_service := mock_service.NewMockSorting(c)
-
-			service := NewService(new_service, logger)
-			table.mockBehavior(new_service, table.ctx, table.req, table.products)
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte(table.requestBody))
+			}))
+			defer srv.Close()
 
-			time.Sleep(1 * time.Second)
-
-			r.POST("/products/", table.Handler)
-			//req := httptest.NewRequest("POST", "http://localhost:8888/poducts/", bytes.NewBufferString(table.requestBod
+			mockService := mock_service.NewMockSorting(c)
+			service := NewService(mockService, testSources(), logger)
+			table.mockBehavior(mockService, table.products)
 
-			got, err := service.Fetch(table.ctx, table.req)
+			got, err := service.Fetch(context.Background(), &grpcPb.FetchRequest{Url: srv.URL})
 			if table.isErr {
 				assert.Error(t, err)
 			} else {
 				assert.NoError(t, err)
-				assert.Equal(t, table.want, got)
 			}
+			assert.Equal(t, table.want, got)
 		})
 	}
 }
-*/
 
 func TestList(t *testing.T) {
 	type mockBehavior func(m *mock_service.MockSorting, ctx context.Context, req *grpcPb.ListRequest, products []domain.Product)
@@ -173,7 +184,7 @@ func TestList(t *testing.T) {
 		{
 			name: "Valid",
 			mockBehavior: func(m *mock_service.MockSorting, ctx context.Context, req *grpcPb.ListRequest, products []domain.Product) {
-				m.EXPECT().List(ctx, req).Return(products, nil)
+				m.EXPECT().List(ctx, req, domain.MergeRule{Strategy: domain.MergePriority, Priorities: map[string]int{"default": 0}}).Return(products, nil)
 			},
 			products: []domain.Product{
 				{
@@ -212,7 +223,7 @@ func TestList(t *testing.T) {
 		{
 			name: "Repository error",
 			mockBehavior: func(m *mock_service.MockSorting, ctx context.Context, req *grpcPb.ListRequest, products []domain.Product) {
-				m.EXPECT().List(ctx, req).Return(products, errors.New("some error"))
+				m.EXPECT().List(ctx, req, domain.MergeRule{Strategy: domain.MergePriority, Priorities: map[string]int{"default": 0}}).Return(products, errors.New("some error"))
 			},
 			req: &grpcPb.ListRequest{
 				SortField:    1,
@@ -232,7 +243,7 @@ func TestList(t *testing.T) {
 			defer c.Finish()
 			mockService := mock_service.NewMockSorting(c)
 
-			service := NewService(mockService, logger)
+			service := NewService(mockService, testSources(), logger)
 
 			table.mockBehavior(mockService, table.ctx, table.req, table.products)
 
@@ -247,3 +258,351 @@ func TestList(t *testing.T) {
 		})
 	}
 }
+
+func TestExport(t *testing.T) {
+	logger := logger.GetLogger()
+
+	t.Run("Merged catalog", func(t *testing.T) {
+		c := gomock.NewController(t)
+		defer c.Finish()
+
+		req := &grpcPb.ListRequest{SortAsc: 1}
+		mockService := mock_service.NewMockSorting(c)
+		mockService.EXPECT().Export(gomock.Any(), req, domain.MergeRule{Strategy: domain.MergePriority, Priorities: map[string]int{"default": 0}}, gomock.Any()).DoAndReturn(
+			func(ctx context.Context, req *grpcPb.ListRequest, merge domain.MergeRule, send func(domain.Product) error) error {
+				return send(domain.Product{Id: 1, Name: "name"})
+			})
+
+		var got []domain.Product
+		err := NewService(mockService, testSources(), logger).Export(context.Background(), req, func(product domain.Product) error {
+			got = append(got, product)
+			return nil
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, []domain.Product{{Id: 1, Name: "name"}}, got)
+	})
+
+	t.Run("Unknown source", func(t *testing.T) {
+		c := gomock.NewController(t)
+		defer c.Finish()
+
+		err := NewService(mock_service.NewMockSorting(c), testSources(), logger).Export(context.Background(), &grpcPb.ListRequest{SortAsc: 1, Source: "shop"}, func(domain.Product) error {
+			return nil
+		})
+		var fieldErr *domain.FieldError
+		if assert.ErrorAs(t, err, &fieldErr) {
+			assert.Equal(t, "source", fieldErr.Field)
+		}
+	})
+}
+
+func TestGetProduct(t *testing.T) {
+	deletedAt := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	testTables := []struct {
+		name           string
+		id             int
+		includeDeleted bool
+		found          domain.Product
+		foundErr       error
+		want           domain.Product
+		wantErr        error
+	}{
+		{
+			name:  "Valid",
+			id:    1,
+			found: domain.Product{Id: 1, Name: "name"},
+			want:  domain.Product{Id: 1, Name: "name"},
+		},
+		{
+			name:     "Not found",
+			id:       2,
+			foundErr: domain.ErrProductNotFound,
+			wantErr:  domain.ErrProductNotFound,
+		},
+		{
+			name:    "Deleted",
+			id:      3,
+			found:   domain.Product{Id: 3, Name: "name", DeletedAt: &deletedAt},
+			wantErr: domain.ErrProductNotFound,
+		},
+		{
+			name:           "Deleted included",
+			id:             3,
+			includeDeleted: true,
+			found:          domain.Product{Id: 3, Name: "name", DeletedAt: &deletedAt},
+			want:           domain.Product{Id: 3, Name: "name", DeletedAt: &deletedAt},
+		},
+	}
+
+	logger := logger.GetLogger()
+	for _, table := range testTables {
+		t.Run(table.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+			mockService := mock_service.NewMockSorting(c)
+			mockService.EXPECT().GetByName(gomock.Any(), domain.Product{Source: sources.DefaultName, Id: table.id}).Return(table.found, table.foundErr)
+
+			service := NewService(mockService, testSources(), logger)
+			got, err := service.GetProduct(context.Background(), "", table.id, table.includeDeleted)
+
+			if table.wantErr != nil {
+				assert.ErrorIs(t, err, table.wantErr)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, table.want, got)
+			}
+		})
+	}
+}
+
+func TestBatchGetProducts(t *testing.T) {
+	deletedAt := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	testTables := []struct {
+		name           string
+		ids            []int
+		includeDeleted bool
+		found          []domain.Product
+		want           []domain.Product
+		wantErr        error
+	}{
+		{
+			name:  "Keeps request order",
+			ids:   []int{3, 1, 3},
+			found: []domain.Product{{Id: 1, Name: "one"}, {Id: 3, Name: "three"}},
+			want:  []domain.Product{{Id: 3, Name: "three"}, {Id: 1, Name: "one"}, {Id: 3, Name: "three"}},
+		},
+		{
+			name:    "Missing id",
+			ids:     []int{1, 2},
+			found:   []domain.Product{{Id: 1, Name: "one"}},
+			wantErr: domain.ErrProductNotFound,
+		},
+		{
+			name:    "Deleted is missing",
+			ids:     []int{1},
+			found:   []domain.Product{{Id: 1, Name: "one", DeletedAt: &deletedAt}},
+			wantErr: domain.ErrProductNotFound,
+		},
+		{
+			name:           "Deleted included",
+			ids:            []int{1},
+			includeDeleted: true,
+			found:          []domain.Product{{Id: 1, Name: "one", DeletedAt: &deletedAt}},
+			want:           []domain.Product{{Id: 1, Name: "one", DeletedAt: &deletedAt}},
+		},
+	}
+
+	logger := logger.GetLogger()
+	for _, table := range testTables {
+		t.Run(table.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+			mockService := mock_service.NewMockSorting(c)
+			mockService.EXPECT().GetByIds(gomock.Any(), sources.DefaultName, table.ids).Return(table.found, nil)
+
+			service := NewService(mockService, testSources(), logger)
+			got, err := service.BatchGetProducts(context.Background(), "", table.ids, table.includeDeleted)
+
+			if table.wantErr != nil {
+				assert.ErrorIs(t, err, table.wantErr)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, table.want, got)
+			}
+		})
+	}
+}
+
+func TestGetStats(t *testing.T) {
+	price := func(v string) primitive.Decimal128 {
+		got, _ := primitive.ParseDecimal128(v)
+		return got
+	}
+	ptr := func(v primitive.Decimal128) *primitive.Decimal128 { return &v }
+
+	testTables := []struct {
+		name    string
+		filter  domain.StatsFilter
+		want    func(t *testing.T, filter domain.StatsFilter)
+		wantErr error
+	}{
+		{
+			name:   "Defaults",
+			filter: domain.StatsFilter{Name: "name"},
+			want: func(t *testing.T, filter domain.StatsFilter) {
+				assert.Equal(t, "name", filter.Name)
+				assert.Equal(t, int32(10), filter.HistogramBuckets)
+				assert.Equal(t, int32(5), filter.TopChanged)
+				assert.Equal(t, int32(7), filter.ChangedDays)
+				assert.WithinDuration(t, time.Now().AddDate(0, 0, -7), filter.ChangedSince, time.Minute)
+			},
+		},
+		{
+			name:    "Min greater than max",
+			filter:  domain.StatsFilter{MinPrice: ptr(price("100")), MaxPrice: ptr(price("9.99"))},
+			wantErr: domain.ErrInvalidArgument,
+		},
+		{
+			name:    "Single bound",
+			filter:  domain.StatsFilter{HistogramBounds: []primitive.Decimal128{price("10")}},
+			wantErr: domain.ErrInvalidArgument,
+		},
+		{
+			name:    "Bounds not increasing",
+			filter:  domain.StatsFilter{HistogramBounds: []primitive.Decimal128{price("10"), price("5.5"), price("20")}},
+			wantErr: domain.ErrInvalidArgument,
+		},
+	}
+
+	logger := logger.GetLogger()
+	for _, table := range testTables {
+		t.Run(table.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+			mockService := mock_service.NewMockSorting(c)
+			if table.wantErr == nil {
+				mockService.EXPECT().GetStats(gomock.Any(), gomock.Any()).DoAndReturn(
+					func(ctx context.Context, filter domain.StatsFilter) (domain.Stats, error) {
+						table.want(t, filter)
+						return domain.Stats{Total: 1}, nil
+					})
+			}
+
+			service := NewService(mockService, testSources(), logger)
+			got, err := service.GetStats(context.Background(), table.filter)
+
+			if table.wantErr != nil {
+				assert.ErrorIs(t, err, table.wantErr)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, domain.Stats{Total: 1}, got)
+			}
+		})
+	}
+}
+
+func TestFetchMetrics(t *testing.T) {
+	logger := logger.GetLogger()
+	body := "1;name;50.00\n2;Name2;60.00\nbroken\n"
+	price := func(v string) primitive.Decimal128 {
+		got, _ := primitive.ParseDecimal128(v)
+		return got
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(body))
+	}))
+	defer srv.Close()
+
+	c := gomock.NewController(t)
+	defer c.Finish()
+	mockService := mock_service.NewMockSorting(c)
+	expectTransaction(mockService)
+	mockService.EXPECT().GetByName(gomock.Any(), gomock.Any()).Return(domain.Product{}, domain.ErrProductNotFound)
+	mockService.EXPECT().GetByName(gomock.Any(), gomock.Any()).Return(domain.Product{Id: 2, Name: "Name2", Price: price("55.00")}, nil)
+	mockService.EXPECT().UpdateProduct(gomock.Any(), gomock.Any()).Return(nil)
+	mockService.EXPECT().Fetch(gomock.Any(), gomock.Any()).Return(domain.Status{}, nil)
+
+	parsed := testutil.ToFloat64(metrics.ImportRowsParsed)
+	rejected := testutil.ToFloat64(metrics.ImportRowsRejected.WithLabelValues(RuleColumns))
+	inserted := testutil.ToFloat64(metrics.ImportProductsInserted)
+	updated := testutil.ToFloat64(metrics.ImportProductsUpdated)
+	downloaded := testutil.ToFloat64(metrics.ImportBytesDownloaded)
+
+	service := NewService(mockService, testSources(), logger)
+	_, err := service.Fetch(context.Background(), &grpcPb.FetchRequest{Url: srv.URL})
+
+	assert.NoError(t, err)
+	assert.Equal(t, float64(2), testutil.ToFloat64(metrics.ImportRowsParsed)-parsed)
+	assert.Equal(t, float64(1), testutil.ToFloat64(metrics.ImportRowsRejected.WithLabelValues(RuleColumns))-rejected)
+	assert.Equal(t, float64(1), testutil.ToFloat64(metrics.ImportProductsInserted)-inserted)
+	assert.Equal(t, float64(1), testutil.ToFloat64(metrics.ImportProductsUpdated)-updated)
+	assert.Equal(t, float64(len(body)), testutil.ToFloat64(metrics.ImportBytesDownloaded)-downloaded)
+}
+
+func TestFetchSharesImport(t *testing.T) {
+	logger := logger.GetLogger()
+
+	var hits atomic.Int32
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		<-release
+		w.Write([]byte("1;name;50.00"))
+	}))
+	defer srv.Close()
+
+	c := gomock.NewController(t)
+	defer c.Finish()
+	mockService := mock_service.NewMockSorting(c)
+	expectTransaction(mockService).Times(1)
+	mockService.EXPECT().GetByName(gomock.Any(), gomock.Any()).Return(domain.Product{}, domain.ErrProductNotFound).Times(1)
+	mockService.EXPECT().Fetch(gomock.Any(), gomock.Any()).Return(domain.Status{}, nil).Times(1)
+
+	service := NewService(mockService, testSources(), logger)
+
+	var wg sync.WaitGroup
+	results := make([]error, 3)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, results[i] = service.Fetch(context.Background(), &grpcPb.FetchRequest{Url: srv.URL})
+		}(i)
+	}
+
+	assert.Eventually(t, func() bool { return hits.Load() == 1 }, time.Second, 10*time.Millisecond)
+	// give the other calls time to join the running import
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	assert.Equal(t, int32(1), hits.Load())
+	for _, err := range results {
+		assert.NoError(t, err)
+	}
+}
+
+func TestFetchQueueFull(t *testing.T) {
+	logger := logger.GetLogger()
+	viper.Set("fetch.max_concurrent", 1)
+	viper.Set("fetch.queue_size", 0)
+	defer func() {
+		viper.Set("fetch.max_concurrent", nil)
+		viper.Set("fetch.queue_size", nil)
+	}()
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer srv.Close()
+
+	c := gomock.NewController(t)
+	defer c.Finish()
+	service := NewService(mock_service.NewMockSorting(c), testSources(), logger)
+
+	done := make(chan error)
+	go func() {
+		_, err := service.Fetch(context.Background(), &grpcPb.FetchRequest{Url: srv.URL + "/first"})
+		done <- err
+	}()
+	<-started
+
+	got, err := service.Fetch(context.Background(), &grpcPb.FetchRequest{Url: srv.URL + "/second"})
+	assert.ErrorIs(t, err, domain.ErrImportQueueFull)
+	assert.Equal(t, domain.Status{Status: "Fail"}, got)
+
+	close(release)
+	assert.ErrorIs(t, <-done, domain.ErrSourceUnavailable)
+}
+
+func testSources() *sources.Registry {
+	registry, _ := sources.New(nil, "", "")
+	return registry
+}
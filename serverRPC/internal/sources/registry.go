@@ -0,0 +1,201 @@
+package sources
+
+import (
+	"fmt"
+	"gRPC-server/internal/domain"
+	"gRPC-server/internal/rules"
+	"slices"
+	"unicode/utf8"
+
+	"github.com/spf13/viper"
+)
+
+// DefaultName is used when sources.default is not set, products imported before sources
+// existed are moved under it.
+const DefaultName = "default"
+
+// Registry knows every source products can come from and how the merged catalog picks between them.
+type Registry struct {
+	sources       map[string]domain.Source
+	names         []string
+	defaultSource string
+	merge         string
+	rules         map[string]rules.Pipeline
+}
+
+type sourceConfig struct {
+	Name       string `mapstructure:"name"`
+	Url        string `mapstructure:"url"`
+	Priority   int    `mapstructure:"priority"`
+	Delimiter  string `mapstructure:"delimiter"`
+	SkipHeader bool   `mapstructure:"skip_header"`
+	// nil means sources.rules, an empty list turns rules off for the source
+	Rules *[]rules.Config `mapstructure:"rules"`
+}
+
+// FromConfig reads sources.registry, sources.default, sources.merge and sources.rules.
+func FromConfig() (*Registry, error) {
+	var configs []sourceConfig
+	if err := viper.UnmarshalKey("sources.registry", &configs); err != nil {
+		return nil, fmt.Errorf("sources.registry: %w", err)
+	}
+	var defaultRules []rules.Config
+	if err := viper.UnmarshalKey("sources.rules", &defaultRules); err != nil {
+		return nil, fmt.Errorf("sources.rules: %w", err)
+	}
+	fallback, err := rules.Build(defaultRules)
+	if err != nil {
+		return nil, fmt.Errorf("sources.rules: %w", err)
+	}
+	pipelines := make(map[string]rules.Pipeline, len(configs))
+
+	list := make([]domain.Source, 0, len(configs))
+	for _, c := range configs {
+		format := domain.DefaultCSVFormat
+		format.SkipHeader = c.SkipHeader
+		if c.Delimiter != "" {
+			r, size := utf8.DecodeRuneInString(c.Delimiter)
+			if size != len(c.Delimiter) || r == utf8.RuneError {
+				return nil, fmt.Errorf("sources.registry: %s: delimiter must be a single character", c.Name)
+			}
+			format.Delimiter = r
+		}
+		if c.Rules != nil {
+			pipeline, err := rules.Build(*c.Rules)
+			if err != nil {
+				return nil, fmt.Errorf("sources.registry: %s: %w", c.Name, err)
+			}
+			pipelines[c.Name] = pipeline
+		}
+		list = append(list, domain.Source{
+			Name:     c.Name,
+			Url:      c.Url,
+			Format:   format,
+			Priority: c.Priority,
+		})
+	}
+
+	registry, err := New(list, viper.GetString("sources.default"), viper.GetString("sources.merge"))
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range registry.names {
+		pipeline, ok := pipelines[name]
+		if !ok {
+			pipeline = fallback
+		}
+		registry.SetRules(name, pipeline)
+	}
+	return registry, nil
+}
+
+// New builds a registry. The default source is added with the web-app format if the list
+// doesn't have it, an empty merge strategy means priority.
+func New(list []domain.Source, defaultSource, merge string) (*Registry, error) {
+	if defaultSource == "" {
+		defaultSource = DefaultName
+	}
+	switch merge {
+	case "":
+		merge = domain.MergePriority
+	case domain.MergePriority, domain.MergeLowestPrice, domain.MergeNewest:
+	default:
+		return nil, fmt.Errorf("sources.merge: unknown strategy %q", merge)
+	}
+
+	r := &Registry{
+		sources:       make(map[string]domain.Source, len(list)+1),
+		defaultSource: defaultSource,
+		merge:         merge,
+		rules:         make(map[string]rules.Pipeline),
+	}
+	for _, source := range list {
+		if source.Name == "" {
+			return nil, fmt.Errorf("sources.registry: source without a name")
+		}
+		if _, ok := r.sources[source.Name]; ok {
+			return nil, fmt.Errorf("sources.registry: duplicate source %q", source.Name)
+		}
+		r.sources[source.Name] = source
+		r.names = append(r.names, source.Name)
+	}
+	if _, ok := r.sources[defaultSource]; !ok {
+		r.sources[defaultSource] = domain.Source{Name: defaultSource, Format: domain.DefaultCSVFormat}
+		r.names = append(r.names, defaultSource)
+	}
+	return r, nil
+}
+
+// Default is the source of requests that don't name one.
+func (r *Registry) Default() string {
+	return r.defaultSource
+}
+
+// List returns the sources in config order.
+func (r *Registry) List() []domain.Source {
+	list := make([]domain.Source, len(r.names))
+	for i, name := range r.names {
+		list[i] = r.sources[name]
+	}
+	return list
+}
+
+func (r *Registry) Get(name string) (domain.Source, bool) {
+	source, ok := r.sources[name]
+	return source, ok
+}
+
+// Name checks a source name from a request, empty means the default source.
+func (r *Registry) Name(name string) (string, error) {
+	if name == "" {
+		return r.defaultSource, nil
+	}
+	if _, ok := r.sources[name]; !ok {
+		return "", domain.NewFieldError("source", fmt.Sprintf("unknown source %q", name))
+	}
+	return name, nil
+}
+
+// Resolve finds the source an import of url belongs to. A named source must be registered and
+// url, if given, overrides its url. Without a name the source is looked up by url and falls
+// back to the default one.
+func (r *Registry) Resolve(name, url string) (domain.Source, error) {
+	if name == "" {
+		i := slices.IndexFunc(r.names, func(n string) bool { return url != "" && r.sources[n].Url == url })
+		name = r.defaultSource
+		if i >= 0 {
+			name = r.names[i]
+		}
+	}
+
+	source, ok := r.sources[name]
+	if !ok {
+		return domain.Source{}, domain.NewFieldError("source", fmt.Sprintf("unknown source %q", name))
+	}
+	if url != "" {
+		source.Url = url
+	}
+	if source.Url == "" {
+		return domain.Source{}, domain.NewFieldError("url", "must not be empty when the source has no url")
+	}
+	return source, nil
+}
+
+// SetRules replaces the rules imports into the source go through.
+func (r *Registry) SetRules(name string, pipeline rules.Pipeline) {
+	r.rules[name] = pipeline
+}
+
+// Rules returns the pipeline of the source, sources without one import rows as they are.
+func (r *Registry) Rules(name string) rules.Pipeline {
+	return r.rules[name]
+}
+
+// MergeRule is what the storage needs to build the merged catalog.
+func (r *Registry) MergeRule() domain.MergeRule {
+	priorities := make(map[string]int, len(r.sources))
+	for name, source := range r.sources {
+		priorities[name] = source.Priority
+	}
+	return domain.MergeRule{Strategy: r.merge, Priorities: priorities}
+}
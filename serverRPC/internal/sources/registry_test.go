@@ -0,0 +1,137 @@
+package sources
+
+import (
+	"gRPC-server/internal/domain"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResolve(t *testing.T) {
+	registry, err := New([]domain.Source{
+		{Name: "web-app", Url: "http://web-app:8085/products/", Format: domain.DefaultCSVFormat, Priority: 10},
+		{Name: "supplier", Format: domain.CSVFormat{Delimiter: ',', SkipHeader: true}},
+	}, "web-app", "")
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	testTables := []struct {
+		name       string
+		source     string
+		url        string
+		wantSource string
+		wantUrl    string
+		field      string
+	}{
+		{
+			name:       "Registered url",
+			url:        "http://web-app:8085/products/",
+			wantSource: "web-app",
+			wantUrl:    "http://web-app:8085/products/",
+		},
+		{
+			name:       "Unknown url goes to default",
+			url:        "http://other/products.csv",
+			wantSource: "web-app",
+			wantUrl:    "http://other/products.csv",
+		},
+		{
+			name:       "Named source uses its url",
+			source:     "web-app",
+			wantSource: "web-app",
+			wantUrl:    "http://web-app:8085/products/",
+		},
+		{
+			name:       "Named source with url",
+			source:     "supplier",
+			url:        "http://supplier/prices.csv",
+			wantSource: "supplier",
+			wantUrl:    "http://supplier/prices.csv",
+		},
+		{
+			name:   "Source without url",
+			source: "supplier",
+			field:  "url",
+		},
+		{
+			name:   "Unknown source",
+			source: "nobody",
+			url:    "http://other/products.csv",
+			field:  "source",
+		},
+	}
+
+	for _, table := range testTables {
+		t.Run(table.name, func(t *testing.T) {
+			got, err := registry.Resolve(table.source, table.url)
+			if table.field != "" {
+				var fieldErr *domain.FieldError
+				if assert.ErrorAs(t, err, &fieldErr) {
+					assert.Equal(t, table.field, fieldErr.Field)
+				}
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, table.wantSource, got.Name)
+			assert.Equal(t, table.wantUrl, got.Url)
+		})
+	}
+}
+
+func TestNew(t *testing.T) {
+	registry, err := New(nil, "", "")
+	if assert.NoError(t, err) {
+		assert.Equal(t, DefaultName, registry.Default())
+		assert.Equal(t, []domain.Source{{Name: DefaultName, Format: domain.DefaultCSVFormat}}, registry.List())
+		assert.Equal(t, domain.MergeRule{Strategy: domain.MergePriority, Priorities: map[string]int{DefaultName: 0}}, registry.MergeRule())
+	}
+
+	_, err = New([]domain.Source{{Name: "a"}, {Name: "a"}}, "", "")
+	assert.Error(t, err)
+
+	_, err = New(nil, "", "cheapest")
+	assert.Error(t, err)
+
+	name, err := registry.Name("")
+	assert.NoError(t, err)
+	assert.Equal(t, DefaultName, name)
+}
+
+func TestFromConfig(t *testing.T) {
+	viper.Set("sources.default", "supplier")
+	viper.Set("sources.merge", domain.MergeLowestPrice)
+	viper.Set("sources.registry", []map[string]interface{}{
+		{"name": "web-app", "url": "http://web-app:8085/products/", "priority": 10},
+		{"name": "supplier", "priority": 5, "delimiter": ",", "skip_header": true, "rules": []map[string]interface{}{}},
+	})
+	viper.Set("sources.rules", []map[string]interface{}{{"rule": "positive_price"}})
+	defer func() {
+		viper.Set("sources.default", nil)
+		viper.Set("sources.merge", nil)
+		viper.Set("sources.registry", nil)
+		viper.Set("sources.rules", nil)
+	}()
+
+	registry, err := FromConfig()
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, []domain.Source{
+		{Name: "web-app", Url: "http://web-app:8085/products/", Format: domain.DefaultCSVFormat, Priority: 10},
+		{Name: "supplier", Format: domain.CSVFormat{Delimiter: ',', SkipHeader: true}, Priority: 5},
+	}, registry.List())
+	assert.Equal(t, "supplier", registry.Default())
+	assert.Equal(t, domain.MergeLowestPrice, registry.MergeRule().Strategy)
+	assert.Len(t, registry.Rules("web-app"), 1, "default rules")
+	assert.Empty(t, registry.Rules("supplier"), "rules turned off")
+
+	viper.Set("sources.registry", []map[string]interface{}{{"name": "bad", "delimiter": ";;"}})
+	_, err = FromConfig()
+	assert.Error(t, err)
+
+	viper.Set("sources.registry", []map[string]interface{}{{"name": "bad", "rules": []map[string]interface{}{{"rule": "nope"}}}})
+	_, err = FromConfig()
+	assert.Error(t, err)
+}
@@ -0,0 +1,109 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"gRPC-server/pkg/parseCSV/grpcPb"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel/propagation"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+type fetchServer struct {
+	grpcPb.UnimplementedSortServiceServer
+	httpClient *http.Client
+	url        string
+}
+
+func (f *fetchServer) Fetch(ctx context.Context, req *grpcPb.FetchRequest) (*grpcPb.FethResponce, error) {
+	httpReq, _ := http.NewRequestWithContext(ctx, http.MethodGet, f.url, nil)
+	resp, err := f.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+	return &grpcPb.FethResponce{Status: "Success"}, nil
+}
+
+type exportedSpan struct {
+	Name        string
+	SpanContext struct {
+		TraceID string
+	}
+	Parent struct {
+		TraceID string
+	}
+}
+
+// TestTracePropagation checks that the client span, the server span and the outbound HTTP span
+// all end up in one trace.
+func TestTracePropagation(t *testing.T) {
+	var buf bytes.Buffer
+	provider, err := NewStdoutProvider(&buf)
+	assert.NoError(t, err)
+	propagator := propagation.TraceContext{}
+
+	source := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.NotEmpty(t, r.Header.Get("traceparent"))
+	}))
+	defer source.Close()
+
+	listener := bufconn.Listen(1024 * 1024)
+	srv := grpc.NewServer(grpc.StatsHandler(otelgrpc.NewServerHandler(
+		otelgrpc.WithTracerProvider(provider),
+		otelgrpc.WithPropagators(propagator),
+	)))
+	grpcPb.RegisterSortServiceServer(srv, &fetchServer{
+		httpClient: &http.Client{Transport: otelhttp.NewTransport(http.DefaultTransport,
+			otelhttp.WithTracerProvider(provider),
+			otelhttp.WithPropagators(propagator),
+		)},
+		url: source.URL,
+	})
+	go srv.Serve(listener)
+	defer srv.Stop()
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithStatsHandler(otelgrpc.NewClientHandler(
+			otelgrpc.WithTracerProvider(provider),
+			otelgrpc.WithPropagators(propagator),
+		)),
+	)
+	assert.NoError(t, err)
+	defer conn.Close()
+
+	_, err = grpcPb.NewSortServiceClient(conn).Fetch(context.Background(), &grpcPb.FetchRequest{Url: source.URL})
+	assert.NoError(t, err)
+	assert.NoError(t, provider.Shutdown(context.Background()))
+
+	var spans []exportedSpan
+	decoder := json.NewDecoder(&buf)
+	for decoder.More() {
+		var span exportedSpan
+		if err := decoder.Decode(&span); err != nil {
+			break
+		}
+		spans = append(spans, span)
+	}
+
+	if assert.Len(t, spans, 3) {
+		traceID := spans[0].SpanContext.TraceID
+		for _, span := range spans {
+			assert.Equal(t, traceID, span.SpanContext.TraceID, span.Name)
+		}
+	}
+}
@@ -0,0 +1,202 @@
+// Package client is a Go SDK for SortService: it sets up the connection with keepalive,
+// retries and default deadlines and converts products into typed values.
+package client
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"gRPC-server/pkg/parseCSV/grpcPb"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/keepalive"
+)
+
+const (
+	DefaultTimeout  = 10 * time.Second
+	DefaultPageSize = 100
+)
+
+// serviceConfig retries reads on UNAVAILABLE with exponential backoff. Fetch, Delete, Restore
+// and Purge change data, so they are never retried automatically.
+const serviceConfig = `{
+	"methodConfig": [{
+		"name": [
+			{"service": "grpcPb.SortService", "method": "List"},
+			{"service": "grpcPb.SortService", "method": "GetProduct"},
+			{"service": "grpcPb.SortService", "method": "BatchGetProducts"},
+			{"service": "grpcPb.SortService", "method": "GetStats"}
+		],
+		"retryPolicy": {
+			"maxAttempts": 4,
+			"initialBackoff": "0.1s",
+			"maxBackoff": "2s",
+			"backoffMultiplier": 2,
+			"retryableStatusCodes": ["UNAVAILABLE"]
+		}
+	}]
+}`
+
+type Options struct {
+	// Addr is the gRPC target, e.g. "localhost:8889".
+	Addr string
+	// TLS enables TLS, nil dials in plaintext. See pkg/tlsconfig.ClientConfig.
+	TLS *tls.Config
+	// APIKey and Token (a JWT) are sent with every call.
+	APIKey string
+	Token  string
+	// Timeout is the deadline of unary calls whose context has none, DefaultTimeout when zero.
+	Timeout time.Duration
+	// Keepalive defaults to a ping every 30s on connections with active calls.
+	Keepalive *keepalive.ClientParameters
+	// DialOptions are appended last and override the ones above.
+	DialOptions []grpc.DialOption
+}
+
+type Client struct {
+	conn *grpc.ClientConn
+	rpc  grpcPb.SortServiceClient
+}
+
+func New(opts Options) (*Client, error) {
+	if opts.Addr == "" {
+		return nil, errors.New("client: addr is required")
+	}
+	timeout := opts.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	params := keepalive.ClientParameters{Time: 30 * time.Second, Timeout: 10 * time.Second}
+	if opts.Keepalive != nil {
+		params = *opts.Keepalive
+	}
+
+	creds := insecure.NewCredentials()
+	if opts.TLS != nil {
+		creds = credentials.NewTLS(opts.TLS)
+	}
+
+	dialOpts := []grpc.DialOption{
+		grpc.WithTransportCredentials(creds),
+		grpc.WithKeepaliveParams(params),
+		grpc.WithDefaultServiceConfig(serviceConfig),
+		grpc.WithChainUnaryInterceptor(deadlineInterceptor(timeout)),
+	}
+	if opts.APIKey != "" || opts.Token != "" {
+		dialOpts = append(dialOpts, grpc.WithPerRPCCredentials(callCredentials{
+			apiKey: opts.APIKey,
+			token:  opts.Token,
+		}))
+	}
+	dialOpts = append(dialOpts, opts.DialOptions...)
+
+	conn, err := grpc.NewClient(opts.Addr, dialOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return &Client{conn: conn, rpc: grpcPb.NewSortServiceClient(conn)}, nil
+}
+
+// deadlineInterceptor gives unary calls without a deadline the default one.
+func deadlineInterceptor(timeout time.Duration) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		if _, ok := ctx.Deadline(); !ok {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, timeout)
+			defer cancel()
+		}
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+}
+
+type callCredentials struct {
+	apiKey string
+	token  string
+}
+
+func (c callCredentials) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	md := map[string]string{}
+	if c.apiKey != "" {
+		md["x-api-key"] = c.apiKey
+	}
+	if c.token != "" {
+		md["authorization"] = "Bearer " + c.token
+	}
+	return md, nil
+}
+
+// RequireTransportSecurity is false so plaintext works in development,
+// credentials then travel unencrypted.
+func (c callCredentials) RequireTransportSecurity() bool {
+	return false
+}
+
+// Raw returns the generated client for calls the SDK doesn't wrap, e.g. Watch.
+func (c *Client) Raw() grpcPb.SortServiceClient {
+	return c.rpc
+}
+
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
+
+// Fetch asks the server to import the CSV at url and returns the import status.
+func (c *Client) Fetch(ctx context.Context, url string) (string, error) {
+	return c.FetchSource(ctx, "", url)
+}
+
+// FetchSource imports into a source of the server's registry, an empty url means the source's own.
+func (c *Client) FetchSource(ctx context.Context, source, url string) (string, error) {
+	resp, err := c.rpc.Fetch(ctx, &grpcPb.FetchRequest{Url: url, Source: source})
+	if err != nil {
+		return "", err
+	}
+	return resp.GetStatus(), nil
+}
+
+func (c *Client) GetProduct(ctx context.Context, id int64) (Product, error) {
+	resp, err := c.rpc.GetProduct(ctx, &grpcPb.GetProductRequest{Id: id})
+	if err != nil {
+		return Product{}, err
+	}
+	return ToProduct(resp.GetProduct())
+}
+
+// Product is grpcPb.Product with the price parsed.
+type Product struct {
+	Source    string
+	Id        int64
+	Name      string
+	Price     decimal.Decimal
+	DeletedAt *time.Time
+}
+
+func ToProduct(p *grpcPb.Product) (Product, error) {
+	price, err := decimal.NewFromString(p.GetPrice())
+	if err != nil {
+		return Product{}, fmt.Errorf("product %d: price %q: %w", p.GetId(), p.GetPrice(), err)
+	}
+	product := Product{Source: p.GetSource(), Id: p.GetId(), Name: p.GetName(), Price: price}
+	if p.GetDeletedAt() != nil {
+		deletedAt := p.GetDeletedAt().AsTime()
+		product.DeletedAt = &deletedAt
+	}
+	return product, nil
+}
+
+func toProducts(in []*grpcPb.Product) ([]Product, error) {
+	out := make([]Product, 0, len(in))
+	for _, p := range in {
+		product, err := ToProduct(p)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, product)
+	}
+	return out, nil
+}
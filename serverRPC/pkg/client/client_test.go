@@ -0,0 +1,198 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"gRPC-server/pkg/parseCSV/grpcPb"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+type sortServer struct {
+	grpcPb.UnimplementedSortServiceServer
+
+	mu           sync.Mutex
+	products     []*grpcPb.Product
+	listCalls    int
+	failListOnce int
+	fetchCalls   int
+	deadline     time.Duration
+	apiKey       string
+}
+
+func (s *sortServer) List(ctx context.Context, req *grpcPb.ListRequest) (*grpcPb.ListResponce, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.listCalls++
+	if s.failListOnce > 0 {
+		s.failListOnce--
+		return nil, status.Error(codes.Unavailable, "storage unavailable")
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		s.deadline = time.Until(deadline)
+	}
+	if md, ok := metadata.FromIncomingContext(ctx); ok && len(md.Get("x-api-key")) > 0 {
+		s.apiKey = md.Get("x-api-key")[0]
+	}
+
+	start := min(int(req.GetPagingOffset()), len(s.products))
+	end := len(s.products)
+	if req.GetPagingLimit() > 0 {
+		end = min(start+int(req.GetPagingLimit()), end)
+	}
+	return &grpcPb.ListResponce{Product: s.products[start:end]}, nil
+}
+
+func (s *sortServer) Fetch(ctx context.Context, req *grpcPb.FetchRequest) (*grpcPb.FethResponce, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.fetchCalls++
+	return nil, status.Error(codes.Unavailable, "source unavailable")
+}
+
+func (s *sortServer) GetProduct(ctx context.Context, req *grpcPb.GetProductRequest) (*grpcPb.GetProductResponce, error) {
+	return &grpcPb.GetProductResponce{Product: &grpcPb.Product{
+		Id: req.GetId(), Name: "name", Price: "10.50", DeletedAt: timestamppb.New(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)),
+	}}, nil
+}
+
+func newTestClient(t *testing.T, srv *sortServer, opts Options) *Client {
+	t.Helper()
+	listener := bufconn.Listen(1 << 20)
+	server := grpc.NewServer()
+	grpcPb.RegisterSortServiceServer(server, srv)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	opts.Addr = "passthrough:///bufnet"
+	opts.DialOptions = append(opts.DialOptions, grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+		return listener.DialContext(ctx)
+	}))
+	c, err := New(opts)
+	require.NoError(t, err)
+	t.Cleanup(func() { c.Close() })
+	return c
+}
+
+func products(n int) []*grpcPb.Product {
+	out := make([]*grpcPb.Product, n)
+	for i := range out {
+		out[i] = &grpcPb.Product{Id: int64(i + 1), Name: fmt.Sprintf("Name%d", i+1), Price: fmt.Sprintf("%d.25", i+1)}
+	}
+	return out
+}
+
+func TestAll(t *testing.T) {
+	testTables := []struct {
+		name      string
+		products  int
+		opts      ListOptions
+		wantIds   int
+		wantCalls int
+	}{
+		{name: "Several pages", products: 7, opts: ListOptions{Limit: 3}, wantIds: 7, wantCalls: 3},
+		{name: "Exact pages", products: 6, opts: ListOptions{Limit: 3}, wantIds: 6, wantCalls: 3},
+		{name: "Offset", products: 7, opts: ListOptions{Limit: 3, Offset: 5}, wantIds: 2, wantCalls: 1},
+		{name: "Default page size", products: 5, wantIds: 5, wantCalls: 1},
+		{name: "Empty", products: 0, opts: ListOptions{Limit: 3}, wantIds: 0, wantCalls: 1},
+	}
+
+	for _, table := range testTables {
+		t.Run(table.name, func(t *testing.T) {
+			srv := &sortServer{products: products(table.products)}
+			c := newTestClient(t, srv, Options{})
+
+			var got []Product
+			for product, err := range c.All(context.Background(), table.opts) {
+				require.NoError(t, err)
+				got = append(got, product)
+			}
+
+			assert.Len(t, got, table.wantIds)
+			assert.Equal(t, table.wantCalls, srv.listCalls)
+			for i, product := range got {
+				want := int64(table.opts.Offset + i + 1)
+				assert.Equal(t, want, product.Id)
+				assert.True(t, decimal.NewFromInt(want).Add(decimal.RequireFromString("0.25")).Equal(product.Price))
+			}
+		})
+	}
+}
+
+func TestAllStopsEarly(t *testing.T) {
+	srv := &sortServer{products: products(10)}
+	c := newTestClient(t, srv, Options{})
+
+	var got int
+	for _, err := range c.All(context.Background(), ListOptions{Limit: 2}) {
+		require.NoError(t, err)
+		got++
+		if got == 3 {
+			break
+		}
+	}
+	assert.Equal(t, 3, got)
+	assert.Equal(t, 2, srv.listCalls)
+}
+
+func TestAllBadPrice(t *testing.T) {
+	srv := &sortServer{products: []*grpcPb.Product{{Id: 1, Name: "name", Price: "fifty"}}}
+	c := newTestClient(t, srv, Options{})
+
+	for _, err := range c.All(context.Background(), ListOptions{}) {
+		assert.ErrorContains(t, err, `price "fifty"`)
+	}
+}
+
+func TestRetry(t *testing.T) {
+	srv := &sortServer{products: products(2), failListOnce: 2}
+	c := newTestClient(t, srv, Options{})
+
+	got, err := c.List(context.Background(), ListOptions{})
+	assert.NoError(t, err)
+	assert.Len(t, got, 2)
+	assert.Equal(t, 3, srv.listCalls)
+
+	// Fetch changes data and isn't retried
+	_, err = c.Fetch(context.Background(), "http://web-app:8085/products/")
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+	assert.Equal(t, 1, srv.fetchCalls)
+}
+
+func TestDefaults(t *testing.T) {
+	srv := &sortServer{products: products(1)}
+	c := newTestClient(t, srv, Options{Timeout: 3 * time.Second, APIKey: "secret"})
+
+	_, err := c.List(context.Background(), ListOptions{})
+	require.NoError(t, err)
+	assert.InDelta(t, 3*time.Second, srv.deadline, float64(time.Second))
+	assert.Equal(t, "secret", srv.apiKey)
+
+	// an explicit deadline wins
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	_, err = c.List(ctx, ListOptions{})
+	require.NoError(t, err)
+	assert.Greater(t, srv.deadline, 30*time.Second)
+
+	product, err := c.GetProduct(context.Background(), 5)
+	require.NoError(t, err)
+	assert.Equal(t, "10.5", product.Price.String())
+	assert.Equal(t, time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), *product.DeletedAt)
+
+	_, err = New(Options{})
+	assert.Error(t, err)
+}
@@ -0,0 +1,76 @@
+package client
+
+import (
+	"context"
+	"gRPC-server/pkg/parseCSV/grpcPb"
+	"iter"
+)
+
+type SortField = grpcPb.ListRequest_SortParameters
+
+const (
+	SortByID    = grpcPb.ListRequest_id
+	SortByName  = grpcPb.ListRequest_name
+	SortByPrice = grpcPb.ListRequest_price
+)
+
+type ListOptions struct {
+	SortBy         SortField
+	Descending     bool
+	Offset         int
+	Limit          int
+	IncludeDeleted bool
+	// Source lists one source, empty means the catalog merged across sources
+	Source string
+}
+
+func (o ListOptions) request() *grpcPb.ListRequest {
+	sortAsc := int32(1)
+	if o.Descending {
+		sortAsc = -1
+	}
+	return &grpcPb.ListRequest{
+		SortField:      o.SortBy,
+		SortAsc:        sortAsc,
+		PagingOffset:   int32(o.Offset),
+		PagingLimit:    int32(o.Limit),
+		IncludeDeleted: o.IncludeDeleted,
+		Source:         o.Source,
+	}
+}
+
+// List returns one page; Limit 0 means everything starting at Offset.
+func (c *Client) List(ctx context.Context, opts ListOptions) ([]Product, error) {
+	resp, err := c.rpc.List(ctx, opts.request())
+	if err != nil {
+		return nil, err
+	}
+	return toProducts(resp.GetProduct())
+}
+
+// All iterates over every product starting at opts.Offset, requesting pages of opts.Limit
+// (DefaultPageSize when zero). Each page is a separate call with its own deadline. The
+// iteration stops at the first error, which is yielded with a zero Product.
+func (c *Client) All(ctx context.Context, opts ListOptions) iter.Seq2[Product, error] {
+	if opts.Limit <= 0 {
+		opts.Limit = DefaultPageSize
+	}
+	return func(yield func(Product, error) bool) {
+		for {
+			page, err := c.List(ctx, opts)
+			if err != nil {
+				yield(Product{}, err)
+				return
+			}
+			for _, product := range page {
+				if !yield(product, nil) {
+					return
+				}
+			}
+			if len(page) < opts.Limit {
+				return
+			}
+			opts.Offset += len(page)
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package logger
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"os"
@@ -45,6 +46,32 @@ func GetLogger() *Logger {
 	return &Logger{e}
 }
 
+type fieldsKey struct{}
+
+// ContextWithField attaches a field that every FromContext logger of this ctx will carry.
+func ContextWithField(ctx context.Context, key string, value interface{}) context.Context {
+	fields := logrus.Fields{}
+	if parent, ok := ctx.Value(fieldsKey{}).(logrus.Fields); ok {
+		for k, v := range parent {
+			fields[k] = v
+		}
+	}
+	fields[key] = value
+	return context.WithValue(ctx, fieldsKey{}, fields)
+}
+
+// FromContext returns the logger extended with the fields stored in ctx, e.g. the request id of the current call.
+func (l *Logger) FromContext(ctx context.Context) *Logger {
+	if ctx == nil {
+		return l
+	}
+	fields, ok := ctx.Value(fieldsKey{}).(logrus.Fields)
+	if !ok {
+		return l
+	}
+	return &Logger{l.WithFields(fields)}
+}
+
 func init() {
 	l := logrus.New()
 	l.SetReportCaller(true)
@@ -36,6 +36,166 @@ func (m *MockSortServiceClient) EXPECT() *MockSortServiceClientMockRecorder {
 	return m.recorder
 }
 
+// BatchGetProducts mocks base method.
+func (m *MockSortServiceClient) BatchGetProducts(ctx context.Context, in *grpcPb.BatchGetProductsRequest, opts ...grpc.CallOption) (*grpcPb.BatchGetProductsResponce, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx, in}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "BatchGetProducts", varargs...)
+	ret0, _ := ret[0].(*grpcPb.BatchGetProductsResponce)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// BatchGetProducts indicates an expected call of BatchGetProducts.
+func (mr *MockSortServiceClientMockRecorder) BatchGetProducts(ctx, in interface{}, opts ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx, in}, opts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BatchGetProducts", reflect.TypeOf((*MockSortServiceClient)(nil).BatchGetProducts), varargs...)
+}
+
+// CreateAlertRule mocks base method.
+func (m *MockSortServiceClient) CreateAlertRule(ctx context.Context, in *grpcPb.CreateAlertRuleRequest, opts ...grpc.CallOption) (*grpcPb.CreateAlertRuleResponce, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx, in}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "CreateAlertRule", varargs...)
+	ret0, _ := ret[0].(*grpcPb.CreateAlertRuleResponce)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateAlertRule indicates an expected call of CreateAlertRule.
+func (mr *MockSortServiceClientMockRecorder) CreateAlertRule(ctx, in interface{}, opts ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx, in}, opts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateAlertRule", reflect.TypeOf((*MockSortServiceClient)(nil).CreateAlertRule), varargs...)
+}
+
+// CreateSchedule mocks base method.
+func (m *MockSortServiceClient) CreateSchedule(ctx context.Context, in *grpcPb.CreateScheduleRequest, opts ...grpc.CallOption) (*grpcPb.CreateScheduleResponce, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx, in}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "CreateSchedule", varargs...)
+	ret0, _ := ret[0].(*grpcPb.CreateScheduleResponce)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateSchedule indicates an expected call of CreateSchedule.
+func (mr *MockSortServiceClientMockRecorder) CreateSchedule(ctx, in interface{}, opts ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx, in}, opts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateSchedule", reflect.TypeOf((*MockSortServiceClient)(nil).CreateSchedule), varargs...)
+}
+
+// DeleteAlertRule mocks base method.
+func (m *MockSortServiceClient) DeleteAlertRule(ctx context.Context, in *grpcPb.DeleteAlertRuleRequest, opts ...grpc.CallOption) (*grpcPb.DeleteAlertRuleResponce, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx, in}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "DeleteAlertRule", varargs...)
+	ret0, _ := ret[0].(*grpcPb.DeleteAlertRuleResponce)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteAlertRule indicates an expected call of DeleteAlertRule.
+func (mr *MockSortServiceClientMockRecorder) DeleteAlertRule(ctx, in interface{}, opts ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx, in}, opts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteAlertRule", reflect.TypeOf((*MockSortServiceClient)(nil).DeleteAlertRule), varargs...)
+}
+
+// DeleteProduct mocks base method.
+func (m *MockSortServiceClient) DeleteProduct(ctx context.Context, in *grpcPb.DeleteProductRequest, opts ...grpc.CallOption) (*grpcPb.DeleteProductResponce, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx, in}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "DeleteProduct", varargs...)
+	ret0, _ := ret[0].(*grpcPb.DeleteProductResponce)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteProduct indicates an expected call of DeleteProduct.
+func (mr *MockSortServiceClientMockRecorder) DeleteProduct(ctx, in interface{}, opts ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx, in}, opts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteProduct", reflect.TypeOf((*MockSortServiceClient)(nil).DeleteProduct), varargs...)
+}
+
+// DeleteSchedule mocks base method.
+func (m *MockSortServiceClient) DeleteSchedule(ctx context.Context, in *grpcPb.DeleteScheduleRequest, opts ...grpc.CallOption) (*grpcPb.DeleteScheduleResponce, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx, in}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "DeleteSchedule", varargs...)
+	ret0, _ := ret[0].(*grpcPb.DeleteScheduleResponce)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteSchedule indicates an expected call of DeleteSchedule.
+func (mr *MockSortServiceClientMockRecorder) DeleteSchedule(ctx, in interface{}, opts ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx, in}, opts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteSchedule", reflect.TypeOf((*MockSortServiceClient)(nil).DeleteSchedule), varargs...)
+}
+
+// DiffFetch mocks base method.
+func (m *MockSortServiceClient) DiffFetch(ctx context.Context, in *grpcPb.DiffFetchRequest, opts ...grpc.CallOption) (*grpcPb.DiffFetchResponce, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx, in}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "DiffFetch", varargs...)
+	ret0, _ := ret[0].(*grpcPb.DiffFetchResponce)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DiffFetch indicates an expected call of DiffFetch.
+func (mr *MockSortServiceClientMockRecorder) DiffFetch(ctx, in interface{}, opts ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx, in}, opts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DiffFetch", reflect.TypeOf((*MockSortServiceClient)(nil).DiffFetch), varargs...)
+}
+
+// Export mocks base method.
+func (m *MockSortServiceClient) Export(ctx context.Context, in *grpcPb.ExportRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[grpcPb.ExportChunk], error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx, in}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "Export", varargs...)
+	ret0, _ := ret[0].(grpc.ServerStreamingClient[grpcPb.ExportChunk])
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Export indicates an expected call of Export.
+func (mr *MockSortServiceClientMockRecorder) Export(ctx, in interface{}, opts ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx, in}, opts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Export", reflect.TypeOf((*MockSortServiceClient)(nil).Export), varargs...)
+}
+
 // Fetch mocks base method.
 func (m *MockSortServiceClient) Fetch(ctx context.Context, in *grpcPb.FetchRequest, opts ...grpc.CallOption) (*grpcPb.FethResponce, error) {
 	m.ctrl.T.Helper()
@@ -56,6 +216,46 @@ func (mr *MockSortServiceClientMockRecorder) Fetch(ctx, in interface{}, opts ...
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Fetch", reflect.TypeOf((*MockSortServiceClient)(nil).Fetch), varargs...)
 }
 
+// GetProduct mocks base method.
+func (m *MockSortServiceClient) GetProduct(ctx context.Context, in *grpcPb.GetProductRequest, opts ...grpc.CallOption) (*grpcPb.GetProductResponce, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx, in}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "GetProduct", varargs...)
+	ret0, _ := ret[0].(*grpcPb.GetProductResponce)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetProduct indicates an expected call of GetProduct.
+func (mr *MockSortServiceClientMockRecorder) GetProduct(ctx, in interface{}, opts ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx, in}, opts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetProduct", reflect.TypeOf((*MockSortServiceClient)(nil).GetProduct), varargs...)
+}
+
+// GetStats mocks base method.
+func (m *MockSortServiceClient) GetStats(ctx context.Context, in *grpcPb.GetStatsRequest, opts ...grpc.CallOption) (*grpcPb.GetStatsResponce, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx, in}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "GetStats", varargs...)
+	ret0, _ := ret[0].(*grpcPb.GetStatsResponce)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetStats indicates an expected call of GetStats.
+func (mr *MockSortServiceClientMockRecorder) GetStats(ctx, in interface{}, opts ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx, in}, opts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetStats", reflect.TypeOf((*MockSortServiceClient)(nil).GetStats), varargs...)
+}
+
 // List mocks base method.
 func (m *MockSortServiceClient) List(ctx context.Context, in *grpcPb.ListRequest, opts ...grpc.CallOption) (*grpcPb.ListResponce, error) {
 	m.ctrl.T.Helper()
@@ -76,6 +276,166 @@ func (mr *MockSortServiceClientMockRecorder) List(ctx, in interface{}, opts ...i
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "List", reflect.TypeOf((*MockSortServiceClient)(nil).List), varargs...)
 }
 
+// ListAlertRules mocks base method.
+func (m *MockSortServiceClient) ListAlertRules(ctx context.Context, in *grpcPb.ListAlertRulesRequest, opts ...grpc.CallOption) (*grpcPb.ListAlertRulesResponce, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx, in}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "ListAlertRules", varargs...)
+	ret0, _ := ret[0].(*grpcPb.ListAlertRulesResponce)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListAlertRules indicates an expected call of ListAlertRules.
+func (mr *MockSortServiceClientMockRecorder) ListAlertRules(ctx, in interface{}, opts ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx, in}, opts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListAlertRules", reflect.TypeOf((*MockSortServiceClient)(nil).ListAlertRules), varargs...)
+}
+
+// ListDeadLetters mocks base method.
+func (m *MockSortServiceClient) ListDeadLetters(ctx context.Context, in *grpcPb.ListDeadLettersRequest, opts ...grpc.CallOption) (*grpcPb.ListDeadLettersResponce, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx, in}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "ListDeadLetters", varargs...)
+	ret0, _ := ret[0].(*grpcPb.ListDeadLettersResponce)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListDeadLetters indicates an expected call of ListDeadLetters.
+func (mr *MockSortServiceClientMockRecorder) ListDeadLetters(ctx, in interface{}, opts ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx, in}, opts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListDeadLetters", reflect.TypeOf((*MockSortServiceClient)(nil).ListDeadLetters), varargs...)
+}
+
+// ListSchedules mocks base method.
+func (m *MockSortServiceClient) ListSchedules(ctx context.Context, in *grpcPb.ListSchedulesRequest, opts ...grpc.CallOption) (*grpcPb.ListSchedulesResponce, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx, in}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "ListSchedules", varargs...)
+	ret0, _ := ret[0].(*grpcPb.ListSchedulesResponce)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListSchedules indicates an expected call of ListSchedules.
+func (mr *MockSortServiceClientMockRecorder) ListSchedules(ctx, in interface{}, opts ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx, in}, opts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListSchedules", reflect.TypeOf((*MockSortServiceClient)(nil).ListSchedules), varargs...)
+}
+
+// ListSources mocks base method.
+func (m *MockSortServiceClient) ListSources(ctx context.Context, in *grpcPb.ListSourcesRequest, opts ...grpc.CallOption) (*grpcPb.ListSourcesResponce, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx, in}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "ListSources", varargs...)
+	ret0, _ := ret[0].(*grpcPb.ListSourcesResponce)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListSources indicates an expected call of ListSources.
+func (mr *MockSortServiceClientMockRecorder) ListSources(ctx, in interface{}, opts ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx, in}, opts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListSources", reflect.TypeOf((*MockSortServiceClient)(nil).ListSources), varargs...)
+}
+
+// PauseSchedule mocks base method.
+func (m *MockSortServiceClient) PauseSchedule(ctx context.Context, in *grpcPb.PauseScheduleRequest, opts ...grpc.CallOption) (*grpcPb.PauseScheduleResponce, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx, in}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "PauseSchedule", varargs...)
+	ret0, _ := ret[0].(*grpcPb.PauseScheduleResponce)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PauseSchedule indicates an expected call of PauseSchedule.
+func (mr *MockSortServiceClientMockRecorder) PauseSchedule(ctx, in interface{}, opts ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx, in}, opts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PauseSchedule", reflect.TypeOf((*MockSortServiceClient)(nil).PauseSchedule), varargs...)
+}
+
+// PurgeDeleted mocks base method.
+func (m *MockSortServiceClient) PurgeDeleted(ctx context.Context, in *grpcPb.PurgeDeletedRequest, opts ...grpc.CallOption) (*grpcPb.PurgeDeletedResponce, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx, in}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "PurgeDeleted", varargs...)
+	ret0, _ := ret[0].(*grpcPb.PurgeDeletedResponce)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PurgeDeleted indicates an expected call of PurgeDeleted.
+func (mr *MockSortServiceClientMockRecorder) PurgeDeleted(ctx, in interface{}, opts ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx, in}, opts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PurgeDeleted", reflect.TypeOf((*MockSortServiceClient)(nil).PurgeDeleted), varargs...)
+}
+
+// RestoreProduct mocks base method.
+func (m *MockSortServiceClient) RestoreProduct(ctx context.Context, in *grpcPb.RestoreProductRequest, opts ...grpc.CallOption) (*grpcPb.RestoreProductResponce, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx, in}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "RestoreProduct", varargs...)
+	ret0, _ := ret[0].(*grpcPb.RestoreProductResponce)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RestoreProduct indicates an expected call of RestoreProduct.
+func (mr *MockSortServiceClientMockRecorder) RestoreProduct(ctx, in interface{}, opts ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx, in}, opts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RestoreProduct", reflect.TypeOf((*MockSortServiceClient)(nil).RestoreProduct), varargs...)
+}
+
+// Watch mocks base method.
+func (m *MockSortServiceClient) Watch(ctx context.Context, in *grpcPb.WatchRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[grpcPb.WatchEvent], error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx, in}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "Watch", varargs...)
+	ret0, _ := ret[0].(grpc.ServerStreamingClient[grpcPb.WatchEvent])
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Watch indicates an expected call of Watch.
+func (mr *MockSortServiceClientMockRecorder) Watch(ctx, in interface{}, opts ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx, in}, opts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Watch", reflect.TypeOf((*MockSortServiceClient)(nil).Watch), varargs...)
+}
+
 // MockSortServiceServer is a mock of SortServiceServer interface.
 type MockSortServiceServer struct {
 	ctrl     *gomock.Controller
@@ -99,6 +459,125 @@ func (m *MockSortServiceServer) EXPECT() *MockSortServiceServerMockRecorder {
 	return m.recorder
 }
 
+// BatchGetProducts mocks base method.
+func (m *MockSortServiceServer) BatchGetProducts(arg0 context.Context, arg1 *grpcPb.BatchGetProductsRequest) (*grpcPb.BatchGetProductsResponce, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "BatchGetProducts", arg0, arg1)
+	ret0, _ := ret[0].(*grpcPb.BatchGetProductsResponce)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// BatchGetProducts indicates an expected call of BatchGetProducts.
+func (mr *MockSortServiceServerMockRecorder) BatchGetProducts(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BatchGetProducts", reflect.TypeOf((*MockSortServiceServer)(nil).BatchGetProducts), arg0, arg1)
+}
+
+// CreateAlertRule mocks base method.
+func (m *MockSortServiceServer) CreateAlertRule(arg0 context.Context, arg1 *grpcPb.CreateAlertRuleRequest) (*grpcPb.CreateAlertRuleResponce, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateAlertRule", arg0, arg1)
+	ret0, _ := ret[0].(*grpcPb.CreateAlertRuleResponce)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateAlertRule indicates an expected call of CreateAlertRule.
+func (mr *MockSortServiceServerMockRecorder) CreateAlertRule(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateAlertRule", reflect.TypeOf((*MockSortServiceServer)(nil).CreateAlertRule), arg0, arg1)
+}
+
+// CreateSchedule mocks base method.
+func (m *MockSortServiceServer) CreateSchedule(arg0 context.Context, arg1 *grpcPb.CreateScheduleRequest) (*grpcPb.CreateScheduleResponce, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateSchedule", arg0, arg1)
+	ret0, _ := ret[0].(*grpcPb.CreateScheduleResponce)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateSchedule indicates an expected call of CreateSchedule.
+func (mr *MockSortServiceServerMockRecorder) CreateSchedule(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateSchedule", reflect.TypeOf((*MockSortServiceServer)(nil).CreateSchedule), arg0, arg1)
+}
+
+// DeleteAlertRule mocks base method.
+func (m *MockSortServiceServer) DeleteAlertRule(arg0 context.Context, arg1 *grpcPb.DeleteAlertRuleRequest) (*grpcPb.DeleteAlertRuleResponce, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteAlertRule", arg0, arg1)
+	ret0, _ := ret[0].(*grpcPb.DeleteAlertRuleResponce)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteAlertRule indicates an expected call of DeleteAlertRule.
+func (mr *MockSortServiceServerMockRecorder) DeleteAlertRule(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteAlertRule", reflect.TypeOf((*MockSortServiceServer)(nil).DeleteAlertRule), arg0, arg1)
+}
+
+// DeleteProduct mocks base method.
+func (m *MockSortServiceServer) DeleteProduct(arg0 context.Context, arg1 *grpcPb.DeleteProductRequest) (*grpcPb.DeleteProductResponce, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteProduct", arg0, arg1)
+	ret0, _ := ret[0].(*grpcPb.DeleteProductResponce)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteProduct indicates an expected call of DeleteProduct.
+func (mr *MockSortServiceServerMockRecorder) DeleteProduct(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteProduct", reflect.TypeOf((*MockSortServiceServer)(nil).DeleteProduct), arg0, arg1)
+}
+
+// DeleteSchedule mocks base method.
+func (m *MockSortServiceServer) DeleteSchedule(arg0 context.Context, arg1 *grpcPb.DeleteScheduleRequest) (*grpcPb.DeleteScheduleResponce, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteSchedule", arg0, arg1)
+	ret0, _ := ret[0].(*grpcPb.DeleteScheduleResponce)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteSchedule indicates an expected call of DeleteSchedule.
+func (mr *MockSortServiceServerMockRecorder) DeleteSchedule(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteSchedule", reflect.TypeOf((*MockSortServiceServer)(nil).DeleteSchedule), arg0, arg1)
+}
+
+// DiffFetch mocks base method.
+func (m *MockSortServiceServer) DiffFetch(arg0 context.Context, arg1 *grpcPb.DiffFetchRequest) (*grpcPb.DiffFetchResponce, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DiffFetch", arg0, arg1)
+	ret0, _ := ret[0].(*grpcPb.DiffFetchResponce)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DiffFetch indicates an expected call of DiffFetch.
+func (mr *MockSortServiceServerMockRecorder) DiffFetch(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DiffFetch", reflect.TypeOf((*MockSortServiceServer)(nil).DiffFetch), arg0, arg1)
+}
+
+// Export mocks base method.
+func (m *MockSortServiceServer) Export(arg0 *grpcPb.ExportRequest, arg1 grpc.ServerStreamingServer[grpcPb.ExportChunk]) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Export", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Export indicates an expected call of Export.
+func (mr *MockSortServiceServerMockRecorder) Export(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Export", reflect.TypeOf((*MockSortServiceServer)(nil).Export), arg0, arg1)
+}
+
 // Fetch mocks base method.
 func (m *MockSortServiceServer) Fetch(arg0 context.Context, arg1 *grpcPb.FetchRequest) (*grpcPb.FethResponce, error) {
 	m.ctrl.T.Helper()
@@ -114,6 +593,36 @@ func (mr *MockSortServiceServerMockRecorder) Fetch(arg0, arg1 interface{}) *gomo
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Fetch", reflect.TypeOf((*MockSortServiceServer)(nil).Fetch), arg0, arg1)
 }
 
+// GetProduct mocks base method.
+func (m *MockSortServiceServer) GetProduct(arg0 context.Context, arg1 *grpcPb.GetProductRequest) (*grpcPb.GetProductResponce, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetProduct", arg0, arg1)
+	ret0, _ := ret[0].(*grpcPb.GetProductResponce)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetProduct indicates an expected call of GetProduct.
+func (mr *MockSortServiceServerMockRecorder) GetProduct(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetProduct", reflect.TypeOf((*MockSortServiceServer)(nil).GetProduct), arg0, arg1)
+}
+
+// GetStats mocks base method.
+func (m *MockSortServiceServer) GetStats(arg0 context.Context, arg1 *grpcPb.GetStatsRequest) (*grpcPb.GetStatsResponce, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetStats", arg0, arg1)
+	ret0, _ := ret[0].(*grpcPb.GetStatsResponce)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetStats indicates an expected call of GetStats.
+func (mr *MockSortServiceServerMockRecorder) GetStats(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetStats", reflect.TypeOf((*MockSortServiceServer)(nil).GetStats), arg0, arg1)
+}
+
 // List mocks base method.
 func (m *MockSortServiceServer) List(arg0 context.Context, arg1 *grpcPb.ListRequest) (*grpcPb.ListResponce, error) {
 	m.ctrl.T.Helper()
@@ -129,6 +638,125 @@ func (mr *MockSortServiceServerMockRecorder) List(arg0, arg1 interface{}) *gomoc
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "List", reflect.TypeOf((*MockSortServiceServer)(nil).List), arg0, arg1)
 }
 
+// ListAlertRules mocks base method.
+func (m *MockSortServiceServer) ListAlertRules(arg0 context.Context, arg1 *grpcPb.ListAlertRulesRequest) (*grpcPb.ListAlertRulesResponce, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListAlertRules", arg0, arg1)
+	ret0, _ := ret[0].(*grpcPb.ListAlertRulesResponce)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListAlertRules indicates an expected call of ListAlertRules.
+func (mr *MockSortServiceServerMockRecorder) ListAlertRules(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListAlertRules", reflect.TypeOf((*MockSortServiceServer)(nil).ListAlertRules), arg0, arg1)
+}
+
+// ListDeadLetters mocks base method.
+func (m *MockSortServiceServer) ListDeadLetters(arg0 context.Context, arg1 *grpcPb.ListDeadLettersRequest) (*grpcPb.ListDeadLettersResponce, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListDeadLetters", arg0, arg1)
+	ret0, _ := ret[0].(*grpcPb.ListDeadLettersResponce)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListDeadLetters indicates an expected call of ListDeadLetters.
+func (mr *MockSortServiceServerMockRecorder) ListDeadLetters(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListDeadLetters", reflect.TypeOf((*MockSortServiceServer)(nil).ListDeadLetters), arg0, arg1)
+}
+
+// ListSchedules mocks base method.
+func (m *MockSortServiceServer) ListSchedules(arg0 context.Context, arg1 *grpcPb.ListSchedulesRequest) (*grpcPb.ListSchedulesResponce, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListSchedules", arg0, arg1)
+	ret0, _ := ret[0].(*grpcPb.ListSchedulesResponce)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListSchedules indicates an expected call of ListSchedules.
+func (mr *MockSortServiceServerMockRecorder) ListSchedules(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListSchedules", reflect.TypeOf((*MockSortServiceServer)(nil).ListSchedules), arg0, arg1)
+}
+
+// ListSources mocks base method.
+func (m *MockSortServiceServer) ListSources(arg0 context.Context, arg1 *grpcPb.ListSourcesRequest) (*grpcPb.ListSourcesResponce, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListSources", arg0, arg1)
+	ret0, _ := ret[0].(*grpcPb.ListSourcesResponce)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListSources indicates an expected call of ListSources.
+func (mr *MockSortServiceServerMockRecorder) ListSources(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListSources", reflect.TypeOf((*MockSortServiceServer)(nil).ListSources), arg0, arg1)
+}
+
+// PauseSchedule mocks base method.
+func (m *MockSortServiceServer) PauseSchedule(arg0 context.Context, arg1 *grpcPb.PauseScheduleRequest) (*grpcPb.PauseScheduleResponce, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PauseSchedule", arg0, arg1)
+	ret0, _ := ret[0].(*grpcPb.PauseScheduleResponce)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PauseSchedule indicates an expected call of PauseSchedule.
+func (mr *MockSortServiceServerMockRecorder) PauseSchedule(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PauseSchedule", reflect.TypeOf((*MockSortServiceServer)(nil).PauseSchedule), arg0, arg1)
+}
+
+// PurgeDeleted mocks base method.
+func (m *MockSortServiceServer) PurgeDeleted(arg0 context.Context, arg1 *grpcPb.PurgeDeletedRequest) (*grpcPb.PurgeDeletedResponce, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PurgeDeleted", arg0, arg1)
+	ret0, _ := ret[0].(*grpcPb.PurgeDeletedResponce)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PurgeDeleted indicates an expected call of PurgeDeleted.
+func (mr *MockSortServiceServerMockRecorder) PurgeDeleted(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PurgeDeleted", reflect.TypeOf((*MockSortServiceServer)(nil).PurgeDeleted), arg0, arg1)
+}
+
+// RestoreProduct mocks base method.
+func (m *MockSortServiceServer) RestoreProduct(arg0 context.Context, arg1 *grpcPb.RestoreProductRequest) (*grpcPb.RestoreProductResponce, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RestoreProduct", arg0, arg1)
+	ret0, _ := ret[0].(*grpcPb.RestoreProductResponce)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RestoreProduct indicates an expected call of RestoreProduct.
+func (mr *MockSortServiceServerMockRecorder) RestoreProduct(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RestoreProduct", reflect.TypeOf((*MockSortServiceServer)(nil).RestoreProduct), arg0, arg1)
+}
+
+// Watch mocks base method.
+func (m *MockSortServiceServer) Watch(arg0 *grpcPb.WatchRequest, arg1 grpc.ServerStreamingServer[grpcPb.WatchEvent]) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Watch", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Watch indicates an expected call of Watch.
+func (mr *MockSortServiceServerMockRecorder) Watch(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Watch", reflect.TypeOf((*MockSortServiceServer)(nil).Watch), arg0, arg1)
+}
+
 // mustEmbedUnimplementedSortServiceServer mocks base method.
 func (m *MockSortServiceServer) mustEmbedUnimplementedSortServiceServer() {
 	m.ctrl.T.Helper()
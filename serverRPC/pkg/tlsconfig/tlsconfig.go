@@ -0,0 +1,248 @@
+package tlsconfig
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+type ServerOptions struct {
+	CertFile string
+	KeyFile  string
+	// ClientCAFile turns on mutual TLS: client certificates are verified against this bundle.
+	ClientCAFile string
+	// OptionalClientCert accepts clients without a certificate, those that send one still get verified.
+	OptionalClientCert bool
+}
+
+type ClientOptions struct {
+	// CAFile verifies the server; empty means the system roots.
+	CAFile string
+	// CertFile and KeyFile are sent to servers that ask for a client certificate.
+	CertFile   string
+	KeyFile    string
+	ServerName string
+}
+
+// Reloader keeps the certificate, key and CA bundle in memory and reads them again whenever
+// one of the files changes, so rotated certificates are picked up without a restart.
+type Reloader struct {
+	certFile string
+	keyFile  string
+	caFile   string
+
+	mu   sync.RWMutex
+	cert *tls.Certificate
+	ca   *x509.CertPool
+
+	watcher *fsnotify.Watcher
+	errors  chan error
+	done    chan struct{}
+}
+
+func NewReloader(certFile, keyFile, caFile string) (*Reloader, error) {
+	r := &Reloader{
+		certFile: certFile,
+		keyFile:  keyFile,
+		caFile:   caFile,
+		errors:   make(chan error, 1),
+		done:     make(chan struct{}),
+	}
+	if err := r.load(); err != nil {
+		return nil, err
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	// directories, not files: rotation usually replaces the file (or a symlink to it)
+	dirs := map[string]struct{}{}
+	for _, file := range []string{certFile, keyFile, caFile} {
+		if file != "" {
+			dirs[filepath.Dir(file)] = struct{}{}
+		}
+	}
+	for dir := range dirs {
+		if err := watcher.Add(dir); err != nil {
+			watcher.Close()
+			return nil, err
+		}
+	}
+	r.watcher = watcher
+
+	go r.watch()
+	return r, nil
+}
+
+func (r *Reloader) load() error {
+	var cert *tls.Certificate
+	if r.certFile != "" || r.keyFile != "" {
+		pair, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+		if err != nil {
+			return fmt.Errorf("load key pair: %w", err)
+		}
+		cert = &pair
+	}
+
+	var ca *x509.CertPool
+	if r.caFile != "" {
+		pool, err := loadCertPool(r.caFile)
+		if err != nil {
+			return err
+		}
+		ca = pool
+	}
+
+	r.mu.Lock()
+	r.cert = cert
+	r.ca = ca
+	r.mu.Unlock()
+	return nil
+}
+
+func (r *Reloader) watch() {
+	for {
+		select {
+		case <-r.done:
+			return
+		case event, ok := <-r.watcher.Events:
+			if !ok {
+				return
+			}
+			if event.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Rename|fsnotify.Remove) == 0 || !r.watches(event.Name) {
+				continue
+			}
+			// a half written pair fails to load, the old one stays until the next event
+			if err := r.load(); err != nil {
+				r.report(err)
+			}
+		case err, ok := <-r.watcher.Errors:
+			if !ok {
+				return
+			}
+			r.report(err)
+		}
+	}
+}
+
+func (r *Reloader) watches(name string) bool {
+	for _, file := range []string{r.certFile, r.keyFile, r.caFile} {
+		if file != "" && filepath.Clean(file) == filepath.Clean(name) {
+			return true
+		}
+	}
+	// symlinked files (e.g. Kubernetes secrets) change through a ..data directory swap
+	return filepath.Base(name) == "..data"
+}
+
+func (r *Reloader) report(err error) {
+	select {
+	case r.errors <- err:
+	default:
+	}
+}
+
+// Errors reports failed reloads; the last good certificate keeps being served meanwhile.
+func (r *Reloader) Errors() <-chan error {
+	return r.errors
+}
+
+func (r *Reloader) Certificate() *tls.Certificate {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.cert
+}
+
+func (r *Reloader) CertPool() *x509.CertPool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.ca
+}
+
+func (r *Reloader) Close() error {
+	close(r.done)
+	return r.watcher.Close()
+}
+
+// ServerConfig builds a tls.Config whose certificate and client CA bundle follow the files on disk.
+func ServerConfig(opts ServerOptions) (*tls.Config, *Reloader, error) {
+	if opts.CertFile == "" || opts.KeyFile == "" {
+		return nil, nil, errors.New("tls: cert_file and key_file are required")
+	}
+
+	reloader, err := NewReloader(opts.CertFile, opts.KeyFile, opts.ClientCAFile)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	clientAuth := tls.NoClientCert
+	if opts.ClientCAFile != "" {
+		clientAuth = tls.RequireAndVerifyClientCert
+		if opts.OptionalClientCert {
+			clientAuth = tls.VerifyClientCertIfGiven
+		}
+	}
+
+	config := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			return &tls.Config{
+				MinVersion:   tls.VersionTLS12,
+				Certificates: []tls.Certificate{*reloader.Certificate()},
+				ClientAuth:   clientAuth,
+				ClientCAs:    reloader.CertPool(),
+				NextProtos:   []string{"h2"},
+			}, nil
+		},
+	}
+	return config, reloader, nil
+}
+
+// ClientConfig builds a tls.Config for dialing the server; the client certificate, if any,
+// is reloaded from disk the same way as on the server.
+func ClientConfig(opts ClientOptions) (*tls.Config, *Reloader, error) {
+	config := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		ServerName: opts.ServerName,
+	}
+
+	if opts.CAFile != "" {
+		pool, err := loadCertPool(opts.CAFile)
+		if err != nil {
+			return nil, nil, err
+		}
+		config.RootCAs = pool
+	}
+
+	if opts.CertFile == "" && opts.KeyFile == "" {
+		return config, nil, nil
+	}
+
+	reloader, err := NewReloader(opts.CertFile, opts.KeyFile, "")
+	if err != nil {
+		return nil, nil, err
+	}
+	config.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+		return reloader.Certificate(), nil
+	}
+	return config, reloader, nil
+}
+
+func loadCertPool(file string) (*x509.CertPool, error) {
+	pem, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("read ca bundle: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("ca bundle %s has no certificates", file)
+	}
+	return pool, nil
+}
@@ -0,0 +1,258 @@
+package tlsconfig
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+func newTestCA(t *testing.T, name string) *testCA {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return &testCA{cert: cert, key: key, pem: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}
+}
+
+// issue returns PEM encoded certificate and key signed by the CA.
+func (ca *testCA) issue(t *testing.T, name string, usage x509.ExtKeyUsage) ([]byte, []byte) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		DNSNames:     []string{name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	require.NoError(t, err)
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer})
+}
+
+func writeFile(t *testing.T, path string, data []byte) {
+	t.Helper()
+	// write and rename, the same way certificate managers rotate files
+	tmp := path + ".tmp"
+	require.NoError(t, os.WriteFile(tmp, data, 0o600))
+	require.NoError(t, os.Rename(tmp, path))
+}
+
+// handshake dials a TLS listener and returns the common name of the server certificate.
+func handshake(t *testing.T, serverConfig, clientConfig *tls.Config) (string, error) {
+	t.Helper()
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", serverConfig)
+	require.NoError(t, err)
+	defer listener.Close()
+
+	serverErr := make(chan error, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			serverErr <- err
+			return
+		}
+		defer conn.Close()
+		serverErr <- conn.(*tls.Conn).Handshake()
+	}()
+
+	conn, err := tls.Dial("tcp", listener.Addr().String(), clientConfig)
+	if err != nil {
+		<-serverErr
+		return "", err
+	}
+	defer conn.Close()
+	// with TLS 1.3 the client finishes before the server checks its certificate
+	if err := <-serverErr; err != nil {
+		return "", err
+	}
+	return conn.ConnectionState().PeerCertificates[0].Subject.CommonName, nil
+}
+
+func TestMutualTLS(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCA(t, "test-ca")
+	serverCert, serverKey := ca.issue(t, "localhost", x509.ExtKeyUsageServerAuth)
+	clientCert, clientKey := ca.issue(t, "client", x509.ExtKeyUsageClientAuth)
+
+	files := map[string][]byte{
+		"ca.crt":     ca.pem,
+		"server.crt": serverCert,
+		"server.key": serverKey,
+		"client.crt": clientCert,
+		"client.key": clientKey,
+	}
+	for name, data := range files {
+		writeFile(t, filepath.Join(dir, name), data)
+	}
+
+	serverConfig, serverReloader, err := ServerConfig(ServerOptions{
+		CertFile:     filepath.Join(dir, "server.crt"),
+		KeyFile:      filepath.Join(dir, "server.key"),
+		ClientCAFile: filepath.Join(dir, "ca.crt"),
+	})
+	require.NoError(t, err)
+	defer serverReloader.Close()
+
+	tests := []struct {
+		name    string
+		opts    ClientOptions
+		wantErr bool
+	}{
+		{
+			name: "Client certificate",
+			opts: ClientOptions{
+				CAFile:     filepath.Join(dir, "ca.crt"),
+				CertFile:   filepath.Join(dir, "client.crt"),
+				KeyFile:    filepath.Join(dir, "client.key"),
+				ServerName: "localhost",
+			},
+		},
+		{
+			name: "No client certificate",
+			opts: ClientOptions{
+				CAFile:     filepath.Join(dir, "ca.crt"),
+				ServerName: "localhost",
+			},
+			wantErr: true,
+		},
+		{
+			name: "Unknown server CA",
+			opts: ClientOptions{
+				CertFile:   filepath.Join(dir, "client.crt"),
+				KeyFile:    filepath.Join(dir, "client.key"),
+				ServerName: "localhost",
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			clientConfig, clientReloader, err := ClientConfig(test.opts)
+			require.NoError(t, err)
+			if clientReloader != nil {
+				defer clientReloader.Close()
+			}
+
+			_, err = handshake(t, serverConfig, clientConfig)
+			if test.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestOptionalClientCert(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCA(t, "test-ca")
+	serverCert, serverKey := ca.issue(t, "localhost", x509.ExtKeyUsageServerAuth)
+	writeFile(t, filepath.Join(dir, "ca.crt"), ca.pem)
+	writeFile(t, filepath.Join(dir, "server.crt"), serverCert)
+	writeFile(t, filepath.Join(dir, "server.key"), serverKey)
+
+	serverConfig, reloader, err := ServerConfig(ServerOptions{
+		CertFile:           filepath.Join(dir, "server.crt"),
+		KeyFile:            filepath.Join(dir, "server.key"),
+		ClientCAFile:       filepath.Join(dir, "ca.crt"),
+		OptionalClientCert: true,
+	})
+	require.NoError(t, err)
+	defer reloader.Close()
+
+	clientConfig, _, err := ClientConfig(ClientOptions{CAFile: filepath.Join(dir, "ca.crt"), ServerName: "localhost"})
+	require.NoError(t, err)
+
+	_, err = handshake(t, serverConfig, clientConfig)
+	assert.NoError(t, err)
+}
+
+func TestReload(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCA(t, "test-ca")
+	oldCert, oldKey := ca.issue(t, "localhost", x509.ExtKeyUsageServerAuth)
+	writeFile(t, filepath.Join(dir, "ca.crt"), ca.pem)
+	writeFile(t, filepath.Join(dir, "server.crt"), oldCert)
+	writeFile(t, filepath.Join(dir, "server.key"), oldKey)
+
+	serverConfig, reloader, err := ServerConfig(ServerOptions{
+		CertFile: filepath.Join(dir, "server.crt"),
+		KeyFile:  filepath.Join(dir, "server.key"),
+	})
+	require.NoError(t, err)
+	defer reloader.Close()
+
+	// the rotated certificate comes from a new CA, so only a client that trusts it can connect
+	newCA := newTestCA(t, "rotated-ca")
+	newCert, newKey := newCA.issue(t, "localhost", x509.ExtKeyUsageServerAuth)
+	writeFile(t, filepath.Join(dir, "rotated-ca.crt"), newCA.pem)
+
+	clientConfig, _, err := ClientConfig(ClientOptions{CAFile: filepath.Join(dir, "rotated-ca.crt"), ServerName: "localhost"})
+	require.NoError(t, err)
+
+	_, err = handshake(t, serverConfig, clientConfig)
+	require.Error(t, err)
+
+	writeFile(t, filepath.Join(dir, "server.key"), newKey)
+	writeFile(t, filepath.Join(dir, "server.crt"), newCert)
+
+	assert.Eventually(t, func() bool {
+		_, err := handshake(t, serverConfig, clientConfig)
+		return err == nil
+	}, 5*time.Second, 50*time.Millisecond)
+}
+
+func TestServerConfigErrors(t *testing.T) {
+	dir := t.TempDir()
+
+	_, _, err := ServerConfig(ServerOptions{})
+	assert.Error(t, err)
+
+	_, _, err = ServerConfig(ServerOptions{
+		CertFile: filepath.Join(dir, "missing.crt"),
+		KeyFile:  filepath.Join(dir, "missing.key"),
+	})
+	assert.Error(t, err)
+
+	writeFile(t, filepath.Join(dir, "empty.crt"), []byte("not a certificate"))
+	_, _, err = ClientConfig(ClientOptions{CAFile: filepath.Join(dir, "empty.crt")})
+	assert.Error(t, err)
+}